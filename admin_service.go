@@ -0,0 +1,544 @@
+package mockservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// AdminService serves the registration endpoint and the administration routes beneath it:
+//
+//	POST   {registration endpoint}           registers a mock endpoint
+//	PUT    {registration endpoint}           updates a registered mock endpoint
+//	GET    {registration endpoint}           lists the registered mock endpoints, only those with all the tag query parameters when given
+//	DELETE {registration endpoint}           deletes the mock endpoint given by the method and endpoint query parameters
+//	POST   {registration endpoint}/reset     deletes all the mock endpoints and recorded requests
+//	GET    {registration endpoint}/requests  lists the recorded requests, starting at the optional offset query parameter
+//	DELETE {registration endpoint}/requests  deletes all the recorded requests
+//	POST   {registration endpoint}/verify    verifies the recorded requests against a Verification
+//	GET    {registration endpoint}/ca.pem    downloads the certificate of the certificate authority used to serve HTTPS
+//	POST   {registration endpoint}/websocket pushes the request body as a text message to the WebSocket clients connected to the mock endpoint given by the method and endpoint query parameters
+//	GET    {registration endpoint}/frames    lists the WebSocket messages received from clients
+//	POST   {registration endpoint}/openapi   registers the mock endpoints generated from the OpenAPI document in the request body, see ImportOpenAPI
+//	POST   {registration endpoint}/har       registers the mock endpoints imported from the HAR log in the request body, see ImportHAR
+//	GET    {registration endpoint}/har       exports the recorded requests and their responses, or the mock endpoints when source is "mocks", as a HAR log
+//	POST   {registration endpoint}/postman   registers the mock endpoints imported from the Postman collection in the request body, see ImportPostman
+//	POST   {registration endpoint}/pact      registers the mock endpoints imported from the Pact contract in the request body, see ImportPact
+//	GET    {registration endpoint}/pact      reports which of the imported Pact interactions were exercised
+//	GET    {registration endpoint}/scenarios lists the current scenario states
+//	PUT    {registration endpoint}/scenarios moves the scenario to the ScenarioState in the request body
+//	DELETE {registration endpoint}/scenarios moves all the scenarios back to ScenarioStarted
+//	GET    {registration endpoint}/snapshot  responds with the Snapshot of the mock endpoints and scenario states
+//	PUT    {registration endpoint}/snapshot  replaces the mock endpoints and scenario states with the Snapshot in the request body
+//	POST   {registration endpoint}/events    pushes a ServerSentEvent to the clients connected to the mock endpoint given by the method and endpoint query parameters
+//
+// A MockService also serves GET {registration endpoint}/sessions, which lists the sessions in use, and DELETE
+// {registration endpoint}/sessions, which deletes the sessions given by the id query parameters or all of them, while
+// the routes above only act on the session selected by SessionPathPrefix or SessionHeader.
+type AdminService struct {
+	registrationEndpoint string
+	registrationService  *RegistrationService
+	mockedEndpoints      Store
+	journal              *Journal
+	certificateAuthority *CertificateAuthority
+	events               *eventHub
+	sockets              *socketHub
+}
+
+// NewAdminService creates an admin service for the mock endpoints and journal served under the registration endpoint
+func NewAdminService(registrationEndpoint string, endpoints Store, journal *Journal) *AdminService {
+	registrationService := NewRegistrationService(endpoints)
+	registrationService.registrationEndpoint = registrationEndpoint
+	return &AdminService{
+		registrationEndpoint: registrationEndpoint,
+		registrationService:  registrationService,
+		mockedEndpoints:      endpoints,
+		journal:              journal,
+		events:               newEventHub(),
+		sockets:              newSocketHub(),
+	}
+}
+
+// Handles reports whether the request is for the registration endpoint or one of the administration routes
+func (a *AdminService) Handles(req *http.Request) bool {
+	return underRegistrationEndpoint(a.registrationEndpoint, req.URL.Path)
+}
+
+// underRegistrationEndpoint reports whether the path is the registration endpoint or one of the administration routes
+func underRegistrationEndpoint(registrationEndpoint, path string) bool {
+	return path == registrationEndpoint || strings.HasPrefix(path, strings.TrimSuffix(registrationEndpoint, "/")+"/")
+}
+
+// registrationConflict returns ErrRegistrationEndpointConflict when one of the endpoints would be served by the
+// registration endpoint or the administration routes instead of being mocked
+func registrationConflict(registrationEndpoint string, endpoints ...*MockEndpoint) error {
+	if registrationEndpoint == "" {
+		return nil
+	}
+	for _, endpoint := range endpoints {
+		if underRegistrationEndpoint(registrationEndpoint, endpoint.Endpoint) {
+			return ErrRegistrationEndpointConflict
+		}
+	}
+	return nil
+}
+
+// load registers the endpoints of an import, none of which may conflict with the registration endpoint
+func (a *AdminService) load(endpoints []*MockEndpoint) error {
+	if err := registrationConflict(a.registrationEndpoint, endpoints...); err != nil {
+		return err
+	}
+	return loadEndpoints(a.mockedEndpoints, endpoints)
+}
+
+// registers reports whether the request registers mock endpoints, through the registration endpoint or an import route
+func (a *AdminService) registers(req *http.Request) bool {
+	if !a.Handles(req) {
+		return false
+	}
+	switch strings.TrimPrefix(req.URL.Path, strings.TrimSuffix(a.registrationEndpoint, "/")) {
+	case "", "/":
+		return req.Method == http.MethodPost || req.Method == http.MethodPut
+	case "/openapi", "/har", "/postman", "/pact":
+		return req.Method == http.MethodPost
+	case "/snapshot":
+		return req.Method == http.MethodPut
+	}
+	return false
+}
+
+// ServeHTTP serves the registration endpoint and administration routes
+func (a *AdminService) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	route := strings.TrimPrefix(req.URL.Path, strings.TrimSuffix(a.registrationEndpoint, "/"))
+	switch {
+	case route == "" || route == "/":
+		switch req.Method {
+		case http.MethodPost, http.MethodPut:
+			a.registrationService.ServeHTTP(w, req)
+		case http.MethodGet:
+			a.listEndpoints(w, req)
+		case http.MethodDelete:
+			a.deleteEndpoint(w, req)
+		default:
+			methodNotAllowed(w, http.MethodPost, http.MethodPut, http.MethodGet, http.MethodDelete)
+		}
+	case route == "/reset":
+		if req.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		a.mockedEndpoints.Reset()
+		a.journal.Reset()
+		w.WriteHeader(http.StatusNoContent)
+	case route == "/requests":
+		switch req.Method {
+		case http.MethodGet:
+			a.listRequests(w, req)
+		case http.MethodDelete:
+			a.journal.Reset()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+		}
+	case route == "/verify":
+		if req.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		a.verify(w, req)
+	case route == "/ca.pem":
+		if req.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		if a.certificateAuthority == nil {
+			http.Error(w, ErrNoCertificateAuthority.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-pem-file")
+		w.Write(a.certificateAuthority.CertificatePEM())
+	case route == "/events":
+		if req.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		a.pushEvent(w, req)
+	case route == "/websocket":
+		if req.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		a.pushWebSocketMessage(w, req)
+	case route == "/openapi":
+		if req.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		a.importOpenAPI(w, req)
+	case route == "/har":
+		switch req.Method {
+		case http.MethodPost:
+			a.importHAR(w, req)
+		case http.MethodGet:
+			a.exportHAR(w, req)
+		default:
+			methodNotAllowed(w, http.MethodPost, http.MethodGet)
+		}
+	case route == "/postman":
+		if req.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		a.importPostman(w, req)
+	case route == "/pact":
+		switch req.Method {
+		case http.MethodPost:
+			a.importPact(w, req)
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, pactReport(a.mockedEndpoints))
+		default:
+			methodNotAllowed(w, http.MethodPost, http.MethodGet)
+		}
+	case route == "/scenarios":
+		switch req.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, a.mockedEndpoints.Scenarios())
+		case http.MethodPut:
+			a.setScenarioState(w, req)
+		case http.MethodDelete:
+			a.mockedEndpoints.ResetScenarios()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodDelete)
+		}
+	case route == "/snapshot":
+		switch req.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, snapshotOf(a.mockedEndpoints))
+		case http.MethodPut:
+			a.restoreSnapshot(w, req)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodPut)
+		}
+	case route == "/frames":
+		if req.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		writeJSON(w, http.StatusOK, a.journal.Frames())
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// importOpenAPI registers the mock endpoints of an OpenAPI document, configured by the basePath, validate, fake and seed
+// query parameters and status query parameters formatted as "operationId:code" or "METHOD /path:code"
+func (a *AdminService) importOpenAPI(w http.ResponseWriter, req *http.Request) {
+	options := &OpenAPIOptions{
+		BasePath:         req.URL.Query().Get("basePath"),
+		StatusCodes:      map[string]int{},
+		ValidateRequests: req.URL.Query().Get("validate") == "true",
+		FakeResponses:    req.URL.Query().Get("fake") == "true",
+	}
+	if seed := req.URL.Query().Get("seed"); seed != "" {
+		var err error
+		if options.Seed, err = strconv.ParseInt(seed, 10, 64); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid seed: %s", seed), http.StatusBadRequest)
+			return
+		}
+	}
+	for _, param := range req.URL.Query()["status"] {
+		i := strings.LastIndex(param, ":")
+		statusCode, err := strconv.Atoi(param[i+1:])
+		if i < 0 || err != nil {
+			http.Error(w, fmt.Sprintf("Invalid status: %s", param), http.StatusBadRequest)
+			return
+		}
+		options.StatusCodes[param[:i]] = statusCode
+	}
+
+	spec, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unable to read from payload due to: %s", err), http.StatusBadRequest)
+		return
+	}
+	conf, err := ImportOpenAPI(spec, options)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := a.load(conf.Endpoints); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusCreated, conf.Endpoints)
+}
+
+// listEndpoints responds with the registered mock endpoints, only those with all the tag query parameters when given
+func (a *AdminService) listEndpoints(w http.ResponseWriter, req *http.Request) {
+	tags := req.URL.Query()["tag"]
+	endpoints := []*MockEndpoint{}
+	for _, endpoint := range a.mockedEndpoints.List() {
+		if endpoint.hasTags(tags) {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	writeJSON(w, http.StatusOK, endpoints)
+}
+
+// importPostman registers the mock endpoints of a Postman collection, configured by the header and matchBody query
+// parameters and variable query parameters formatted as "name=value"
+func (a *AdminService) importPostman(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	options := &PostmanOptions{
+		Variables:      map[string]string{},
+		RequestHeaders: query["header"],
+		MatchBody:      query.Get("matchBody") == "true",
+	}
+	for _, param := range query["variable"] {
+		i := strings.Index(param, "=")
+		if i < 0 {
+			http.Error(w, fmt.Sprintf("Invalid variable: %s", param), http.StatusBadRequest)
+			return
+		}
+		options.Variables[param[:i]] = param[i+1:]
+	}
+
+	collection, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unable to read from payload due to: %s", err), http.StatusBadRequest)
+		return
+	}
+	conf, err := ImportPostman(collection, options)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := a.load(conf.Endpoints); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusCreated, conf.Endpoints)
+}
+
+// importPact registers the mock endpoints of the Pact contract in the request body
+func (a *AdminService) importPact(w http.ResponseWriter, req *http.Request) {
+	pact, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unable to read from payload due to: %s", err), http.StatusBadRequest)
+		return
+	}
+	conf, err := ImportPact(pact)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := a.load(conf.Endpoints); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusCreated, conf.Endpoints)
+}
+
+// setScenarioState moves the scenario in the request body to its state
+func (a *AdminService) setScenarioState(w http.ResponseWriter, req *http.Request) {
+	state := ScenarioState{}
+	if err := json.NewDecoder(req.Body).Decode(&state); err != nil {
+		http.Error(w, fmt.Sprintf("Unable to decode scenario state: %s", err), http.StatusBadRequest)
+		return
+	}
+	if state.Name == "" || state.State == "" {
+		http.Error(w, "Scenario name and state are required", http.StatusBadRequest)
+		return
+	}
+	a.mockedEndpoints.SetScenarioState(state.Name, state.State)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// restoreSnapshot replaces the mock endpoints and scenario states with the snapshot in the request body
+func (a *AdminService) restoreSnapshot(w http.ResponseWriter, req *http.Request) {
+	snapshot := &Snapshot{}
+	if err := json.NewDecoder(req.Body).Decode(snapshot); err != nil {
+		http.Error(w, fmt.Sprintf("Unable to decode snapshot: %s", err), http.StatusBadRequest)
+		return
+	}
+	if err := registrationConflict(a.registrationEndpoint, snapshot.Endpoints...); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := a.mockedEndpoints.Restore(snapshot); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// exportHAR responds with the recorded requests and their responses as a HAR log, or with the mock endpoints when the
+// source query parameter is "mocks"
+func (a *AdminService) exportHAR(w http.ResponseWriter, req *http.Request) {
+	var har []byte
+	var err error
+	switch source := req.URL.Query().Get("source"); source {
+	case "", "requests":
+		har, err = ExportHAR(a.journal.Requests())
+	case "mocks":
+		scheme := "http"
+		if req.TLS != nil {
+			scheme = "https"
+		}
+		har, err = ExportEndpointsHAR(a.mockedEndpoints.List(), scheme+"://"+req.Host)
+	default:
+		http.Error(w, fmt.Sprintf("Invalid source: %s", source), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unable to export HAR log: %s", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(har)
+}
+
+// importHAR registers the mock endpoints of a HAR log, configured by the host, pathPrefix, header, matchBody, dedupe,
+// stripVolatile and strip query parameters
+func (a *AdminService) importHAR(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	options := &HAROptions{
+		Hosts:                query["host"],
+		PathPrefix:           query.Get("pathPrefix"),
+		RequestHeaders:       query["header"],
+		MatchBody:            query.Get("matchBody") == "true",
+		Deduplicate:          query.Get("dedupe") == "true",
+		StripVolatileHeaders: query.Get("stripVolatile") == "true",
+		StripHeaders:         query["strip"],
+	}
+
+	har, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unable to read from payload due to: %s", err), http.StatusBadRequest)
+		return
+	}
+	conf, err := ImportHAR(har, options)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := a.load(conf.Endpoints); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusCreated, conf.Endpoints)
+}
+
+func (a *AdminService) pushWebSocketMessage(w http.ResponseWriter, req *http.Request) {
+	method, endpoint := req.URL.Query().Get("method"), req.URL.Query().Get("endpoint")
+	if method == "" {
+		method = http.MethodGet
+	}
+	if strings.Trim(endpoint, " ") == "" {
+		http.Error(w, ErrEmptyEndpoint.Error(), http.StatusBadRequest)
+		return
+	}
+
+	message, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unable to read from payload due to: %s", err), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"clients": a.sockets.push(method, endpoint, string(message))})
+}
+
+func (a *AdminService) pushEvent(w http.ResponseWriter, req *http.Request) {
+	method, endpoint := req.URL.Query().Get("method"), req.URL.Query().Get("endpoint")
+	if method == "" {
+		method = http.MethodGet
+	}
+	if strings.Trim(endpoint, " ") == "" {
+		http.Error(w, ErrEmptyEndpoint.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reqPayload, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unable to read from payload due to: %s", err), http.StatusBadRequest)
+		return
+	}
+	event := ServerSentEvent{}
+	if err := json.Unmarshal(reqPayload, &event); err != nil {
+		http.Error(w, fmt.Sprintf("Unable to Unmarshal request body %s: %s", reqPayload, err), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"clients": a.events.push(method, endpoint, event)})
+}
+
+func (a *AdminService) deleteEndpoint(w http.ResponseWriter, req *http.Request) {
+	method, endpoint := req.URL.Query().Get("method"), req.URL.Query().Get("endpoint")
+	if strings.Trim(method, " ") == "" {
+		http.Error(w, ErrEmptyHTTPMethod.Error(), http.StatusBadRequest)
+		return
+	}
+	if strings.Trim(endpoint, " ") == "" {
+		http.Error(w, ErrEmptyEndpoint.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := a.mockedEndpoints.Delete(method, endpoint); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *AdminService) listRequests(w http.ResponseWriter, req *http.Request) {
+	offset := 0
+	if param := req.URL.Query().Get("offset"); param != "" {
+		var err error
+		if offset, err = strconv.Atoi(param); err != nil || offset < 0 {
+			http.Error(w, fmt.Sprintf("Invalid offset: %s", param), http.StatusBadRequest)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, a.journal.RequestsSince(offset))
+}
+
+func (a *AdminService) verify(w http.ResponseWriter, req *http.Request) {
+	reqPayload, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unable to read from payload due to: %s", err), http.StatusBadRequest)
+		return
+	}
+	verification := Verification{}
+	if err := json.Unmarshal(reqPayload, &verification); err != nil {
+		http.Error(w, fmt.Sprintf("Unable to Unmarshal request body %s: %s", reqPayload, err), http.StatusBadRequest)
+		return
+	}
+
+	count, err := a.journal.Verify(verification)
+	if err != nil {
+		writeJSON(w, http.StatusExpectationFailed, VerificationResult{Count: count, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, VerificationResult{Count: count})
+}
+
+// writeJSON writes the value as a JSON response body with the status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Unable to marshal response body: %s", err)
+		http.Error(w, fmt.Sprintf("Unable to marshal response body: %s", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
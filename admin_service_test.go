@@ -0,0 +1,101 @@
+package mockservice_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wchan2/mock_service"
+)
+
+func serve(service http.Handler, method, url, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	service.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestAdminService_ServeHTTP(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+
+	if recorder := serve(service, http.MethodPost, "/mocks", successfulRegistrationRequest); recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected %d status but got %d", http.StatusCreated, recorder.Code)
+	}
+
+	t.Run("List_endpoints", func(t *testing.T) {
+		recorder := serve(service, http.MethodGet, "/mocks", "")
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected %d status but got %d", http.StatusOK, recorder.Code)
+		}
+
+		endpoints := []*mockservice.MockEndpoint{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &endpoints); err != nil {
+			t.Fatalf("Expected the response body to be a list of endpoints but got %s", err)
+		}
+
+		if len(endpoints) != 1 || endpoints[0].Endpoint != "/mock/test" || endpoints[0].ResponseHeaders["Foo"] != "Bar" {
+			t.Errorf("Expected the registered endpoint but got %+v", endpoints)
+		}
+	})
+
+	t.Run("Requests_and_verify", func(t *testing.T) {
+		serve(service, http.MethodGet, "/mock/test?foo=bar", "")
+		serve(service, http.MethodGet, "/mock/unknown", "")
+
+		recorder := serve(service, http.MethodGet, "/mocks/requests?offset=1", "")
+		requests := []*mockservice.RecordedRequest{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &requests); err != nil {
+			t.Fatalf("Expected the response body to be a list of requests but got %s", err)
+		}
+
+		if len(requests) != 1 || requests[0].Endpoint != "/mock/unknown" || requests[0].Matched {
+			t.Errorf("Expected the unmatched request after the offset but got %+v", requests)
+		}
+
+		recorder = serve(service, http.MethodPost, "/mocks/verify", `{"method": "GET", "endpoint": "/mock/test", "count": 1}`)
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected %d status but got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		recorder = serve(service, http.MethodPost, "/mocks/verify", `{"method": "POST", "endpoint": "/mock/test"}`)
+		if recorder.Code != http.StatusExpectationFailed {
+			t.Errorf("Expected %d status but got %d", http.StatusExpectationFailed, recorder.Code)
+		}
+	})
+
+	t.Run("Delete_endpoint", func(t *testing.T) {
+		if recorder := serve(service, http.MethodDelete, "/mocks?method=GET&endpoint=/mock/test", ""); recorder.Code != http.StatusNoContent {
+			t.Errorf("Expected %d status but got %d", http.StatusNoContent, recorder.Code)
+		}
+
+		if recorder := serve(service, http.MethodDelete, "/mocks?method=GET&endpoint=/mock/test", ""); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected %d status but got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		serve(service, http.MethodPost, "/mocks", successfulRegistrationRequest)
+		if recorder := serve(service, http.MethodPost, "/mocks/reset", ""); recorder.Code != http.StatusNoContent {
+			t.Errorf("Expected %d status but got %d", http.StatusNoContent, recorder.Code)
+		}
+
+		if recorder := serve(service, http.MethodGet, "/mocks", ""); recorder.Body.String() != "[]" {
+			t.Errorf("Expected no endpoints after a reset but got %s", recorder.Body.String())
+		}
+
+		if requests := service.Journal().Requests(); len(requests) != 0 {
+			t.Errorf("Expected no recorded requests after a reset but got %d", len(requests))
+		}
+	})
+
+	t.Run("Unknown_route", func(t *testing.T) {
+		if recorder := serve(service, http.MethodGet, "/mocks/unknown", ""); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected %d status but got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package mockservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// BodyMatcher matches a request body or a WebSocket message. All the non-empty properties must match.
+type BodyMatcher struct {
+	// EqualTo matches the exact body
+	EqualTo string `json:"equalTo,omitempty" xml:"equalTo,omitempty"`
+	// Contains matches bodies containing the substring
+	Contains string `json:"contains,omitempty" xml:"contains,omitempty"`
+	// Matches matches bodies against the regular expression
+	Matches string `json:"matches,omitempty" xml:"matches,omitempty"`
+	// EqualToJSON matches JSON bodies semantically equal to the JSON value, ignoring formatting and key order
+	EqualToJSON json.RawMessage `json:"equalToJson,omitempty" xml:"equalToJson,omitempty"`
+	// MatchingRules relax EqualToJSON for the values at JSON paths such as $.items[*].id, which are matched by the
+	// rules instead of compared to the expected value
+	MatchingRules map[string]*MatchingRules `json:"matchingRules,omitempty" xml:"-"`
+}
+
+// validate checks the regular expression and JSON value of the matcher
+func (b *BodyMatcher) validate() error {
+	if b.Matches != "" {
+		if _, err := regexp.Compile(b.Matches); err != nil {
+			return fmt.Errorf("Invalid body matcher regular expression %q: %s", b.Matches, err)
+		}
+	}
+	if len(b.EqualToJSON) > 0 && !json.Valid(b.EqualToJSON) {
+		return fmt.Errorf("Invalid body matcher JSON %s", b.EqualToJSON)
+	}
+	if len(b.MatchingRules) > 0 && len(b.EqualToJSON) == 0 {
+		return fmt.Errorf("Body matcher matching rules require equalToJson")
+	}
+	for path, rules := range b.MatchingRules {
+		if err := rules.validate(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *BodyMatcher) matches(body []byte) bool {
+	if b.EqualTo != "" && b.EqualTo != string(body) {
+		return false
+	}
+	if b.Contains != "" && !strings.Contains(string(body), b.Contains) {
+		return false
+	}
+	if b.Matches != "" {
+		if matched, err := regexp.Match(b.Matches, body); err != nil || !matched {
+			return false
+		}
+	}
+	if len(b.EqualToJSON) > 0 && len(b.MatchingRules) > 0 {
+		return matchJSONRules(b.EqualToJSON, body, b.MatchingRules)
+	}
+	if len(b.EqualToJSON) > 0 && !equalJSON(b.EqualToJSON, body) {
+		return false
+	}
+	return true
+}
+
+func equalJSON(expected, actual []byte) bool {
+	var expectedVal, actualVal interface{}
+	if json.Unmarshal(expected, &expectedVal) != nil {
+		return false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(actual))
+	if decoder.Decode(&actualVal) != nil {
+		return false
+	}
+	return reflect.DeepEqual(expectedVal, actualVal)
+}
+
+func matchBody(matchers []BodyMatcher, body []byte) bool {
+	for i := range matchers {
+		if !matchers[i].matches(body) {
+			return false
+		}
+	}
+	return true
+}
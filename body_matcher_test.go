@@ -0,0 +1,118 @@
+package mockservice_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wchan2/mock_service"
+)
+
+func TestBodyMatchers(t *testing.T) {
+	endpoints := mockservice.NewEndpoints()
+	err := endpoints.Create(&mockservice.MockEndpoint{
+		Method:   http.MethodPost,
+		Endpoint: "/orders",
+		BodyMatchers: []mockservice.BodyMatcher{
+			{Contains: "widget"},
+			{Matches: `"quantity":\s*[0-9]+`},
+			{EqualToJSON: []byte(`{"item": "widget", "quantity": 2}`)},
+		},
+		StatusCode: http.StatusCreated,
+	})
+	if err != nil {
+		t.Fatalf("Expected creating the endpoint to succeed but got %s", err)
+	}
+	svc := mockservice.NewEndpointService(endpoints)
+
+	cases := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"All_matchers", `{"quantity": 2, "item": "widget"}`, http.StatusCreated},
+		{"Different_JSON", `{"quantity": 3, "item": "widget"}`, http.StatusNotFound},
+		{"Not_JSON", `widget "quantity": 2`, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			svc.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(c.body)))
+			if recorder.Code != c.expected {
+				t.Errorf("Expected %d status but got %d", c.expected, recorder.Code)
+			}
+		})
+	}
+
+	t.Run("Invalid_regular_expression", func(t *testing.T) {
+		err := endpoints.Create(&mockservice.MockEndpoint{Method: http.MethodPost, Endpoint: "/orders", BodyMatchers: []mockservice.BodyMatcher{{Matches: "["}}})
+		if err == nil {
+			t.Errorf("Expected an error for an invalid regular expression")
+		}
+	})
+}
+
+func TestBodyMatchers_MatchingRules(t *testing.T) {
+	one, two := 1, 2
+	matcher := mockservice.BodyMatcher{
+		EqualToJSON: []byte(`{"id": 1, "name": "ada", "email": "ada@example.com", "born": "1815-12-10", "tags": [{"name": "math", "score": 1.5}], "kind": "user"}`),
+		MatchingRules: map[string]*mockservice.MatchingRules{
+			"$.id":           {Matchers: []mockservice.MatchingRule{{Match: "integer"}}},
+			"$.name":         {Matchers: []mockservice.MatchingRule{{Match: "type"}}},
+			"$.email":        {Matchers: []mockservice.MatchingRule{{Match: "regex", Regex: `[^@]+@example\.com`}}},
+			"$.born":         {Matchers: []mockservice.MatchingRule{{Match: "date", Format: "yyyy-MM-dd"}}},
+			"$.tags":         {Matchers: []mockservice.MatchingRule{{Match: "type", Min: &one, Max: &two}}},
+			"$.tags[*].name": {Matchers: []mockservice.MatchingRule{{Match: "equality"}}},
+			"$.kind":         {Combine: "OR", Matchers: []mockservice.MatchingRule{{Match: "regex", Regex: "user"}, {Match: "null"}}},
+		},
+	}
+	endpoints := mockservice.NewEndpoints()
+	if err := endpoints.Create(&mockservice.MockEndpoint{Method: http.MethodPost, Endpoint: "/users", StatusCode: http.StatusCreated, BodyMatchers: []mockservice.BodyMatcher{matcher}}); err != nil {
+		t.Fatalf("Expected creating the endpoint to succeed but got %s", err)
+	}
+	svc := mockservice.NewEndpointService(endpoints)
+
+	cases := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"Example", `{"id": 1, "name": "ada", "email": "ada@example.com", "born": "1815-12-10", "tags": [{"name": "math", "score": 1.5}], "kind": "user"}`, http.StatusCreated},
+		{"Other_values", `{"id": 7, "name": "grace", "email": "grace@example.com", "born": "1906-12-09", "tags": [{"name": "math", "score": 3}, {"name": "math", "score": 0.5}], "kind": null}`, http.StatusCreated},
+		{"Decimal_id", `{"id": 1.5, "name": "ada", "email": "ada@example.com", "born": "1815-12-10", "tags": [{"name": "math", "score": 1.5}], "kind": "user"}`, http.StatusNotFound},
+		{"Name_of_other_type", `{"id": 1, "name": 1, "email": "ada@example.com", "born": "1815-12-10", "tags": [{"name": "math", "score": 1.5}], "kind": "user"}`, http.StatusNotFound},
+		{"Email_not_matching_regex", `{"id": 1, "name": "ada", "email": "ada@example.org", "born": "1815-12-10", "tags": [{"name": "math", "score": 1.5}], "kind": "user"}`, http.StatusNotFound},
+		{"Invalid_date", `{"id": 1, "name": "ada", "email": "ada@example.com", "born": "10/12/1815", "tags": [{"name": "math", "score": 1.5}], "kind": "user"}`, http.StatusNotFound},
+		{"Too_few_items", `{"id": 1, "name": "ada", "email": "ada@example.com", "born": "1815-12-10", "tags": [], "kind": "user"}`, http.StatusNotFound},
+		{"Too_many_items", `{"id": 1, "name": "ada", "email": "ada@example.com", "born": "1815-12-10", "tags": [{"name": "math", "score": 1}, {"name": "math", "score": 1}, {"name": "math", "score": 1}], "kind": "user"}`, http.StatusNotFound},
+		{"Item_not_equal", `{"id": 1, "name": "ada", "email": "ada@example.com", "born": "1815-12-10", "tags": [{"name": "art", "score": 1.5}], "kind": "user"}`, http.StatusNotFound},
+		{"Item_of_other_type", `{"id": 1, "name": "ada", "email": "ada@example.com", "born": "1815-12-10", "tags": [{"name": "math", "score": "high"}], "kind": "user"}`, http.StatusNotFound},
+		{"Neither_alternative", `{"id": 1, "name": "ada", "email": "ada@example.com", "born": "1815-12-10", "tags": [{"name": "math", "score": 1.5}], "kind": "admin"}`, http.StatusNotFound},
+		{"Unexpected_key", `{"id": 1, "name": "ada", "email": "ada@example.com", "born": "1815-12-10", "tags": [{"name": "math", "score": 1.5}], "kind": "user", "admin": true}`, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			svc.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(c.body)))
+			if recorder.Code != c.expected {
+				t.Errorf("Expected %d status but got %d", c.expected, recorder.Code)
+			}
+		})
+	}
+
+	t.Run("Invalid_rules", func(t *testing.T) {
+		invalid := []map[string]*mockservice.MatchingRules{
+			{"name": {Matchers: []mockservice.MatchingRule{{Match: "type"}}}},
+			{"$.name": {Matchers: []mockservice.MatchingRule{{Match: "values"}}}},
+			{"$.name": {Matchers: []mockservice.MatchingRule{{Match: "regex", Regex: "["}}}},
+			{"$.name": nil},
+		}
+		for _, rules := range invalid {
+			matcher := mockservice.BodyMatcher{EqualToJSON: []byte(`{"name": "ada"}`), MatchingRules: rules}
+			if err := endpoints.Create(&mockservice.MockEndpoint{Method: http.MethodPost, Endpoint: "/users", BodyMatchers: []mockservice.BodyMatcher{matcher}}); err == nil {
+				t.Errorf("Expected an error for the matching rules %v", rules)
+			}
+		}
+	})
+}
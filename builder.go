@@ -0,0 +1,322 @@
+package mockservice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidStatusCode is returned when building or adding a mock endpoint with a status code outside of 100-999
+	ErrInvalidStatusCode = errors.New("Invalid HTTP status code provided")
+
+	// ErrEmptyHeaderName is returned when building a mock endpoint with an empty header name
+	ErrEmptyHeaderName = errors.New("Empty header name provided")
+
+	// ErrInvalidChunkSize is returned when building a mock endpoint streaming its body in chunks of zero or fewer bytes
+	ErrInvalidChunkSize = errors.New("Invalid chunk size provided")
+)
+
+// EndpointBuilder builds a MockEndpoint fluently, starting with how requests are matched.
+// Each step is validated as it is applied and the first error is returned by Build.
+type EndpointBuilder struct {
+	endpoint *MockEndpoint
+	err      error
+}
+
+// ResponseBuilder configures the response of the MockEndpoint being built
+type ResponseBuilder struct {
+	builder *EndpointBuilder
+}
+
+// NewEndpointBuilder starts building a mock endpoint for the HTTP method and URL path or path template
+func NewEndpointBuilder(method, path string) *EndpointBuilder {
+	b := &EndpointBuilder{endpoint: &MockEndpoint{
+		Method:     method,
+		Endpoint:   path,
+		StatusCode: http.StatusOK,
+	}}
+	b.err = b.endpoint.Validate()
+	return b
+}
+
+// Get starts building a mock endpoint for GET requests to the path
+func Get(path string) *EndpointBuilder { return NewEndpointBuilder(http.MethodGet, path) }
+
+// Head starts building a mock endpoint for HEAD requests to the path
+func Head(path string) *EndpointBuilder { return NewEndpointBuilder(http.MethodHead, path) }
+
+// Post starts building a mock endpoint for POST requests to the path
+func Post(path string) *EndpointBuilder { return NewEndpointBuilder(http.MethodPost, path) }
+
+// Put starts building a mock endpoint for PUT requests to the path
+func Put(path string) *EndpointBuilder { return NewEndpointBuilder(http.MethodPut, path) }
+
+// Patch starts building a mock endpoint for PATCH requests to the path
+func Patch(path string) *EndpointBuilder { return NewEndpointBuilder(http.MethodPatch, path) }
+
+// Delete starts building a mock endpoint for DELETE requests to the path
+func Delete(path string) *EndpointBuilder { return NewEndpointBuilder(http.MethodDelete, path) }
+
+// Options starts building a mock endpoint for OPTIONS requests to the path
+func Options(path string) *EndpointBuilder { return NewEndpointBuilder(http.MethodOptions, path) }
+
+// WithHeader requires matched requests to have the header value
+func (b *EndpointBuilder) WithHeader(key, value string) *EndpointBuilder {
+	if b.err == nil && strings.TrimSpace(key) == "" {
+		b.err = ErrEmptyHeaderName
+	}
+	if b.endpoint.RequestHeaders == nil {
+		b.endpoint.RequestHeaders = map[string]string{}
+	}
+	b.endpoint.RequestHeaders[key] = value
+	return b
+}
+
+// WithQueryParameter requires matched requests to have the query parameter with the value
+func (b *EndpointBuilder) WithQueryParameter(key, value string) *EndpointBuilder {
+	if b.endpoint.QueryParameters == nil {
+		b.endpoint.QueryParameters = map[string]string{}
+	}
+	b.endpoint.QueryParameters[key] = value
+	return b
+}
+
+// WithBody requires matched requests to have exactly the body
+func (b *EndpointBuilder) WithBody(body string) *EndpointBuilder {
+	b.endpoint.RequestBody = body
+	return b
+}
+
+// WithClientCertificate requires matched requests to be sent with a TLS client certificate matching the matcher
+func (b *EndpointBuilder) WithClientCertificate(matcher ClientCertificateMatcher) *EndpointBuilder {
+	b.endpoint.ClientCertificate = &matcher
+	return b
+}
+
+// WithoutClientCertificate requires matched requests to be sent without a TLS client certificate
+func (b *EndpointBuilder) WithoutClientCertificate() *EndpointBuilder {
+	b.endpoint.ClientCertificate = &ClientCertificateMatcher{Absent: true}
+	return b
+}
+
+// WithProtocol requires matched requests to use the protocol, such as "HTTP/2.0" or "HTTP/2"
+func (b *EndpointBuilder) WithProtocol(protocol string) *EndpointBuilder {
+	b.endpoint.Protocol = protocol
+	return b
+}
+
+// WithGraphQLOperation requires matched requests to execute the GraphQL operation, selecting all the top-level fields
+func (b *EndpointBuilder) WithGraphQLOperation(operationName string, fields ...string) *EndpointBuilder {
+	if b.endpoint.GraphQL == nil {
+		b.endpoint.GraphQL = &GraphQLMatcher{}
+	}
+	b.endpoint.GraphQL.OperationName = operationName
+	b.endpoint.GraphQL.Fields = append(b.endpoint.GraphQL.Fields, fields...)
+	return b
+}
+
+// WithGraphQLVariable requires matched GraphQL requests to have the variable with the value marshaled as JSON
+func (b *EndpointBuilder) WithGraphQLVariable(name string, value interface{}) *EndpointBuilder {
+	raw, err := json.Marshal(value)
+	if err != nil && b.err == nil {
+		b.err = fmt.Errorf("Unable to marshal GraphQL variable %s: %s", name, err)
+	}
+	if b.endpoint.GraphQL == nil {
+		b.endpoint.GraphQL = &GraphQLMatcher{}
+	}
+	if b.endpoint.GraphQL.Variables == nil {
+		b.endpoint.GraphQL.Variables = map[string]json.RawMessage{}
+	}
+	b.endpoint.GraphQL.Variables[name] = raw
+	return b
+}
+
+// WithSOAPAction requires matched SOAP requests to call the action
+func (b *EndpointBuilder) WithSOAPAction(action string) *EndpointBuilder {
+	if b.endpoint.SOAP == nil {
+		b.endpoint.SOAP = &SOAPMatcher{}
+	}
+	b.endpoint.SOAP.Action = action
+	return b
+}
+
+// WithSOAPBodyElement requires the first child of the body of matched SOAP requests to be the element,
+// given by its local name or as "{namespace}name"
+func (b *EndpointBuilder) WithSOAPBodyElement(name string) *EndpointBuilder {
+	if b.endpoint.SOAP == nil {
+		b.endpoint.SOAP = &SOAPMatcher{}
+	}
+	b.endpoint.SOAP.BodyElement = name
+	return b
+}
+
+// WithTags adds tags grouping the endpoint with related ones
+func (b *EndpointBuilder) WithTags(tags ...string) *EndpointBuilder {
+	b.endpoint.Tags = append(b.endpoint.Tags, tags...)
+	return b
+}
+
+// WithPriority sets the priority of the endpoint over other endpoints matching the same requests, highest first
+func (b *EndpointBuilder) WithPriority(priority int) *EndpointBuilder {
+	b.endpoint.Priority = priority
+	return b
+}
+
+// WithTTL removes the endpoint once the duration has passed since it was created
+func (b *EndpointBuilder) WithTTL(ttl time.Duration) *EndpointBuilder {
+	if b.err == nil && ttl < 0 {
+		b.err = ErrInvalidLimit
+	}
+	b.endpoint.TTL = Duration(ttl)
+	return b
+}
+
+// Times removes the endpoint once it was matched n times, so later requests fall through to other endpoints
+func (b *EndpointBuilder) Times(n int) *EndpointBuilder {
+	if b.err == nil && n < 0 {
+		b.err = ErrInvalidLimit
+	}
+	b.endpoint.MaxMatches = n
+	return b
+}
+
+// Expected marks the endpoint as one that must be matched at least once, see TestServer
+func (b *EndpointBuilder) Expected() *EndpointBuilder {
+	b.endpoint.Expected = true
+	return b
+}
+
+// WillReturn sets the status code of the response and continues with the rest of the response
+func (b *EndpointBuilder) WillReturn(statusCode int) *ResponseBuilder {
+	if b.err == nil && !validStatusCode(statusCode) {
+		b.err = ErrInvalidStatusCode
+	}
+	b.endpoint.StatusCode = statusCode
+	return &ResponseBuilder{builder: b}
+}
+
+// Build returns the mock endpoint, or the first error encountered while building or validating it
+func (b *EndpointBuilder) Build() (*MockEndpoint, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if err := b.endpoint.Validate(); err != nil {
+		return nil, err
+	}
+	return b.endpoint, nil
+}
+
+// MustBuild returns the mock endpoint and panics if an error was encountered while building it
+func (b *EndpointBuilder) MustBuild() *MockEndpoint {
+	endpoint, err := b.Build()
+	if err != nil {
+		panic(fmt.Sprintf("mockservice: unable to build %s %s: %s", b.endpoint.Method, b.endpoint.Endpoint, err))
+	}
+	return endpoint
+}
+
+// WithHeader adds the header to the response
+func (r *ResponseBuilder) WithHeader(key, value string) *ResponseBuilder {
+	b := r.builder
+	if b.err == nil && strings.TrimSpace(key) == "" {
+		b.err = ErrEmptyHeaderName
+	}
+	if b.endpoint.ResponseHeaders == nil {
+		b.endpoint.ResponseHeaders = map[string]string{}
+	}
+	b.endpoint.ResponseHeaders[key] = value
+	return r
+}
+
+// WithBody sets the body of the response
+func (r *ResponseBuilder) WithBody(body string) *ResponseBuilder {
+	r.builder.endpoint.ResponseBody = body
+	return r
+}
+
+// WithTrailer adds the trailer to the response, sent after the body
+func (r *ResponseBuilder) WithTrailer(key, value string) *ResponseBuilder {
+	b := r.builder
+	if b.err == nil && strings.TrimSpace(key) == "" {
+		b.err = ErrEmptyHeaderName
+	}
+	if b.endpoint.ResponseTrailers == nil {
+		b.endpoint.ResponseTrailers = map[string]string{}
+	}
+	b.endpoint.ResponseTrailers[key] = value
+	return r
+}
+
+// InChunks streams the response body in chunks of size bytes, waiting for delay between chunks
+func (r *ResponseBuilder) InChunks(size int, delay time.Duration) *ResponseBuilder {
+	if r.builder.err == nil && size <= 0 {
+		r.builder.err = ErrInvalidChunkSize
+	}
+	r.builder.endpoint.ChunkSize = size
+	r.builder.endpoint.ChunkDelay = Duration(delay)
+	return r
+}
+
+// WithJSONBody sets the body of the response to the value marshaled as JSON, along with a JSON Content-Type header
+func (r *ResponseBuilder) WithJSONBody(v interface{}) *ResponseBuilder {
+	body, err := json.Marshal(v)
+	if err != nil && r.builder.err == nil {
+		r.builder.err = fmt.Errorf("Unable to marshal JSON response body: %s", err)
+	}
+	r.builder.endpoint.ResponseBody = string(body)
+	return r.WithHeader("Content-Type", "application/json")
+}
+
+// WithBodySchema responds with fake JSON generated from the JSON schema, which is deterministic when seed is not zero
+func (r *ResponseBuilder) WithBodySchema(schema string, seed int64) *ResponseBuilder {
+	r.builder.endpoint.ResponseSchema = &ResponseSchema{Schema: json.RawMessage(schema), Seed: seed}
+	return r
+}
+
+// WithGraphQLData responds with the GraphQL data marshaled as JSON
+func (r *ResponseBuilder) WithGraphQLData(data interface{}) *ResponseBuilder {
+	raw, err := json.Marshal(data)
+	if err != nil && r.builder.err == nil {
+		r.builder.err = fmt.Errorf("Unable to marshal GraphQL data: %s", err)
+	}
+	r.graphQLResponse().Data = raw
+	return r
+}
+
+// WithGraphQLError adds a GraphQL error with the message to the response
+func (r *ResponseBuilder) WithGraphQLError(message string) *ResponseBuilder {
+	response := r.graphQLResponse()
+	errs := []map[string]interface{}{}
+	if len(response.Errors) > 0 {
+		json.Unmarshal(response.Errors, &errs)
+	}
+	response.Errors, _ = json.Marshal(append(errs, map[string]interface{}{"message": message}))
+	return r
+}
+
+// WithSOAPFault responds with a SOAP fault with the code, such as "Client" or "Server", and the reason
+func (r *ResponseBuilder) WithSOAPFault(code, reason string) *ResponseBuilder {
+	r.builder.endpoint.SOAPFault = &SOAPFault{Code: code, Reason: reason}
+	return r
+}
+
+func (r *ResponseBuilder) graphQLResponse() *GraphQLResponse {
+	if r.builder.endpoint.GraphQLResponse == nil {
+		r.builder.endpoint.GraphQLResponse = &GraphQLResponse{}
+	}
+	return r.builder.endpoint.GraphQLResponse
+}
+
+// Build returns the mock endpoint, or the first error encountered while building or validating it
+func (r *ResponseBuilder) Build() (*MockEndpoint, error) {
+	return r.builder.Build()
+}
+
+// MustBuild returns the mock endpoint and panics if an error was encountered while building it
+func (r *ResponseBuilder) MustBuild() *MockEndpoint {
+	return r.builder.MustBuild()
+}
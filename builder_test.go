@@ -0,0 +1,96 @@
+package mockservice_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/wchan2/mock_service"
+)
+
+func TestEndpointBuilder(t *testing.T) {
+	t.Run("Build", func(t *testing.T) {
+		endpoint, err := mockservice.Get("/users/{id}").
+			WithHeader("Authorization", "Bearer token").
+			WillReturn(http.StatusOK).
+			WithHeader("X-Request-Id", "1").
+			WithJSONBody(map[string]string{"name": "gopher"}).
+			Build()
+		if err != nil {
+			t.Fatalf("Expected build error to be nil but got %s", err)
+		}
+
+		if endpoint.Method != http.MethodGet || endpoint.Endpoint != "/users/{id}" || endpoint.StatusCode != http.StatusOK {
+			t.Errorf("Expected GET /users/{id} returning 200 but got %+v", endpoint)
+		}
+
+		if endpoint.RequestHeaders["Authorization"] != "Bearer token" {
+			t.Errorf("Expected the Authorization request header but got %+v", endpoint.RequestHeaders)
+		}
+
+		if endpoint.ResponseHeaders["X-Request-Id"] != "1" || endpoint.ResponseHeaders["Content-Type"] != "application/json" {
+			t.Errorf("Expected the response headers but got %+v", endpoint.ResponseHeaders)
+		}
+
+		if endpoint.ResponseBody != `{"name":"gopher"}` {
+			t.Errorf(`Expected {"name":"gopher"} response body but got %s`, endpoint.ResponseBody)
+		}
+	})
+
+	t.Run("Serve_built_endpoint", func(t *testing.T) {
+		endpoints := mockservice.NewEndpoints()
+		endpoints.Create(mockservice.Get("/users/{id}").WithHeader("Authorization", "Bearer token").WillReturn(http.StatusAccepted).MustBuild())
+		svc := mockservice.NewEndpointService(endpoints)
+
+		req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		recorder := httptest.NewRecorder()
+		svc.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusAccepted {
+			t.Errorf("Expected %d status but got %d", http.StatusAccepted, recorder.Code)
+		}
+
+		recorder = httptest.NewRecorder()
+		svc.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected %d status without the request header but got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+
+	t.Run("Validation_errors", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			builder interface {
+				Build() (*mockservice.MockEndpoint, error)
+			}
+			expected error
+		}{
+			{"Empty_endpoint", mockservice.Get(" ").WillReturn(http.StatusOK), mockservice.ErrEmptyEndpoint},
+			{"Empty_method", mockservice.NewEndpointBuilder("", "/test"), mockservice.ErrEmptyHTTPMethod},
+			{"Invalid_template", mockservice.Get("/users/{id"), mockservice.ErrInvalidPathTemplate},
+			{"Invalid_status", mockservice.Get("/test").WillReturn(1000), mockservice.ErrInvalidStatusCode},
+			{"Empty_header", mockservice.Get("/test").WithHeader(" ", "value"), mockservice.ErrEmptyHeaderName},
+			{"First_error_wins", mockservice.Get("/test").WillReturn(0).WithHeader("", "value"), mockservice.ErrInvalidStatusCode},
+			{"Negative_TTL", mockservice.Get("/test").WithTTL(-time.Second), mockservice.ErrInvalidLimit},
+			{"Negative_times", mockservice.Get("/test").Times(-1), mockservice.ErrInvalidLimit},
+			{"Invalid_body_schema", mockservice.Get("/test").WillReturn(http.StatusOK).WithBodySchema("", 0), mockservice.ErrInvalidResponseSchema},
+		}
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				if endpoint, err := c.builder.Build(); err != c.expected || endpoint != nil {
+					t.Errorf("Expected %s error and a nil endpoint but got %v and %+v", c.expected, err, endpoint)
+				}
+			})
+		}
+	})
+
+	t.Run("MustBuild_panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected MustBuild to panic for an invalid endpoint")
+			}
+		}()
+		mockservice.Get("").MustBuild()
+	})
+}
@@ -0,0 +1,141 @@
+package mockservice
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxCapturedBody is the number of bytes of a response body kept in the journal
+const maxCapturedBody = 64 << 10
+
+// RecordedResponse is the response sent by the mock service to a recorded request
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode" xml:"statusCode"`
+	Headers    http.Header `json:"headers" xml:"-"`
+	Trailers   http.Header `json:"trailers,omitempty" xml:"-"`
+	// Body is the response body, truncated to its first 64 KiB
+	Body      string   `json:"body,omitempty" xml:"body,omitempty"`
+	Truncated bool     `json:"truncated,omitempty" xml:"truncated,omitempty"`
+	Duration  Duration `json:"duration" xml:"duration"`
+}
+
+// responseCapture is a ResponseWriter that keeps a copy of the response written to the underlying ResponseWriter
+type responseCapture struct {
+	http.ResponseWriter
+	start    time.Time
+	recorded *RecordedRequest
+	response RecordedResponse
+	body     []byte
+	written  bool
+}
+
+// capturingWriter is implemented by every ResponseWriter returned by captureResponse
+type capturingWriter interface {
+	capture() *responseCapture
+}
+
+// hijackableCapture captures the response of a ResponseWriter that supports taking over the connection
+type hijackableCapture struct {
+	*responseCapture
+}
+
+// pushableCapture captures the response of a ResponseWriter that supports HTTP/2 server push
+type pushableCapture struct {
+	*responseCapture
+}
+
+// captureResponse wraps the ResponseWriter to capture the response, keeping the optional interfaces it implements so
+// that WebSocket upgrades and server pushes behave the same
+func captureResponse(w http.ResponseWriter) (http.ResponseWriter, *responseCapture) {
+	capture := &responseCapture{ResponseWriter: w, start: time.Now()}
+	if _, ok := w.(http.Hijacker); ok {
+		return &hijackableCapture{capture}, capture
+	}
+	if _, ok := w.(http.Pusher); ok {
+		return &pushableCapture{capture}, capture
+	}
+	return capture, capture
+}
+
+func (c *responseCapture) capture() *responseCapture {
+	return c
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (c *responseCapture) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+func (c *responseCapture) WriteHeader(statusCode int) {
+	if !c.written {
+		c.written = true
+		c.response.StatusCode = statusCode
+		c.response.Headers = c.Header().Clone()
+	}
+	c.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (c *responseCapture) Write(data []byte) (int, error) {
+	if !c.written {
+		c.WriteHeader(http.StatusOK)
+	}
+	if remaining := maxCapturedBody - len(c.body); len(data) > remaining {
+		c.body = append(c.body, data[:remaining]...)
+		c.response.Truncated = true
+	} else {
+		c.body = append(c.body, data...)
+	}
+	return c.ResponseWriter.Write(data)
+}
+
+func (c *responseCapture) Flush() {
+	if !c.written {
+		c.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (c *hijackableCapture) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := c.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil && !c.written {
+		c.written = true
+		c.response.StatusCode = http.StatusSwitchingProtocols
+		c.response.Headers = c.Header().Clone()
+	}
+	return conn, rw, err
+}
+
+func (c *pushableCapture) Push(target string, opts *http.PushOptions) error {
+	return c.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+// finish returns the captured response once the handler has returned, with the trailers set after the body
+func (c *responseCapture) finish() *RecordedResponse {
+	response := c.response
+	if !c.written {
+		response.StatusCode = http.StatusOK
+		response.Headers = c.Header().Clone()
+	}
+	response.Body = string(c.body)
+	response.Duration = Duration(time.Since(c.start))
+	declared := map[string]bool{}
+	for _, keys := range response.Headers["Trailer"] {
+		for _, key := range strings.Split(keys, ",") {
+			declared[http.CanonicalHeaderKey(strings.TrimSpace(key))] = true
+		}
+	}
+	for key, values := range c.Header() {
+		if trimmed := strings.TrimPrefix(key, http.TrailerPrefix); trimmed != key || declared[key] {
+			if response.Trailers == nil {
+				response.Trailers = http.Header{}
+			}
+			response.Trailers[http.CanonicalHeaderKey(trimmed)] = append([]string{}, values...)
+		}
+	}
+	return &response
+}
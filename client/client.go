@@ -0,0 +1,427 @@
+// Package client is a Go client for the registration endpoint of a running mock service.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wchan2/mock_service"
+)
+
+var (
+	// ErrBadRequest is matched by errors for requests the mock service rejected as invalid
+	ErrBadRequest = errors.New("Bad request")
+
+	// ErrNotFound is matched by errors for mock endpoints or routes that do not exist
+	ErrNotFound = errors.New("Not found")
+)
+
+// knownErrors are the mock service errors that can be recognized from a response body
+var knownErrors = []error{
+	mockservice.ErrEndpointDoesNotExist,
+	mockservice.ErrEmptyHTTPMethod,
+	mockservice.ErrEmptyEndpoint,
+	mockservice.ErrSessionDoesNotExist,
+	mockservice.ErrTooManySessions,
+}
+
+// Error is returned when the mock service responds with an unsuccessful status code
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("mock service responded with %d: %s", e.StatusCode, e.Message)
+}
+
+// Is matches ErrBadRequest and ErrNotFound by status code
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// Unwrap returns the mock service error described by the response, if it is a known one
+func (e *Error) Unwrap() error {
+	for _, err := range knownErrors {
+		if e.Message == err.Error() {
+			return err
+		}
+	}
+	return nil
+}
+
+// Client manages the mock endpoints of a running mock service
+type Client struct {
+	registrationURL string
+	httpClient      *http.Client
+	session         string
+}
+
+// New creates a client for the mock service with the registration endpoint URL, e.g. "http://localhost:8080/mocks"
+func New(registrationURL string) *Client {
+	return NewWithHTTPClient(registrationURL, http.DefaultClient)
+}
+
+// NewWithHTTPClient creates a client that sends its requests with the HTTP client
+func NewWithHTTPClient(registrationURL string, httpClient *http.Client) *Client {
+	return &Client{registrationURL: strings.TrimSuffix(registrationURL, "/"), httpClient: httpClient}
+}
+
+// Session returns a client managing the mock endpoints and recorded requests of the session, isolated from those of
+// other sessions. The requests of the code under test must carry the session too, in the mockservice.SessionHeader
+// header or under the mockservice.SessionPathPrefix path prefix.
+func (c *Client) Session(id string) *Client {
+	return &Client{registrationURL: c.registrationURL, httpClient: c.httpClient, session: id}
+}
+
+// Sessions returns the IDs of the sessions in use
+func (c *Client) Sessions(ctx context.Context) ([]string, error) {
+	ids := []string{}
+	if err := c.do(ctx, http.MethodGet, "/sessions", nil, nil, &ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// DeleteSession removes the session along with its mock endpoints and recorded requests
+func (c *Client) DeleteSession(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/sessions", url.Values{"id": {id}}, nil, nil)
+}
+
+// Create registers the mock endpoint, replacing any mock endpoint for the same HTTP method and URL path
+func (c *Client) Create(ctx context.Context, endpoint *mockservice.MockEndpoint) error {
+	return c.do(ctx, http.MethodPost, "", nil, endpoint, nil)
+}
+
+// Update replaces a registered mock endpoint, returning an error matching ErrNotFound if it does not exist
+func (c *Client) Update(ctx context.Context, endpoint *mockservice.MockEndpoint) error {
+	return c.do(ctx, http.MethodPut, "", nil, endpoint, nil)
+}
+
+// Delete removes the mock endpoint for the HTTP method and URL path, returning an error matching ErrNotFound if it does not exist
+func (c *Client) Delete(ctx context.Context, method, endpoint string) error {
+	return c.do(ctx, http.MethodDelete, "", url.Values{"method": {method}, "endpoint": {endpoint}}, nil, nil)
+}
+
+// List returns the registered mock endpoints
+func (c *Client) List(ctx context.Context) ([]*mockservice.MockEndpoint, error) {
+	endpoints := []*mockservice.MockEndpoint{}
+	if err := c.do(ctx, http.MethodGet, "", nil, nil, &endpoints); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// ListTagged returns the registered mock endpoints that have all the tags
+func (c *Client) ListTagged(ctx context.Context, tags ...string) ([]*mockservice.MockEndpoint, error) {
+	endpoints := []*mockservice.MockEndpoint{}
+	if err := c.do(ctx, http.MethodGet, "", url.Values{"tag": tags}, nil, &endpoints); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// Reset removes all the mock endpoints and recorded requests
+func (c *Client) Reset(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/reset", nil, nil, nil)
+}
+
+// Requests returns the requests recorded by the mock service in the order they were received
+func (c *Client) Requests(ctx context.Context) ([]*mockservice.RecordedRequest, error) {
+	return c.RequestsSince(ctx, 0)
+}
+
+// RequestsSince returns the recorded requests after skipping the first offset requests
+func (c *Client) RequestsSince(ctx context.Context, offset int) ([]*mockservice.RecordedRequest, error) {
+	requests := []*mockservice.RecordedRequest{}
+	query := url.Values{"offset": {strconv.Itoa(offset)}}
+	if err := c.do(ctx, http.MethodGet, "/requests", query, nil, &requests); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// ClearRequests removes all the recorded requests
+func (c *Client) ClearRequests(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, "/requests", nil, nil, nil)
+}
+
+// Verify returns the number of recorded requests matching the verification, or a *mockservice.VerificationError if it is not satisfied
+func (c *Client) Verify(ctx context.Context, v mockservice.Verification) (int, error) {
+	result := mockservice.VerificationResult{}
+	err := c.do(ctx, http.MethodPost, "/verify", nil, v, &result)
+	if respErr, ok := err.(*Error); ok && respErr.StatusCode == http.StatusExpectationFailed {
+		if json.Unmarshal([]byte(respErr.Message), &result) == nil {
+			return result.Count, &mockservice.VerificationError{Verification: v, Actual: result.Count}
+		}
+	}
+	return result.Count, err
+}
+
+// PushEvent sends the server-sent event to the clients connected to the mock endpoint and returns how many received it
+func (c *Client) PushEvent(ctx context.Context, method, endpoint string, event mockservice.ServerSentEvent) (int, error) {
+	result := map[string]int{}
+	query := url.Values{"method": {method}, "endpoint": {endpoint}}
+	if err := c.do(ctx, http.MethodPost, "/events", query, event, &result); err != nil {
+		return 0, err
+	}
+	return result["clients"], nil
+}
+
+// PushWebSocketMessage sends the text message to the WebSocket clients connected to the mock endpoint and returns how many received it
+func (c *Client) PushWebSocketMessage(ctx context.Context, method, endpoint, text string) (int, error) {
+	result := map[string]int{}
+	query := url.Values{"method": {method}, "endpoint": {endpoint}}
+	if err := c.doRaw(ctx, http.MethodPost, "/websocket", query, []byte(text), &result); err != nil {
+		return 0, err
+	}
+	return result["clients"], nil
+}
+
+// Frames returns the WebSocket messages received from the clients of the mock endpoints
+func (c *Client) Frames(ctx context.Context) ([]*mockservice.RecordedFrame, error) {
+	frames := []*mockservice.RecordedFrame{}
+	if err := c.do(ctx, http.MethodGet, "/frames", nil, nil, &frames); err != nil {
+		return nil, err
+	}
+	return frames, nil
+}
+
+// ImportOpenAPI registers the mock endpoints generated from an OpenAPI 3 document in JSON or YAML and returns them
+func (c *Client) ImportOpenAPI(ctx context.Context, spec []byte, options *mockservice.OpenAPIOptions) ([]*mockservice.MockEndpoint, error) {
+	query := url.Values{}
+	if options != nil {
+		if options.BasePath != "" {
+			query.Set("basePath", options.BasePath)
+		}
+		if options.ValidateRequests {
+			query.Set("validate", "true")
+		}
+		if options.FakeResponses {
+			query.Set("fake", "true")
+		}
+		if options.Seed != 0 {
+			query.Set("seed", strconv.FormatInt(options.Seed, 10))
+		}
+		for operation, statusCode := range options.StatusCodes {
+			query.Add("status", fmt.Sprintf("%s:%d", operation, statusCode))
+		}
+	}
+	endpoints := []*mockservice.MockEndpoint{}
+	if err := c.doRaw(ctx, http.MethodPost, "/openapi", query, spec, &endpoints); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// ImportHAR registers the mock endpoints imported from the HAR log, see mockservice.ImportHAR, and returns them
+func (c *Client) ImportHAR(ctx context.Context, har []byte, options *mockservice.HAROptions) ([]*mockservice.MockEndpoint, error) {
+	query := url.Values{}
+	if options != nil {
+		for _, host := range options.Hosts {
+			query.Add("host", host)
+		}
+		for _, header := range options.RequestHeaders {
+			query.Add("header", header)
+		}
+		for _, header := range options.StripHeaders {
+			query.Add("strip", header)
+		}
+		if options.PathPrefix != "" {
+			query.Set("pathPrefix", options.PathPrefix)
+		}
+		if options.MatchBody {
+			query.Set("matchBody", "true")
+		}
+		if options.Deduplicate {
+			query.Set("dedupe", "true")
+		}
+		if options.StripVolatileHeaders {
+			query.Set("stripVolatile", "true")
+		}
+	}
+	endpoints := []*mockservice.MockEndpoint{}
+	if err := c.doRaw(ctx, http.MethodPost, "/har", query, har, &endpoints); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// ImportPostman registers the mock endpoints imported from the Postman collection, see mockservice.ImportPostman, and
+// returns them
+func (c *Client) ImportPostman(ctx context.Context, collection []byte, options *mockservice.PostmanOptions) ([]*mockservice.MockEndpoint, error) {
+	query := url.Values{}
+	if options != nil {
+		names := make([]string, 0, len(options.Variables))
+		for name := range options.Variables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			query.Add("variable", name+"="+options.Variables[name])
+		}
+		for _, header := range options.RequestHeaders {
+			query.Add("header", header)
+		}
+		if options.MatchBody {
+			query.Set("matchBody", "true")
+		}
+	}
+	endpoints := []*mockservice.MockEndpoint{}
+	if err := c.doRaw(ctx, http.MethodPost, "/postman", query, collection, &endpoints); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// ImportPact registers the mock endpoints imported from the Pact contract, see mockservice.ImportPact, and returns them
+func (c *Client) ImportPact(ctx context.Context, pact []byte) ([]*mockservice.MockEndpoint, error) {
+	endpoints := []*mockservice.MockEndpoint{}
+	if err := c.doRaw(ctx, http.MethodPost, "/pact", nil, pact, &endpoints); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// PactReport reports which of the imported Pact interactions were exercised
+func (c *Client) PactReport(ctx context.Context) (*mockservice.PactReport, error) {
+	report := &mockservice.PactReport{}
+	if err := c.do(ctx, http.MethodGet, "/pact", nil, nil, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Scenarios returns the current scenario states
+func (c *Client) Scenarios(ctx context.Context) ([]mockservice.ScenarioState, error) {
+	states := []mockservice.ScenarioState{}
+	if err := c.do(ctx, http.MethodGet, "/scenarios", nil, nil, &states); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+// SetScenarioState moves the scenario to the state, such as the provider state of Pact interactions
+func (c *Client) SetScenarioState(ctx context.Context, name, state string) error {
+	return c.do(ctx, http.MethodPut, "/scenarios", nil, mockservice.ScenarioState{Name: name, State: state}, nil)
+}
+
+// ResetScenarios moves all the scenarios back to mockservice.ScenarioStarted
+func (c *Client) ResetScenarios(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, "/scenarios", nil, nil, nil)
+}
+
+// ExportHAR returns the recorded requests and the responses sent to them as a HAR 1.2 log, see mockservice.ExportHAR
+func (c *Client) ExportHAR(ctx context.Context) ([]byte, error) {
+	har := []byte{}
+	if err := c.do(ctx, http.MethodGet, "/har", nil, nil, &har); err != nil {
+		return nil, err
+	}
+	return har, nil
+}
+
+// ExportEndpointsHAR returns the registered mock endpoints as a HAR 1.2 log, see mockservice.ExportEndpointsHAR
+func (c *Client) ExportEndpointsHAR(ctx context.Context) ([]byte, error) {
+	har := []byte{}
+	if err := c.do(ctx, http.MethodGet, "/har", url.Values{"source": {"mocks"}}, nil, &har); err != nil {
+		return nil, err
+	}
+	return har, nil
+}
+
+// Snapshot returns the registered mock endpoints and scenario states
+func (c *Client) Snapshot(ctx context.Context) (*mockservice.Snapshot, error) {
+	snapshot := &mockservice.Snapshot{}
+	if err := c.do(ctx, http.MethodGet, "/snapshot", nil, nil, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Restore replaces the registered mock endpoints and scenario states with those of the snapshot
+func (c *Client) Restore(ctx context.Context, snapshot *mockservice.Snapshot) error {
+	return c.do(ctx, http.MethodPut, "/snapshot", nil, snapshot, nil)
+}
+
+// CertificateAuthority returns the PEM encoded certificate of the certificate authority the mock service serves HTTPS with
+func (c *Client) CertificateAuthority(ctx context.Context) ([]byte, error) {
+	pem := []byte{}
+	if err := c.do(ctx, http.MethodGet, "/ca.pem", nil, nil, &pem); err != nil {
+		return nil, err
+	}
+	return pem, nil
+}
+
+// do sends a request to the route under the registration endpoint and decodes a JSON response into v when provided,
+// or copies the raw response body when v is a *[]byte
+func (c *Client) do(ctx context.Context, method, route string, query url.Values, body interface{}, v interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	return c.doRaw(ctx, method, route, query, payload, v)
+}
+
+// doRaw sends the payload as the request body, see do
+func (c *Client) doRaw(ctx context.Context, method, route string, query url.Values, payload []byte, v interface{}) error {
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+
+	reqURL := c.registrationURL + route
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, reqBody)
+	if err != nil {
+		return err
+	}
+	if payload != nil && json.Valid(payload) {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.session != "" {
+		req.Header.Set(mockservice.SessionHeader, c.session)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(respBody))}
+	}
+	if raw, ok := v.(*[]byte); ok {
+		*raw = respBody
+		return nil
+	}
+	if v != nil {
+		return json.Unmarshal(respBody, v)
+	}
+	return nil
+}
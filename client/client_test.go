@@ -0,0 +1,292 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wchan2/mock_service"
+	"github.com/wchan2/mock_service/client"
+)
+
+func TestClient(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected creating the mock service to succeed but got %s", err)
+	}
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	ctx := context.Background()
+	c := client.NewWithHTTPClient(server.URL+"/mocks", server.Client())
+	endpoint := &mockservice.MockEndpoint{Method: http.MethodGet, Endpoint: "/hello", StatusCode: http.StatusOK, ResponseBody: "hi"}
+
+	t.Run("Create_and_list", func(t *testing.T) {
+		if err := c.Create(ctx, endpoint); err != nil {
+			t.Fatalf("Expected create error to be nil but got %s", err)
+		}
+
+		endpoints, err := c.List(ctx)
+		if err != nil {
+			t.Fatalf("Expected list error to be nil but got %s", err)
+		}
+
+		if len(endpoints) != 1 || endpoints[0].Endpoint != "/hello" || endpoints[0].ResponseBody != "hi" {
+			t.Errorf("Expected the created endpoint but got %+v", endpoints)
+		}
+	})
+
+	t.Run("Create_validation_error", func(t *testing.T) {
+		err := c.Create(ctx, &mockservice.MockEndpoint{Method: http.MethodGet})
+		if !errors.Is(err, client.ErrBadRequest) || !errors.Is(err, mockservice.ErrEmptyEndpoint) {
+			t.Errorf("Expected a bad request error for an empty endpoint but got %v", err)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		updated := *endpoint
+		updated.StatusCode = http.StatusAccepted
+		if err := c.Update(ctx, &updated); err != nil {
+			t.Errorf("Expected update error to be nil but got %s", err)
+		}
+
+		missing := updated
+		missing.Endpoint = "/missing"
+		if err := c.Update(ctx, &missing); !errors.Is(err, client.ErrNotFound) || !errors.Is(err, mockservice.ErrEndpointDoesNotExist) {
+			t.Errorf("Expected a not found error when updating a missing endpoint but got %v", err)
+		}
+	})
+
+	t.Run("Requests_and_verify", func(t *testing.T) {
+		resp, err := server.Client().Get(server.URL + "/hello")
+		if err != nil {
+			t.Fatalf("Expected requesting the mock to succeed but got %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusAccepted {
+			t.Errorf("Expected the updated status %d but got %d", http.StatusAccepted, resp.StatusCode)
+		}
+
+		requests, err := c.Requests(ctx)
+		if err != nil || len(requests) != 1 || requests[0].Endpoint != "/hello" {
+			t.Errorf("Expected the recorded request but got %+v (%v)", requests, err)
+		}
+
+		if count, err := c.Verify(ctx, mockservice.Verification{Method: http.MethodGet, Endpoint: "/hello"}); err != nil || count != 1 {
+			t.Errorf("Expected verification to succeed with a count of 1 but got %d (%v)", count, err)
+		}
+
+		two := 2
+		_, err = c.Verify(ctx, mockservice.Verification{Method: http.MethodGet, Endpoint: "/hello", Count: &two})
+		verificationErr, ok := err.(*mockservice.VerificationError)
+		if !ok || verificationErr.Actual != 1 {
+			t.Errorf("Expected a verification error with an actual count of 1 but got %v", err)
+		}
+	})
+
+	t.Run("Delete_and_reset", func(t *testing.T) {
+		if err := c.Delete(ctx, http.MethodGet, "/hello"); err != nil {
+			t.Errorf("Expected delete error to be nil but got %s", err)
+		}
+
+		if err := c.Delete(ctx, http.MethodGet, "/hello"); !errors.Is(err, client.ErrNotFound) {
+			t.Errorf("Expected a not found error when deleting a missing endpoint but got %v", err)
+		}
+
+		if err := c.Reset(ctx); err != nil {
+			t.Errorf("Expected reset error to be nil but got %s", err)
+		}
+
+		if requests, err := c.Requests(ctx); err != nil || len(requests) != 0 {
+			t.Errorf("Expected no recorded requests after a reset but got %+v (%v)", requests, err)
+		}
+	})
+
+	t.Run("Certificate_authority", func(t *testing.T) {
+		if _, err := c.CertificateAuthority(ctx); !errors.Is(err, client.ErrNotFound) {
+			t.Errorf("Expected a not found error without a certificate authority but got %v", err)
+		}
+
+		ca, err := mockservice.NewCertificateAuthority()
+		if err != nil {
+			t.Fatalf("Expected generating a certificate authority to succeed but got %s", err)
+		}
+		service.UseCertificateAuthority(ca)
+		pem, err := c.CertificateAuthority(ctx)
+		if err != nil || string(pem) != string(ca.CertificatePEM()) {
+			t.Errorf("Expected the certificate authority's PEM but got %s (%v)", pem, err)
+		}
+	})
+
+	t.Run("Import_OpenAPI", func(t *testing.T) {
+		spec := []byte("openapi: 3.0.0\npaths:\n  /pets:\n    get:\n      operationId: listPets\n      responses:\n        '200':\n          description: ok\n        '503':\n          description: unavailable\n")
+		endpoints, err := c.ImportOpenAPI(ctx, spec, &mockservice.OpenAPIOptions{
+			BasePath:    "/v1",
+			StatusCodes: map[string]int{"listPets": http.StatusServiceUnavailable},
+		})
+		if err != nil {
+			t.Fatalf("Expected importing the OpenAPI document to succeed but got %s", err)
+		}
+		if len(endpoints) != 1 || endpoints[0].Endpoint != "/v1/pets" || endpoints[0].StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("Expected the imported endpoint but got %+v", endpoints)
+		}
+
+		if _, err := c.ImportOpenAPI(ctx, []byte(`{"swagger":"2.0"}`), nil); !errors.Is(err, client.ErrBadRequest) {
+			t.Errorf("Expected a bad request error for a Swagger 2 document but got %v", err)
+		}
+	})
+
+	t.Run("Import_HAR", func(t *testing.T) {
+		har := []byte(`{"log": {"entries": [
+			{"request": {"method": "GET", "url": "https://staging.example.com/health"}, "response": {"status": 200, "content": {"text": "ok"}}},
+			{"request": {"method": "GET", "url": "https://cdn.example.com/app.js"}, "response": {"status": 200, "content": {"text": ""}}}
+		]}}`)
+		endpoints, err := c.ImportHAR(ctx, har, &mockservice.HAROptions{Hosts: []string{"staging.example.com"}, StripVolatileHeaders: true})
+		if err != nil {
+			t.Fatalf("Expected importing the HAR log to succeed but got %s", err)
+		}
+		if len(endpoints) != 1 || endpoints[0].Endpoint != "/health" || endpoints[0].ResponseBody != "ok" {
+			t.Errorf("Expected the imported endpoint but got %+v", endpoints)
+		}
+	})
+
+	t.Run("Import_Postman", func(t *testing.T) {
+		collection := []byte(`{"info": {"name": "Partner"}, "item": [{"name": "Orders", "item": [
+			{"name": "List", "request": {"method": "GET", "url": "{{baseUrl}}/orders"}, "response": [{"code": 200, "body": "[]"}]}
+		]}]}`)
+		endpoints, err := c.ImportPostman(ctx, collection, &mockservice.PostmanOptions{Variables: map[string]string{"baseUrl": "http://partner"}})
+		if err != nil {
+			t.Fatalf("Expected importing the Postman collection to succeed but got %s", err)
+		}
+		if len(endpoints) != 1 || endpoints[0].Endpoint != "/orders" {
+			t.Errorf("Expected the imported endpoint but got %+v", endpoints)
+		}
+
+		tagged, err := c.ListTagged(ctx, "Orders")
+		if err != nil || len(tagged) != 1 || tagged[0].Endpoint != "/orders" {
+			t.Errorf("Expected the endpoint tagged Orders but got %+v, %v", tagged, err)
+		}
+	})
+
+	t.Run("Pact", func(t *testing.T) {
+		pact := []byte(`{"consumer": {"name": "web"}, "provider": {"name": "users"}, "interactions": [
+			{"description": "a user", "providerStates": [{"name": "a user exists"}], "request": {"method": "GET", "path": "/users/1"}, "response": {"status": 200}}
+		]}`)
+		endpoints, err := c.ImportPact(ctx, pact)
+		if err != nil {
+			t.Fatalf("Expected importing the Pact contract to succeed but got %s", err)
+		}
+		if len(endpoints) != 1 || endpoints[0].RequiredState != "a user exists" {
+			t.Errorf("Expected the imported interaction but got %+v", endpoints)
+		}
+
+		if err := c.SetScenarioState(ctx, mockservice.PactScenario("web", "users"), "a user exists"); err != nil {
+			t.Fatalf("Expected setting the scenario state to succeed but got %s", err)
+		}
+		resp, err := server.Client().Get(server.URL + "/users/1")
+		if err != nil {
+			t.Fatalf("Expected the request to succeed but got %s", err)
+		}
+		resp.Body.Close()
+
+		report, err := c.PactReport(ctx)
+		if err != nil || len(report.Interactions) != 1 || report.Interactions[0].Count != 1 || !report.Exercised {
+			t.Errorf("Expected the interaction to be exercised once but got %+v, %v", report, err)
+		}
+		scenarios, err := c.Scenarios(ctx)
+		if err != nil || len(scenarios) != 1 || scenarios[0].State != "a user exists" {
+			t.Errorf("Expected the scenario state but got %+v, %v", scenarios, err)
+		}
+		if err := c.ResetScenarios(ctx); err != nil {
+			t.Errorf("Expected resetting the scenarios to succeed but got %s", err)
+		}
+	})
+
+	t.Run("Sessions", func(t *testing.T) {
+		session := c.Session("checkout")
+		if err := session.Create(ctx, mockservice.Get("/cart").WillReturn(http.StatusOK).WithBody("[]").MustBuild()); err != nil {
+			t.Fatalf("Expected creating the mock endpoint of the session to succeed but got %s", err)
+		}
+		endpoints, err := c.List(ctx)
+		if err != nil {
+			t.Fatalf("Expected list error to be nil but got %s", err)
+		}
+		for _, endpoint := range endpoints {
+			if endpoint.Endpoint == "/cart" {
+				t.Errorf("Expected the mock endpoint of the session to be hidden from other sessions")
+			}
+		}
+
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/cart", nil)
+		req.Header.Set(mockservice.SessionHeader, "checkout")
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatalf("Expected the request to succeed but got %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected the mock endpoint of the session to match but got %d", resp.StatusCode)
+		}
+		if requests, err := session.Requests(ctx); err != nil || len(requests) != 1 {
+			t.Errorf("Expected the request in the journal of the session but got %+v, %v", requests, err)
+		}
+
+		if ids, err := session.Sessions(ctx); err != nil || len(ids) != 1 || ids[0] != "checkout" {
+			t.Errorf("Expected the checkout session but got %v, %v", ids, err)
+		}
+		if err := c.DeleteSession(ctx, "checkout"); err != nil {
+			t.Errorf("Expected deleting the session to succeed but got %s", err)
+		}
+		if err := c.DeleteSession(ctx, "checkout"); !errors.Is(err, client.ErrNotFound) || !errors.Is(err, mockservice.ErrSessionDoesNotExist) {
+			t.Errorf("Expected deleting a missing session to fail with ErrNotFound but got %v", err)
+		}
+	})
+
+	t.Run("Snapshot_and_restore", func(t *testing.T) {
+		snapshot, err := c.Snapshot(ctx)
+		if err != nil {
+			t.Fatalf("Expected taking a snapshot to succeed but got %s", err)
+		}
+		if err := c.Create(ctx, mockservice.Get("/temporary").MustBuild()); err != nil {
+			t.Fatalf("Expected create error to be nil but got %s", err)
+		}
+		if err := c.Restore(ctx, snapshot); err != nil {
+			t.Fatalf("Expected restoring the snapshot to succeed but got %s", err)
+		}
+		endpoints, err := c.List(ctx)
+		if err != nil || len(endpoints) != len(snapshot.Endpoints) {
+			t.Errorf("Expected the %d endpoints of the snapshot but got %d, %v", len(snapshot.Endpoints), len(endpoints), err)
+		}
+	})
+
+	t.Run("Export_HAR", func(t *testing.T) {
+		resp, err := server.Client().Get(server.URL + "/health")
+		if err != nil {
+			t.Fatalf("Expected the request to succeed but got %s", err)
+		}
+		resp.Body.Close()
+
+		har, err := c.ExportHAR(ctx)
+		if err != nil {
+			t.Fatalf("Expected exporting the HAR log to succeed but got %s", err)
+		}
+		if !strings.Contains(string(har), `"url": "`+server.URL+`/health"`) || !strings.Contains(string(har), `"text": "ok"`) {
+			t.Errorf("Expected the request to /health and its response in the HAR log but got %s", har)
+		}
+
+		if har, err = c.ExportEndpointsHAR(ctx); err != nil || !strings.Contains(string(har), `"status": 200`) {
+			t.Errorf("Expected the mock endpoints in the HAR log but got %s, %v", har, err)
+		}
+	})
+
+	t.Run("Canceled_context", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := c.List(canceled); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected a context canceled error but got %v", err)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package mockservice
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+)
+
+// ClientCertificateMatcher matches requests by the TLS client certificate they were sent with.
+// All the non-empty properties must match the certificate.
+type ClientCertificateMatcher struct {
+	// Absent matches requests sent without a client certificate
+	Absent bool `json:"absent,omitempty" xml:"absent,omitempty"`
+	// CommonName matches the subject common name
+	CommonName string `json:"commonName,omitempty" xml:"commonName,omitempty"`
+	// SAN matches any DNS name, IP address, email address or URI in the subject alternative names
+	SAN string `json:"san,omitempty" xml:"san,omitempty"`
+	// Issuer matches the issuer common name or the issuer's full distinguished name
+	Issuer string `json:"issuer,omitempty" xml:"issuer,omitempty"`
+}
+
+// RecordedCertificate is the TLS client certificate a request was sent with
+type RecordedCertificate struct {
+	Subject string   `json:"subject" xml:"subject"`
+	Issuer  string   `json:"issuer" xml:"issuer"`
+	SANs    []string `json:"sans,omitempty" xml:"sans,omitempty"`
+	PEM     string   `json:"pem" xml:"pem"`
+}
+
+func (c *ClientCertificateMatcher) matches(req *http.Request) bool {
+	cert := clientCertificate(req)
+	if c.Absent {
+		return cert == nil
+	}
+	if cert == nil {
+		return false
+	}
+
+	if c.CommonName != "" && cert.Subject.CommonName != c.CommonName {
+		return false
+	}
+	if c.Issuer != "" && cert.Issuer.CommonName != c.Issuer && cert.Issuer.String() != c.Issuer {
+		return false
+	}
+	if c.SAN != "" {
+		for _, san := range subjectAltNames(cert) {
+			if san == c.SAN {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// clientCertificate returns the leaf certificate the client presented, if any
+func clientCertificate(req *http.Request) *x509.Certificate {
+	if req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return req.TLS.PeerCertificates[0]
+}
+
+func subjectAltNames(cert *x509.Certificate) []string {
+	sans := append([]string{}, cert.DNSNames...)
+	sans = append(sans, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	return sans
+}
+
+// recordCertificate describes the client certificate of the request for the journal
+func recordCertificate(req *http.Request) *RecordedCertificate {
+	cert := clientCertificate(req)
+	if cert == nil {
+		return nil
+	}
+	return &RecordedCertificate{
+		Subject: cert.Subject.String(),
+		Issuer:  cert.Issuer.String(),
+		SANs:    subjectAltNames(cert),
+		PEM:     string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+	}
+}
@@ -0,0 +1,81 @@
+package mockservice_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wchan2/mock_service"
+)
+
+func TestClientCertificateMatching(t *testing.T) {
+	ca, err := mockservice.NewCertificateAuthority()
+	if err != nil {
+		t.Fatalf("Expected generating a certificate authority to succeed but got %s", err)
+	}
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	service.Endpoints().Load([]*mockservice.MockEndpoint{
+		mockservice.Get("/accounts").WithoutClientCertificate().WillReturn(http.StatusUnauthorized).MustBuild(),
+		mockservice.Get("/accounts").WithClientCertificate(mockservice.ClientCertificateMatcher{Issuer: "mockservice CA"}).WillReturn(http.StatusForbidden).MustBuild(),
+		mockservice.Get("/accounts").WithClientCertificate(mockservice.ClientCertificateMatcher{CommonName: "partner", SAN: "spiffe://bank/partner"}).WillReturn(http.StatusOK).MustBuild(),
+	})
+
+	server := httptest.NewUnstartedServer(service)
+	if server.TLS, err = ca.TLSConfig(); err != nil {
+		t.Fatalf("Expected creating the TLS config to succeed but got %s", err)
+	}
+	mockservice.RequestClientCertificates(server.TLS, ca.CertPool())
+	server.StartTLS()
+	defer server.Close()
+
+	clientWith := func(commonName string, sans ...string) *http.Client {
+		config := &tls.Config{RootCAs: ca.CertPool()}
+		if commonName != "" {
+			cert, err := ca.IssueClientCertificate(commonName, sans...)
+			if err != nil {
+				t.Fatalf("Expected issuing a client certificate to succeed but got %s", err)
+			}
+			config.Certificates = []tls.Certificate{cert}
+		}
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	}
+
+	cases := []struct {
+		name     string
+		client   *http.Client
+		expected int
+	}{
+		{"No_certificate", clientWith(""), http.StatusUnauthorized},
+		{"Accepted_identity", clientWith("partner", "spiffe://bank/partner"), http.StatusOK},
+		{"Rejected_identity", clientWith("intruder", "spiffe://bank/intruder"), http.StatusForbidden},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resp, err := c.client.Get(server.URL + "/accounts")
+			if err != nil {
+				t.Fatalf("Expected the request to succeed but got %s", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != c.expected {
+				t.Errorf("Expected %d status but got %d", c.expected, resp.StatusCode)
+			}
+		})
+	}
+
+	requests := service.Journal().Requests()
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 recorded requests but got %d", len(requests))
+	}
+	if requests[0].ClientCertificate != nil {
+		t.Errorf("Expected no recorded certificate without a client certificate but got %+v", requests[0].ClientCertificate)
+	}
+	recorded := requests[1].ClientCertificate
+	if recorded == nil || recorded.Subject != "CN=partner,O=mockservice" || !strings.HasPrefix(recorded.PEM, "-----BEGIN CERTIFICATE-----") {
+		t.Errorf("Expected the partner certificate to be recorded but got %+v", recorded)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/wchan2/mock_service"
+	"github.com/wchan2/mock_service/client"
+)
+
+// command defines the flags of a client subcommand and returns the function that runs it against the registration endpoint of a running mock service
+type command func(fs *flag.FlagSet) func(c *client.Client, out io.Writer) error
+
+var commands = map[string]command{
+	"register": register,
+	"list":     list,
+	"delete":   deleteEndpoint,
+	"reset":    reset,
+	"requests": requests,
+	"verify":   verify,
+}
+
+// runCommand parses the common client flags and runs the named subcommand
+func runCommand(name string, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("mockservice "+name, flag.ContinueOnError)
+	registrationURL := fs.String("url", envString("MOCKSERVICE_URL", "http://localhost:8080/mocks"), "URL of the registration endpoint of the mock service")
+	timeout := fs.Duration("timeout", 10*time.Second, "timeout for each request to the mock service")
+	run := commands[name](fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	c := client.NewWithHTTPClient(*registrationURL, &http.Client{Timeout: *timeout})
+	return run(c, out)
+}
+
+// headersFlag is a repeatable "Key: Value" flag
+type headersFlag map[string]string
+
+func (h headersFlag) String() string {
+	pairs := []string{}
+	for key, val := range h {
+		pairs = append(pairs, key+": "+val)
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func (h headersFlag) Set(value string) error {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("header %q must be formatted as Key: Value", value)
+	}
+	h[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	return nil
+}
+
+func register(fs *flag.FlagSet) func(c *client.Client, out io.Writer) error {
+	file := fs.String("file", "", "JSON, YAML or XML file with a mock endpoint, a list of mock endpoints or a Conf, or an OpenAPI 3 document")
+	method := fs.String("method", http.MethodGet, "HTTP method of the mock endpoint")
+	endpoint := fs.String("endpoint", "", "URL path of the mock endpoint")
+	status := fs.Int("status", http.StatusOK, "HTTP status code of the mock response")
+	body := fs.String("body", "", "body of the mock response")
+	headers := headersFlag{}
+	fs.Var(headers, "header", "header of the mock response formatted as Key: Value (repeatable)")
+
+	return func(c *client.Client, out io.Writer) error {
+		endpoints := []*mockservice.MockEndpoint{}
+		if *file != "" {
+			loaded, err := loadEndpoints(*file, nil)
+			if err != nil {
+				return err
+			}
+			endpoints = loaded
+		} else {
+			endpoints = append(endpoints, &mockservice.MockEndpoint{
+				Method:          *method,
+				Endpoint:        *endpoint,
+				StatusCode:      *status,
+				ResponseBody:    *body,
+				ResponseHeaders: headers,
+			})
+		}
+
+		for _, ep := range endpoints {
+			if err := c.Create(context.Background(), ep); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Registered %s %s\n", ep.Method, ep.Endpoint)
+		}
+		return nil
+	}
+}
+
+func list(fs *flag.FlagSet) func(c *client.Client, out io.Writer) error {
+	return func(c *client.Client, out io.Writer) error {
+		endpoints, err := c.List(context.Background())
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "METHOD\tENDPOINT\tSTATUS")
+		for _, ep := range endpoints {
+			fmt.Fprintf(w, "%s\t%s\t%d\n", ep.Method, ep.Endpoint, ep.StatusCode)
+		}
+		return w.Flush()
+	}
+}
+
+func deleteEndpoint(fs *flag.FlagSet) func(c *client.Client, out io.Writer) error {
+	method := fs.String("method", http.MethodGet, "HTTP method of the mock endpoint")
+	endpoint := fs.String("endpoint", "", "URL path of the mock endpoint")
+
+	return func(c *client.Client, out io.Writer) error {
+		if err := c.Delete(context.Background(), *method, *endpoint); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Deleted %s %s\n", *method, *endpoint)
+		return nil
+	}
+}
+
+func reset(fs *flag.FlagSet) func(c *client.Client, out io.Writer) error {
+	return func(c *client.Client, out io.Writer) error {
+		if err := c.Reset(context.Background()); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "Reset mock endpoints and recorded requests")
+		return nil
+	}
+}
+
+func requests(fs *flag.FlagSet) func(c *client.Client, out io.Writer) error {
+	follow := fs.Bool("follow", false, "keep polling for new requests")
+	interval := fs.Duration("interval", time.Second, "polling interval when following")
+	asJSON := fs.Bool("json", false, "print each request as a line of JSON")
+
+	return func(c *client.Client, out io.Writer) error {
+		offset := 0
+		for {
+			recorded, err := c.RequestsSince(context.Background(), offset)
+			if err != nil {
+				return err
+			}
+			for _, req := range recorded {
+				if err := printRequest(out, req, *asJSON); err != nil {
+					return err
+				}
+			}
+			offset += len(recorded)
+
+			if !*follow {
+				return nil
+			}
+			time.Sleep(*interval)
+		}
+	}
+}
+
+func printRequest(out io.Writer, req *mockservice.RecordedRequest, asJSON bool) error {
+	if asJSON {
+		return json.NewEncoder(out).Encode(req)
+	}
+
+	target := req.Endpoint
+	if req.Query != "" {
+		target += "?" + req.Query
+	}
+	status := "matched"
+	if !req.Matched {
+		status = "unmatched"
+	}
+	_, err := fmt.Fprintf(out, "%s %s %s %s\n", req.Time.Format(time.RFC3339), req.Method, target, status)
+	return err
+}
+
+func verify(fs *flag.FlagSet) func(c *client.Client, out io.Writer) error {
+	method := fs.String("method", http.MethodGet, "HTTP method of the expected requests")
+	endpoint := fs.String("endpoint", "", "URL path of the expected requests")
+	count := fs.Int("count", -1, "exact number of expected requests; at least one request is expected when negative")
+
+	return func(c *client.Client, out io.Writer) error {
+		verification := mockservice.Verification{Method: *method, Endpoint: *endpoint}
+		if *count >= 0 {
+			verification.Count = count
+		}
+		count, err := c.Verify(context.Background(), verification)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Verified %s %s was requested %d times\n", *method, *endpoint, count)
+		return nil
+	}
+}
+
+// isCommand reports whether the argument names a client subcommand
+func isCommand(name string) bool {
+	_, ok := commands[name]
+	return ok
+}
+
+// exitCode prints the error from a subcommand and returns the process exit code
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	fmt.Fprintln(os.Stderr, err)
+	return 1
+}
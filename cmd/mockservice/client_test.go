@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wchan2/mock_service"
+)
+
+func TestCommands(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected creating the mock service to succeed but got %s", err)
+	}
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	run := func(name string, args ...string) (string, error) {
+		out := &bytes.Buffer{}
+		err := runCommand(name, append([]string{"-url", server.URL + "/mocks"}, args...), out)
+		return out.String(), err
+	}
+
+	if _, err := run("register", "-method", "GET", "-endpoint", "/hello", "-status", "202", "-body", "hi", "-header", "X-Foo: bar"); err != nil {
+		t.Fatalf("Expected register to succeed but got %s", err)
+	}
+
+	out, err := run("list")
+	if err != nil || !strings.Contains(out, "GET     /hello    202") {
+		t.Errorf("Expected the registered endpoint to be listed but got %q (%v)", out, err)
+	}
+
+	resp, err := server.Client().Get(server.URL + "/hello")
+	if err != nil {
+		t.Fatalf("Expected requesting the mock to succeed but got %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 202 || resp.Header.Get("X-Foo") != "bar" {
+		t.Errorf("Expected the registered response but got %d %v", resp.StatusCode, resp.Header)
+	}
+
+	out, err = run("requests")
+	if err != nil || !strings.Contains(out, "GET /hello matched") {
+		t.Errorf("Expected the recorded request to be printed but got %q (%v)", out, err)
+	}
+
+	if _, err := run("verify", "-endpoint", "/hello", "-count", "1"); err != nil {
+		t.Errorf("Expected verify to succeed but got %s", err)
+	}
+
+	if _, err := run("verify", "-endpoint", "/hello", "-count", "2"); err == nil {
+		t.Errorf("Expected verify to fail for the wrong count")
+	}
+
+	if _, err := run("delete", "-method", "GET", "-endpoint", "/hello"); err != nil {
+		t.Errorf("Expected delete to succeed but got %s", err)
+	}
+
+	if _, err := run("reset"); err != nil {
+		t.Errorf("Expected reset to succeed but got %s", err)
+	}
+
+	if out, _ := run("requests"); out != "" {
+		t.Errorf("Expected no recorded requests after a reset but got %q", out)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/wchan2/mock_service"
+	"github.com/wchan2/mock_service/internal/yaml"
+)
+
+// loadConf builds a configuration from the registration endpoint and the mock endpoints found in the given files and
+// directories, importing OpenAPI documents with the given options
+func loadConf(registrationEndpoint string, paths []string, openAPI *mockservice.OpenAPIOptions) (*mockservice.Conf, error) {
+	conf := &mockservice.Conf{RegistrationEndpoint: registrationEndpoint}
+	for _, path := range paths {
+		files, err := configFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			endpoints, err := loadEndpoints(file, openAPI)
+			if err != nil {
+				return nil, err
+			}
+			conf.Endpoints = append(conf.Endpoints, endpoints...)
+		}
+	}
+	return conf, nil
+}
+
+// configFiles expands a directory into the JSON, YAML, XML and HAR files directly inside it, sorted by name
+func configFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read config %s: %s", path, err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read config directory %s: %s", path, err)
+	}
+	files := []string{}
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".json", ".yaml", ".yml", ".xml", ".har":
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// loadEndpoints reads the mock endpoints from a config file, which is either a Conf, a list of mock endpoints, a single
+// JSON or YAML mock endpoint, an OpenAPI 3 document whose operations are imported, a HAR log whose entries are replayed,
+// a Postman collection whose examples are imported, or a Pact contract whose interactions are imported
+func loadEndpoints(file string, openAPI *mockservice.OpenAPIOptions) ([]*mockservice.MockEndpoint, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read config %s: %s", file, err)
+	}
+	ext := strings.ToLower(filepath.Ext(file))
+	if ext == ".har" {
+		conf, err := mockservice.ImportHAR(data, &mockservice.HAROptions{StripVolatileHeaders: true})
+		if err != nil {
+			return nil, fmt.Errorf("Unable to import HAR log %s: %s", file, err)
+		}
+		return conf.Endpoints, nil
+	}
+	if ext == ".yaml" || ext == ".yml" {
+		if data, err = yaml.ToJSON(data); err != nil {
+			return nil, fmt.Errorf("Unable to parse config %s: %s", file, err)
+		}
+	}
+	if ext != ".xml" && isOpenAPI(data) {
+		conf, err := mockservice.ImportOpenAPI(data, openAPI)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to import OpenAPI document %s: %s", file, err)
+		}
+		return conf.Endpoints, nil
+	}
+
+	if ext != ".xml" && isPostman(data) {
+		conf, err := mockservice.ImportPostman(data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to import Postman collection %s: %s", file, err)
+		}
+		return conf.Endpoints, nil
+	}
+
+	if ext != ".xml" && isPact(data) {
+		conf, err := mockservice.ImportPact(data)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to import Pact contract %s: %s", file, err)
+		}
+		return conf.Endpoints, nil
+	}
+
+	conf := mockservice.Conf{}
+	if ext == ".xml" {
+		err = xml.Unmarshal(data, &conf)
+	} else if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		err = json.Unmarshal(data, &conf.Endpoints)
+	} else {
+		err = json.Unmarshal(data, &conf)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse config %s: %s", file, err)
+	}
+
+	if conf.Endpoints == nil && ext != ".xml" {
+		endpoint := &mockservice.MockEndpoint{}
+		if err := json.Unmarshal(data, endpoint); err == nil && endpoint.Endpoint != "" {
+			return []*mockservice.MockEndpoint{endpoint}, nil
+		}
+	}
+	return conf.Endpoints, nil
+}
+
+// isOpenAPI reports whether the JSON document is an OpenAPI document rather than mock endpoints
+func isOpenAPI(data []byte) bool {
+	doc := struct {
+		OpenAPI string `json:"openapi"`
+	}{}
+	return json.Unmarshal(data, &doc) == nil && doc.OpenAPI != ""
+}
+
+// isPostman reports whether the JSON document is a Postman collection rather than mock endpoints
+func isPostman(data []byte) bool {
+	doc := struct {
+		Info struct {
+			Schema string `json:"schema"`
+		} `json:"info"`
+	}{}
+	return json.Unmarshal(data, &doc) == nil && strings.Contains(doc.Info.Schema, "schema.getpostman.com")
+}
+
+// isPact reports whether the JSON document is a Pact contract rather than mock endpoints
+func isPact(data []byte) bool {
+	doc := struct {
+		Consumer     *json.RawMessage  `json:"consumer"`
+		Interactions []json.RawMessage `json:"interactions"`
+	}{}
+	return json.Unmarshal(data, &doc) == nil && doc.Consumer != nil && doc.Interactions != nil
+}
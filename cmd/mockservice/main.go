@@ -0,0 +1,409 @@
+// Command mockservice runs a standalone mock HTTP service and manages running ones.
+//
+// Usage:
+//
+//	mockservice [serve] [flags]                   serve a mock service
+//	mockservice register [-url URL] [flags]       register mock endpoints from -file or from -method, -endpoint, -status, -body and -header
+//	mockservice list [-url URL]                   list the registered mock endpoints
+//	mockservice delete [-url URL] [flags]         delete the mock endpoint given by -method and -endpoint
+//	mockservice reset [-url URL]                  delete all mock endpoints and recorded requests
+//	mockservice requests [-url URL] [flags]       print the recorded requests, polling for new ones with -follow
+//	mockservice verify [-url URL] [flags]         verify -method and -endpoint were requested, exactly -count times when given
+//
+// The client subcommands talk to the registration endpoint given by -url or
+// MOCKSERVICE_URL, which defaults to "http://localhost:8080/mocks".
+//
+// When serving, every flag can also be supplied through an environment variable so the
+// service can be configured as a sidecar without any Go code. Invalid values are rejected
+// like invalid flag values:
+//
+//	-addr                   MOCKSERVICE_ADDR                   listen address (default ":8080")
+//	-registration-endpoint  MOCKSERVICE_REGISTRATION_ENDPOINT  URL path used to register mocks (default "/mocks")
+//	-config                 MOCKSERVICE_CONFIG                 JSON, YAML or XML config file, OpenAPI 3 document, HAR log, Postman collection, Pact contract or directory, repeatable (env: list separated by commas)
+//	-tls-cert               MOCKSERVICE_TLS_CERT               TLS certificate file
+//	-tls-key                MOCKSERVICE_TLS_KEY                TLS private key file
+//	-tls-self-signed        MOCKSERVICE_TLS_SELF_SIGNED        serve TLS with a generated certificate authority, downloadable from {registration endpoint}/ca.pem
+//	-tls-hosts              MOCKSERVICE_TLS_HOSTS              comma separated host names of the generated certificate (default "localhost,127.0.0.1,::1")
+//	-tls-client-auth        MOCKSERVICE_TLS_CLIENT_AUTH        ask clients for a certificate so mocks can match on it
+//	-tls-client-ca          MOCKSERVICE_TLS_CLIENT_CA          PEM file of the CAs client certificates must be issued by (default: accept any)
+//	-h2c                    MOCKSERVICE_H2C                    also serve HTTP/2 over cleartext connections; HTTP/2 is always served over TLS
+//	-grpc-descriptor-set    MOCKSERVICE_GRPC_DESCRIPTOR_SET    protobuf FileDescriptorSet file whose gRPC methods can be mocked, repeatable (env: list separated by commas)
+//	-openapi-validate       MOCKSERVICE_OPENAPI_VALIDATE       reject requests breaking the schema of OpenAPI documents loaded with -config, with 400 Bad Request
+//	-openapi-fake           MOCKSERVICE_OPENAPI_FAKE           respond with fake data generated from the schema of OpenAPI responses without an example
+//	-openapi-seed           MOCKSERVICE_OPENAPI_SEED           seed making the fake data of OpenAPI responses deterministic (default: random)
+//	-pact-report            MOCKSERVICE_PACT_REPORT            file the report of the exercised Pact interactions is written to on shutdown
+//	-persist                MOCKSERVICE_PERSIST                file the registered mocks are saved to and restored from on restart
+//	-max-sessions           MOCKSERVICE_MAX_SESSIONS           number of sessions requests can create, 0 for no limit (default 1000)
+//	-session-idle-timeout   MOCKSERVICE_SESSION_IDLE_TIMEOUT   time after which a session without requests is deleted, 0 to keep them (default 1h)
+//	-journal-limit          MOCKSERVICE_JOURNAL_LIMIT          number of requests and WebSocket messages kept in the journal, 0 for no limit (default 1000)
+//	-verbose                MOCKSERVICE_VERBOSE                log every request served
+//	-shutdown-timeout       MOCKSERVICE_SHUTDOWN_TIMEOUT       time allowed for in-flight requests on shutdown (default 10s)
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/wchan2/mock_service"
+)
+
+// options holds the resolved command line configuration
+type options struct {
+	addr                 string
+	registrationEndpoint string
+	configs              []string
+	tlsCert              string
+	tlsKey               string
+	tlsSelfSigned        bool
+	tlsHosts             []string
+	tlsClientAuth        bool
+	tlsClientCA          string
+	h2c                  bool
+	descriptorSets       []string
+	openAPIValidate      bool
+	openAPIFake          bool
+	openAPISeed          int64
+	pactReport           string
+	persist              string
+	maxSessions          int
+	journalLimit         int
+	sessionIdleTimeout   time.Duration
+	verbose              bool
+	shutdownTimeout      time.Duration
+}
+
+// stringsFlag is a repeatable string flag
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func main() {
+	args := os.Args[1:]
+	if len(args) > 0 && isCommand(args[0]) {
+		os.Exit(exitCode(runCommand(args[0], args[1:], os.Stdout)))
+	}
+	if len(args) > 0 && args[0] == "serve" {
+		args = args[1:]
+	}
+
+	opts, err := parseOptions(args)
+	if err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		os.Exit(2)
+	}
+
+	if err := run(opts); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parseOptions reads the options from the arguments, falling back to environment variables and then defaults
+func parseOptions(args []string) (*options, error) {
+	fs := flag.NewFlagSet("mockservice", flag.ContinueOnError)
+	opts := &options{}
+	configs := stringsFlag{}
+	descriptorSets := stringsFlag{}
+
+	fs.StringVar(&opts.addr, "addr", ":8080", "listen address")
+	fs.StringVar(&opts.registrationEndpoint, "registration-endpoint", "/mocks", "URL path used to register mocks")
+	fs.Var(&configs, "config", "JSON, YAML or XML config file, OpenAPI 3 document, HAR log, Postman collection, Pact contract, or directory of them (repeatable)")
+	fs.StringVar(&opts.tlsCert, "tls-cert", "", "TLS certificate file")
+	fs.StringVar(&opts.tlsKey, "tls-key", "", "TLS private key file")
+	fs.BoolVar(&opts.tlsSelfSigned, "tls-self-signed", false, "serve TLS with a generated certificate authority")
+	tlsHosts := fs.String("tls-hosts", strings.Join(mockservice.DefaultTLSHosts, ","), "comma separated host names of the generated certificate")
+	fs.BoolVar(&opts.tlsClientAuth, "tls-client-auth", false, "ask clients for a certificate so mocks can match on it")
+	fs.StringVar(&opts.tlsClientCA, "tls-client-ca", "", "PEM file of the CAs client certificates must be issued by")
+	fs.BoolVar(&opts.h2c, "h2c", false, "also serve HTTP/2 over cleartext connections")
+	fs.Var(&descriptorSets, "grpc-descriptor-set", "protobuf FileDescriptorSet file whose gRPC methods can be mocked (repeatable)")
+	fs.BoolVar(&opts.openAPIValidate, "openapi-validate", false, "reject requests breaking the schema of OpenAPI documents loaded with -config")
+	fs.BoolVar(&opts.openAPIFake, "openapi-fake", false, "respond with fake data generated from the schema of OpenAPI responses without an example")
+	fs.Int64Var(&opts.openAPISeed, "openapi-seed", 0, "seed making the fake data of OpenAPI responses deterministic")
+	fs.StringVar(&opts.pactReport, "pact-report", "", "file the report of the exercised Pact interactions is written to on shutdown")
+	fs.StringVar(&opts.persist, "persist", "", "file the registered mocks are saved to and restored from on restart")
+	fs.IntVar(&opts.maxSessions, "max-sessions", mockservice.DefaultMaxSessions, "number of sessions requests can create, 0 for no limit")
+	fs.DurationVar(&opts.sessionIdleTimeout, "session-idle-timeout", mockservice.DefaultSessionIdleTimeout, "time after which a session without requests is deleted, 0 to keep them")
+	fs.IntVar(&opts.journalLimit, "journal-limit", mockservice.DefaultJournalLimit, "number of requests and WebSocket messages kept in the journal, 0 for no limit")
+	fs.BoolVar(&opts.verbose, "verbose", false, "log every request served")
+	fs.DurationVar(&opts.shutdownTimeout, "shutdown-timeout", 10*time.Second, "time allowed for in-flight requests on shutdown")
+	if err := setFromEnv(fs); err != nil {
+		fmt.Fprintln(fs.Output(), err)
+		return nil, err
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	opts.configs = configs
+	opts.tlsHosts = splitList(*tlsHosts)
+	if len(opts.configs) == 0 {
+		opts.configs = splitList(os.Getenv("MOCKSERVICE_CONFIG"))
+	}
+	opts.descriptorSets = descriptorSets
+	if len(opts.descriptorSets) == 0 {
+		opts.descriptorSets = splitList(os.Getenv("MOCKSERVICE_GRPC_DESCRIPTOR_SET"))
+	}
+
+	if (opts.tlsCert == "") != (opts.tlsKey == "") {
+		err := errors.New("both -tls-cert and -tls-key must be provided to serve TLS")
+		fmt.Fprintln(fs.Output(), err)
+		return nil, err
+	}
+	if opts.tlsClientAuth && opts.tlsCert == "" && !opts.tlsSelfSigned {
+		err := errors.New("-tls-client-auth requires serving TLS")
+		fmt.Fprintln(fs.Output(), err)
+		return nil, err
+	}
+	if opts.tlsSelfSigned && opts.tlsCert != "" {
+		err := errors.New("-tls-self-signed cannot be combined with -tls-cert and -tls-key")
+		fmt.Fprintln(fs.Output(), err)
+		return nil, err
+	}
+	return opts, nil
+}
+
+// run serves the mock service until SIGINT or SIGTERM is received
+func run(opts *options) error {
+	conf, err := loadConf(opts.registrationEndpoint, opts.configs, &mockservice.OpenAPIOptions{
+		ValidateRequests: opts.openAPIValidate,
+		FakeResponses:    opts.openAPIFake,
+		Seed:             opts.openAPISeed,
+	})
+	if err != nil {
+		return err
+	}
+	conf.PersistenceFile = opts.persist
+	service, err := mockservice.NewWithConf(conf)
+	if err != nil {
+		return fmt.Errorf("Unable to create mock service: %s", err)
+	}
+	service.LimitSessions(opts.maxSessions, opts.sessionIdleTimeout)
+	service.LimitJournal(opts.journalLimit)
+	for _, descriptorSet := range opts.descriptorSets {
+		data, err := ioutil.ReadFile(descriptorSet)
+		if err != nil {
+			return fmt.Errorf("Unable to read descriptor set: %s", err)
+		}
+		if err := service.LoadDescriptorSet(data); err != nil {
+			return fmt.Errorf("Unable to load descriptor set %s: %s", descriptorSet, err)
+		}
+	}
+
+	var handler http.Handler = service
+	if opts.verbose {
+		handler = logRequests(handler)
+	}
+	srv := &http.Server{Addr: opts.addr, Handler: handler}
+	mockservice.ConfigureHTTP2(srv, opts.h2c)
+	if opts.tlsSelfSigned {
+		ca, err := mockservice.NewCertificateAuthority()
+		if err != nil {
+			return fmt.Errorf("Unable to generate certificate authority: %s", err)
+		}
+		if srv.TLSConfig, err = ca.TLSConfig(opts.tlsHosts...); err != nil {
+			return fmt.Errorf("Unable to issue certificate: %s", err)
+		}
+		service.UseCertificateAuthority(ca)
+	}
+	if opts.tlsClientAuth {
+		if err := requestClientCertificates(srv, opts.tlsClientCA); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("Serving %d mock endpoints on %s with registration endpoint %s", len(conf.Endpoints), opts.addr, conf.RegistrationEndpoint)
+		if opts.tlsCert != "" || opts.tlsSelfSigned {
+			errs <- srv.ListenAndServeTLS(opts.tlsCert, opts.tlsKey)
+		} else {
+			errs <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), opts.shutdownTimeout)
+	defer cancel()
+	err = srv.Shutdown(shutdownCtx)
+	if closeErr := service.Close(); err == nil {
+		err = closeErr
+	}
+	if opts.pactReport != "" {
+		if reportErr := writePactReport(opts.pactReport, service.PactReport()); err == nil {
+			err = reportErr
+		}
+	}
+	return err
+}
+
+// writePactReport writes the report of the exercised Pact interactions as JSON, logging the interactions never exercised
+func writePactReport(file string, report *mockservice.PactReport) error {
+	for _, interaction := range report.Interactions {
+		if interaction.Count == 0 {
+			log.Printf("Pact interaction %q between %s and %s was not exercised", interaction.Description, interaction.Consumer, interaction.Provider)
+		}
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		return fmt.Errorf("Unable to write Pact report: %s", err)
+	}
+	return nil
+}
+
+// requestClientCertificates configures the server to ask for client certificates, issued by the CAs in the PEM file when provided
+func requestClientCertificates(srv *http.Server, clientCAFile string) error {
+	if srv.TLSConfig == nil {
+		srv.TLSConfig = &tls.Config{}
+	}
+
+	var clientCAs *x509.CertPool
+	if clientCAFile != "" {
+		pem, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return fmt.Errorf("Unable to read client CA file: %s", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("No PEM certificates found in client CA file %s", clientCAFile)
+		}
+	}
+	mockservice.RequestClientCertificates(srv.TLSConfig, clientCAs)
+	return nil
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// hijackableRecorder captures the status code of a ResponseWriter that supports taking over the connection
+type hijackableRecorder struct {
+	*statusRecorder
+}
+
+// pushableRecorder captures the status code of a ResponseWriter that supports HTTP/2 server push
+type pushableRecorder struct {
+	*statusRecorder
+}
+
+// recordStatus wraps the ResponseWriter to capture the status code, keeping the optional interfaces it implements so
+// that WebSocket upgrades, streamed responses and server pushes behave the same with logging
+func recordStatus(w http.ResponseWriter) (http.ResponseWriter, *statusRecorder) {
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	if _, ok := w.(http.Hijacker); ok {
+		return &hijackableRecorder{recorder}, recorder
+	}
+	if _, ok := w.(http.Pusher); ok {
+		return &pushableRecorder{recorder}, recorder
+	}
+	return recorder, recorder
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+func (s *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := s.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		s.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+func (s *pushableRecorder) Push(target string, opts *http.PushOptions) error {
+	return s.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+// logRequests logs the method, path, status and duration of every request
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		writer, recorder := recordStatus(w)
+		next.ServeHTTP(writer, req)
+		log.Printf("%s %s %d %s", req.Method, req.URL.RequestURI(), recorder.status, time.Since(start))
+	})
+}
+
+func envString(key, fallback string) string {
+	if val, ok := os.LookupEnv(key); ok {
+		return val
+	}
+	return fallback
+}
+
+// setFromEnv sets the flags from their environment variable, such as MOCKSERVICE_TLS_CERT for -tls-cert, before the
+// arguments override them. Invalid values are rejected like invalid flag values; repeatable flags are read separately.
+func setFromEnv(fs *flag.FlagSet) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if _, repeatable := f.Value.(*stringsFlag); repeatable || err != nil {
+			return
+		}
+		key := "MOCKSERVICE_" + strings.ToUpper(strings.Replace(f.Name, "-", "_", -1))
+		if val := os.Getenv(key); val != "" {
+			if setErr := fs.Set(f.Name, val); setErr != nil {
+				err = fmt.Errorf("invalid value %q for %s: %s", val, key, setErr)
+			}
+		}
+	})
+	return err
+}
+
+func splitList(vals string) []string {
+	list := []string{}
+	for _, val := range strings.Split(vals, ",") {
+		if val = strings.TrimSpace(val); val != "" {
+			list = append(list, val)
+		}
+	}
+	return list
+}
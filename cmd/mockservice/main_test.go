@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wchan2/mock_service"
+)
+
+func writeFile(t *testing.T, dir, name, contents string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("Expected writing %s to succeed but got %s", path, err)
+	}
+	return path
+}
+
+func TestParseOptions(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		opts, err := parseOptions(nil)
+		if err != nil {
+			t.Fatalf("Expected parsing options to succeed but got %s", err)
+		}
+
+		if opts.addr != ":8080" || opts.registrationEndpoint != "/mocks" || opts.shutdownTimeout != 10*time.Second {
+			t.Errorf("Expected default options but got %+v", opts)
+		}
+	})
+
+	t.Run("Environment_variables", func(t *testing.T) {
+		t.Setenv("MOCKSERVICE_ADDR", ":9090")
+		t.Setenv("MOCKSERVICE_CONFIG", "a.json, b")
+		t.Setenv("MOCKSERVICE_VERBOSE", "true")
+
+		opts, err := parseOptions([]string{"-registration-endpoint", "/admin"})
+		if err != nil {
+			t.Fatalf("Expected parsing options to succeed but got %s", err)
+		}
+
+		if opts.addr != ":9090" || opts.registrationEndpoint != "/admin" || !opts.verbose {
+			t.Errorf("Expected options from the environment and flags but got %+v", opts)
+		}
+
+		if len(opts.configs) != 2 || opts.configs[0] != "a.json" || opts.configs[1] != "b" {
+			t.Errorf("Expected configs [a.json b] but got %v", opts.configs)
+		}
+	})
+
+	t.Run("Invalid_environment_variables", func(t *testing.T) {
+		for key, val := range map[string]string{
+			"MOCKSERVICE_MAX_SESSIONS":         "80a",
+			"MOCKSERVICE_SESSION_IDLE_TIMEOUT": "soon",
+			"MOCKSERVICE_VERBOSE":              "maybe",
+		} {
+			t.Run(key, func(t *testing.T) {
+				t.Setenv(key, val)
+				if _, err := parseOptions(nil); err == nil || !strings.Contains(err.Error(), key) {
+					t.Errorf("Expected an error for the invalid value of %s but got %v", key, err)
+				}
+			})
+		}
+	})
+
+	t.Run("Self_signed_TLS", func(t *testing.T) {
+		opts, err := parseOptions([]string{"-tls-self-signed", "-tls-hosts", "mock.local, 10.0.0.1"})
+		if err != nil {
+			t.Fatalf("Expected parsing options to succeed but got %s", err)
+		}
+
+		if !opts.tlsSelfSigned || len(opts.tlsHosts) != 2 || opts.tlsHosts[0] != "mock.local" || opts.tlsHosts[1] != "10.0.0.1" {
+			t.Errorf("Expected self-signed TLS for mock.local and 10.0.0.1 but got %+v", opts)
+		}
+
+		if _, err := parseOptions([]string{"-tls-self-signed", "-tls-cert", "cert.pem", "-tls-key", "key.pem"}); err == nil {
+			t.Errorf("Expected an error when combining a self-signed certificate with a supplied one")
+		}
+	})
+
+	t.Run("TLS_cert_without_key", func(t *testing.T) {
+		if _, err := parseOptions([]string{"-tls-cert", "cert.pem"}); err == nil {
+			t.Errorf("Expected an error when the TLS key is missing")
+		}
+	})
+}
+
+func TestLoadConf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mockservice")
+	if err != nil {
+		t.Fatalf("Expected creating a temp dir to succeed but got %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "1-conf.json", `{"endpoints": [{"method": "GET", "endpoint": "/a", "httpStatusCode": 200}]}`)
+	writeFile(t, dir, "2-list.json", `[{"method": "POST", "endpoint": "/b", "httpStatusCode": 201}]`)
+	writeFile(t, dir, "3-conf.xml", `<conf><endpoints><method>PUT</method><endpoint>/c</endpoint><httpStatusCode>204</httpStatusCode></endpoints></conf>`)
+	writeFile(t, dir, "4-list.yaml", "- method: DELETE\n  endpoint: /d\n  httpStatusCode: 202\n")
+	writeFile(t, dir, "5-openapi.yml", "openapi: 3.0.3\npaths:\n  /e/{id}:\n    patch:\n      responses:\n        '200':\n          description: ok\n")
+	writeFile(t, dir, "6-capture.har", `{"log": {"entries": [{"request": {"method": "GET", "url": "https://staging.example.com/f"}, "response": {"status": 203, "content": {"text": "f"}}}]}}`)
+	writeFile(t, dir, "7-partner.postman_collection.json", `{"info": {"schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"}, "item": [{"request": {"method": "PUT", "url": "{{baseUrl}}/g"}, "response": [{"code": 205}]}]}`)
+	writeFile(t, dir, "8-web-users.json", `{"consumer": {"name": "web"}, "provider": {"name": "users"}, "interactions": [{"description": "h", "request": {"method": "POST", "path": "/h"}, "response": {"status": 206}}]}`)
+	writeFile(t, dir, "ignored.txt", `not a config`)
+
+	conf, err := loadConf("/mocks", []string{dir}, nil)
+	if err != nil {
+		t.Fatalf("Expected loading the config to succeed but got %s", err)
+	}
+
+	if conf.RegistrationEndpoint != "/mocks" {
+		t.Errorf("Expected registration endpoint /mocks but got %s", conf.RegistrationEndpoint)
+	}
+
+	expected := []struct {
+		method, endpoint string
+		status           int
+	}{
+		{http.MethodGet, "/a", http.StatusOK},
+		{http.MethodPost, "/b", http.StatusCreated},
+		{http.MethodPut, "/c", http.StatusNoContent},
+		{http.MethodDelete, "/d", http.StatusAccepted},
+		{http.MethodPatch, "/e/{id}", http.StatusOK},
+		{http.MethodGet, "/f", http.StatusNonAuthoritativeInfo},
+		{http.MethodPut, "/g", http.StatusResetContent},
+		{http.MethodPost, "/h", http.StatusPartialContent},
+	}
+	if len(conf.Endpoints) != len(expected) {
+		t.Fatalf("Expected %d endpoints but got %d", len(expected), len(conf.Endpoints))
+	}
+	for i, ep := range conf.Endpoints {
+		if ep.Method != expected[i].method || ep.Endpoint != expected[i].endpoint || ep.StatusCode != expected[i].status {
+			t.Errorf("Expected endpoint %+v but got %+v", expected[i], ep)
+		}
+	}
+
+	if _, err := loadConf("/mocks", []string{filepath.Join(dir, "missing.json")}, nil); err == nil {
+		t.Errorf("Expected an error when loading a missing config")
+	}
+}
+
+func TestLogRequests(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected creating the mock service to succeed but got %s", err)
+	}
+	defer service.Close()
+	service.Endpoints().Create(&mockservice.MockEndpoint{
+		Method:    http.MethodGet,
+		Endpoint:  "/prices",
+		WebSocket: &mockservice.WebSocketScript{OnConnect: []mockservice.WebSocketMessage{{Text: "welcome"}}},
+	})
+	service.Endpoints().Create(mockservice.Get("/download").WillReturn(http.StatusOK).WithBody("abcdefghij").InChunks(4, 0).MustBuild())
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+	server := httptest.NewServer(logRequests(service))
+	defer server.Close()
+
+	t.Run("WebSocket", func(t *testing.T) {
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		if err != nil {
+			t.Fatalf("Expected dialing the server to succeed but got %s", err)
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "GET /prices HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+			t.Errorf("Expected the WebSocket upgrade to succeed behind the request log but got %+v, %v", resp, err)
+		}
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		logRequests(service).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/download", nil))
+		if !recorder.Flushed || recorder.Body.String() != "abcdefghij" {
+			t.Errorf("Expected the chunks to be flushed behind the request log but got %q (flushed %t)", recorder.Body.String(), recorder.Flushed)
+		}
+	})
+}
@@ -0,0 +1,21 @@
+package mockservice
+
+import "time"
+
+// Duration is a time.Duration configured in JSON and XML as a string such as "250ms" or "2s"
+type Duration time.Duration
+
+// MarshalText formats the duration such as "250ms"
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText parses a duration such as "250ms"
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
@@ -2,22 +2,70 @@ package mockservice
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"time"
 )
 
 // EndpointService matches HTTP requests to HTTP mock responses
 type EndpointService struct {
-	mockedEndpoints *Endpoints
+	mockedEndpoints Store
+	journal         *Journal
+	events          *eventHub
+	sockets         *socketHub
+	descriptors     *descriptorRegistry
 }
 
 // NewEndpointService creates an EndpointsService with endpoints to be used for matching
-func NewEndpointService(endpoints *Endpoints) *EndpointService {
-	return &EndpointService{mockedEndpoints: endpoints}
+func NewEndpointService(endpoints Store) *EndpointService {
+	return &EndpointService{
+		mockedEndpoints: endpoints,
+		events:          newEventHub(),
+		sockets:         newSocketHub(),
+		descriptors:     newDescriptorRegistry(),
+	}
 }
 
-// ServeHTTP serves HTTP responses when a matched HTTP request is found
+// ServeHTTP serves HTTP responses when a matched HTTP request is found, recording the request and its response in the
+// journal when one is configured
 func (m *EndpointService) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	endpoint, err := m.mockedEndpoints.Lookup(req.Method, req.URL.Path)
+	if m.journal == nil {
+		m.serve(w, req)
+		return
+	}
+	writer, capture := captureResponse(w)
+	m.serve(writer, req)
+	if capture.recorded != nil {
+		m.journal.respond(capture.recorded, capture.finish())
+	}
+}
+
+// serve matches the request to a mock endpoint and writes its response
+func (m *EndpointService) serve(w http.ResponseWriter, req *http.Request) {
+	body := []byte{}
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			http.Error(w, fmt.Sprintf("Unable to read from payload due to: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if isGRPC(req) {
+		m.serveGRPC(w, req, body)
+		return
+	}
+
+	if route := schemaRoute(m.mockedEndpoints, req); route != nil {
+		if violations := route.RequestSchema.violations(req, body, route.Endpoint); len(violations) > 0 {
+			m.record(w, req, body, false)
+			writeJSON(w, http.StatusBadRequest, &schemaViolations{Message: "Request does not match the OpenAPI schema", Violations: violations})
+			return
+		}
+	}
+
+	endpoint, err := m.mockedEndpoints.Match(req, body)
+	m.record(w, req, body, err == nil)
 	if err == ErrEndpointDoesNotExist {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -26,9 +74,65 @@ func (m *EndpointService) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	w.WriteHeader(endpoint.StatusCode)
-	fmt.Fprint(w, endpoint.ResponseBody)
-	for headerKey, headerVal := range endpoint.ResponseHeaders {
-		w.Header().Add(headerKey, headerVal)
+	if endpoint.WebSocket != nil {
+		m.sockets.converse(w, req, endpoint, m.journal)
+		return
+	}
+	if endpoint.ServerSentEvents != nil {
+		m.events.stream(w, req, endpoint)
+		return
+	}
+	if endpoint.SOAPFault != nil {
+		writeSOAPFault(w, req, body, endpoint)
+		return
+	}
+	if endpoint.SOAP != nil && canonicalHeaders(endpoint.ResponseHeaders)["Content-Type"] == "" {
+		w.Header().Set("Content-Type", soapContentType(soapVersion(req, body, endpoint)))
+	}
+	if endpoint.GraphQLResponse != nil {
+		writeGraphQLResponse(w, endpoint)
+		return
+	}
+	if endpoint.ResponseSchema != nil {
+		body, err := endpoint.ResponseSchema.generate()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Unable to generate the response: %s", err), http.StatusInternalServerError)
+			return
+		}
+		if canonicalHeaders(endpoint.ResponseHeaders)["Content-Type"] == "" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		generated := *endpoint
+		generated.ResponseBody = body
+		endpoint = &generated
+	}
+	writeResponse(w, req, endpoint)
+}
+
+// record adds the request to the journal when one is configured, and marks it as the request whose response is captured
+func (m *EndpointService) record(w http.ResponseWriter, req *http.Request, body []byte, matched bool) {
+	if m.journal == nil {
+		return
+	}
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	recorded := &RecordedRequest{
+		Time:              time.Now(),
+		Method:            req.Method,
+		Protocol:          req.Proto,
+		Scheme:            scheme,
+		Host:              req.Host,
+		Endpoint:          req.URL.Path,
+		Query:             req.URL.RawQuery,
+		Headers:           req.Header.Clone(),
+		Body:              string(body),
+		Matched:           matched,
+		ClientCertificate: recordCertificate(req),
+	}
+	m.journal.Record(recorded)
+	if writer, ok := w.(capturingWriter); ok {
+		writer.capture().recorded = recorded
 	}
 }
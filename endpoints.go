@@ -2,8 +2,11 @@ package mockservice
 
 import (
 	"errors"
+	"net/http"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 var (
@@ -15,58 +18,329 @@ var (
 
 	// ErrEmptyEndpoint is returned when attempting to add a mock endpoint with an empty endpoint
 	ErrEmptyEndpoint = errors.New("Empty endpoint provided")
+
+	// ErrInvalidPathTemplate is returned when attempting to add a mock endpoint with a malformed {parameter} in its endpoint
+	ErrInvalidPathTemplate = errors.New("Invalid path template provided")
 )
 
-// Endpoints includes all the registered endpoints broken down by http method and the path
+// Endpoints includes all the registered endpoints broken down by http method, in the order they were created
 type Endpoints struct {
-	endpoints map[string]map[string]*MockEndpoint
+	endpoints map[string][]*MockEndpoint
+	scenarios map[string]string
+	// unmatched holds the endpoints marked as expected that the sweeper removed without them being matched
+	unmatched []*MockEndpoint
+	sweeper   *time.Timer
+	closed    bool
+	watchers  []watcher
+	watcherID int
 	sync.Mutex
 }
 
 // MockEndpoint is used to match a request to a given response
+//
+// The endpoint may be a path template where a segment such as {id} matches any single path segment.
+// When provided, the request headers and body must also be present in a request for it to match.
 type MockEndpoint struct {
 	Method          string            `json:"method" xml:"method"`
 	Endpoint        string            `json:"endpoint" xml:"endpoint"`
 	StatusCode      int               `json:"httpStatusCode" xml:"httpStatusCode"`
 	ResponseBody    string            `json:"responseBody" xml:"responseBody"`
 	ResponseHeaders map[string]string `json:"responseHeaders" xml:"responseHeaders"`
-	RequestHeaders  map[string]string `json:"responseHeaders" xml:"requestHeaders"`
+	RequestHeaders  map[string]string `json:"requestHeaders" xml:"requestHeaders"`
 	RequestBody     string            `json:"requestBody" xml:"requestBody"`
+	// QueryParameters must all be present in the request query with the value, among any others
+	QueryParameters map[string]string `json:"queryParameters,omitempty" xml:"queryParameters,omitempty"`
+	// BodyMatchers must all match the request body
+	BodyMatchers []BodyMatcher `json:"bodyMatchers,omitempty" xml:"bodyMatchers,omitempty"`
+	// ClientCertificate matches the TLS client certificate presented with the request
+	ClientCertificate *ClientCertificateMatcher `json:"clientCertificate,omitempty" xml:"clientCertificate,omitempty"`
+	// Protocol matches the request protocol, such as "HTTP/1.1" or "HTTP/2.0", or only its major version, such as "HTTP/2"
+	Protocol string `json:"protocol,omitempty" xml:"protocol,omitempty"`
+	// PushPromises are paths pushed to HTTP/2 clients that accept server push along with the response
+	PushPromises []string `json:"pushPromises,omitempty" xml:"pushPromises,omitempty"`
+	// ResponseTrailers are sent as HTTP trailers after the response body
+	ResponseTrailers map[string]string `json:"responseTrailers,omitempty" xml:"responseTrailers,omitempty"`
+	// ChunkSize streams the response body in chunks of this many bytes, flushing each chunk to the client
+	ChunkSize int `json:"chunkSize,omitempty" xml:"chunkSize,omitempty"`
+	// ChunkDelay is the time waited between streamed chunks
+	ChunkDelay Duration `json:"chunkDelay,omitempty" xml:"chunkDelay,omitempty"`
+	// ServerSentEvents responds with a stream of server-sent events instead of the response body
+	ServerSentEvents *ServerSentEvents `json:"serverSentEvents,omitempty" xml:"serverSentEvents,omitempty"`
+	// WebSocket accepts WebSocket upgrades and holds a scripted conversation instead of responding
+	WebSocket *WebSocketScript `json:"webSocket,omitempty" xml:"webSocket,omitempty"`
+	// GraphQL matches the operation of GraphQL requests, which are typically all sent to the same endpoint
+	GraphQL *GraphQLMatcher `json:"graphQL,omitempty" xml:"graphQL,omitempty"`
+	// GraphQLResponse responds with GraphQL data and errors instead of the response body
+	GraphQLResponse *GraphQLResponse `json:"graphQLResponse,omitempty" xml:"graphQLResponse,omitempty"`
+	// SOAP matches the action and body element of SOAP requests, which are typically all sent to the same endpoint
+	SOAP *SOAPMatcher `json:"soap,omitempty" xml:"soap,omitempty"`
+	// SOAPFault responds with a SOAP fault instead of the response body
+	SOAPFault *SOAPFault `json:"soapFault,omitempty" xml:"soapFault,omitempty"`
+	// GRPC answers gRPC calls to the endpoint with protobuf messages converted from JSON instead of the response body
+	GRPC *GRPCResponse `json:"grpc,omitempty" xml:"grpc,omitempty"`
+	// RequestSchema rejects requests routed to the endpoint that break the schema of its OpenAPI operation, see ImportOpenAPI
+	RequestSchema *RequestSchema `json:"requestSchema,omitempty" xml:"requestSchema,omitempty"`
+	// ResponseSchema responds with fake JSON generated from a schema instead of the response body
+	ResponseSchema *ResponseSchema `json:"responseSchema,omitempty" xml:"responseSchema,omitempty"`
+	// Tags group related endpoints, such as the folders of an imported Postman collection, and filter the listed endpoints
+	Tags []string `json:"tags,omitempty" xml:"tags,omitempty"`
+	// Pact identifies the Pact interaction the endpoint was imported from, see ImportPact
+	Pact *PactInteraction `json:"pact,omitempty" xml:"pact,omitempty"`
+	// Scenario names a state machine shared by endpoints, which starts in ScenarioStarted
+	Scenario string `json:"scenario,omitempty" xml:"scenario,omitempty"`
+	// RequiredState only matches requests while the scenario is in the state
+	RequiredState string `json:"requiredState,omitempty" xml:"requiredState,omitempty"`
+	// NewState moves the scenario to the state when the endpoint is matched
+	NewState string `json:"newState,omitempty" xml:"newState,omitempty"`
+	// Priority orders the endpoints matching a request, highest first, before preferring literal paths and recency
+	Priority int `json:"priority,omitempty" xml:"priority,omitempty"`
+	// TTL removes the endpoint once this long has passed since it was created
+	TTL Duration `json:"ttl,omitempty" xml:"ttl,omitempty"`
+	// MaxMatches removes the endpoint once it was matched this many times, so later requests fall through to other endpoints
+	MaxMatches int `json:"maxMatches,omitempty" xml:"maxMatches,omitempty"`
+	// Expected marks endpoints that must be matched at least once, see TestServer
+	Expected bool `json:"expected,omitempty" xml:"expected,omitempty"`
+
+	matchCount int
+	expiresAt  time.Time
 }
 
 // NewEndpoints creates a parent struct that adding endpoints for lookup
 func NewEndpoints() *Endpoints {
-	return &Endpoints{endpoints: make(map[string]map[string]*MockEndpoint)}
+	return &Endpoints{endpoints: make(map[string][]*MockEndpoint), scenarios: map[string]string{}}
 }
 
-// Lookup searches an endpoint by HTTP method and URL path
+// Lookup searches an endpoint by HTTP method and the exact URL path or path template it was created with
 func (e *Endpoints) Lookup(method, path string) (*MockEndpoint, error) {
-	mockEndpoint, ok := e.endpoints[method][path]
-	if !ok {
+	e.Lock()
+	defer e.Unlock()
+	for i := len(e.endpoints[method]) - 1; i >= 0; i-- {
+		if e.endpoints[method][i].Endpoint == path && !e.endpoints[method][i].expired(time.Now()) {
+			return e.endpoints[method][i], nil
+		}
+	}
+	return nil, ErrEndpointDoesNotExist
+}
+
+// Match finds the endpoint that best matches the request and its body while its scenario is in the required state.
+// Endpoints with the highest priority are preferred, then endpoints with a literal path over path templates, then the
+// most recently created endpoint wins. Endpoints that expired are skipped, and endpoints matched their maximum number
+// of times are removed.
+func (e *Endpoints) Match(req *http.Request, body []byte) (*MockEndpoint, error) {
+	var events []StoreEvent
+	defer func() { e.notify(events) }()
+	e.Lock()
+	defer e.Unlock()
+	now := time.Now()
+	var best *MockEndpoint
+	for i := len(e.endpoints[req.Method]) - 1; i >= 0; i-- {
+		candidate := e.endpoints[req.Method][i]
+		if candidate.expired(now) || !candidate.Matches(req, body) || !e.inState(candidate) {
+			continue
+		}
+		if best == nil || candidate.Priority > best.Priority ||
+			candidate.Priority == best.Priority && isPathTemplate(best.Endpoint) && !isPathTemplate(candidate.Endpoint) {
+			best = candidate
+		}
+	}
+	if best == nil {
 		return nil, ErrEndpointDoesNotExist
 	}
-	return mockEndpoint, nil
+	best.matchCount++
+	events = append(events, StoreEvent{Op: StoreMatched, Endpoint: best})
+	if best.NewState != "" {
+		e.scenarios[best.Scenario] = best.NewState
+		events = append(events, StoreEvent{Op: StoreScenario, Endpoint: best})
+	}
+	if best.exhausted() {
+		e.remove(best)
+		events = append(events, StoreEvent{Op: StoreDeleted, Endpoint: best})
+	}
+	return best, nil
 }
 
-// Create adds the endpoint to enable Lookup
+// Unsatisfied returns the endpoints marked as expected that have not been matched by any request, including those
+// whose TTL expired
+func (e *Endpoints) Unsatisfied() []*MockEndpoint {
+	e.Lock()
+	unsatisfied := append([]*MockEndpoint{}, e.unmatched...)
+	for _, endpoints := range e.endpoints {
+		for _, endpoint := range endpoints {
+			if endpoint.Expected && endpoint.matchCount == 0 {
+				unsatisfied = append(unsatisfied, endpoint)
+			}
+		}
+	}
+	e.Unlock()
+
+	sort.SliceStable(unsatisfied, func(i, j int) bool {
+		if unsatisfied[i].Method != unsatisfied[j].Method {
+			return unsatisfied[i].Method < unsatisfied[j].Method
+		}
+		return unsatisfied[i].Endpoint < unsatisfied[j].Endpoint
+	})
+	return unsatisfied
+}
+
+// Create adds the endpoint to enable Lookup, replacing an endpoint created with the same HTTP method, URL path and request matchers
 func (e *Endpoints) Create(endpoint *MockEndpoint) error {
-	if strings.Trim(endpoint.Method, " ") == "" {
-		return ErrEmptyHTTPMethod
+	if err := endpoint.Validate(); err != nil {
+		return err
 	}
+	e.Lock()
+	events := e.add(endpoint)
+	e.Unlock()
+	e.notify(events)
+	return nil
+}
 
-	if strings.Trim(endpoint.Endpoint, " ") == "" {
-		return ErrEmptyEndpoint
+// createAll validates all the endpoints before creating them at once, so that either all or none of them are created
+func (e *Endpoints) createAll(endpoints []*MockEndpoint) error {
+	for _, endpoint := range endpoints {
+		if err := endpoint.Validate(); err != nil {
+			return err
+		}
 	}
 	e.Lock()
-	if _, ok := e.endpoints[endpoint.Method]; !ok {
-		e.endpoints[endpoint.Method] = map[string]*MockEndpoint{}
+	events := []StoreEvent{}
+	for _, endpoint := range endpoints {
+		events = append(events, e.add(endpoint)...)
 	}
-	e.endpoints[endpoint.Method][endpoint.Endpoint] = endpoint
 	e.Unlock()
+	e.notify(events)
+	return nil
+}
 
+// add adds the endpoint, replacing an endpoint with the same route, and returns its events. It must be called with the
+// lock held.
+func (e *Endpoints) add(endpoint *MockEndpoint) []StoreEvent {
+	if i := e.index(endpoint); i >= 0 {
+		e.endpoints[endpoint.Method][i] = endpoint
+	} else {
+		e.endpoints[endpoint.Method] = append(e.endpoints[endpoint.Method], endpoint)
+	}
+	return append([]StoreEvent{{Op: StoreCreated, Endpoint: endpoint}}, e.expire(endpoint)...)
+}
+
+// Update replaces an endpoint that was previously created for the same HTTP method, URL path and request matchers
+func (e *Endpoints) Update(endpoint *MockEndpoint) error {
+	if err := endpoint.Validate(); err != nil {
+		return err
+	}
+	e.Lock()
+	i := e.index(endpoint)
+	if i < 0 {
+		e.Unlock()
+		return ErrEndpointDoesNotExist
+	}
+	e.endpoints[endpoint.Method][i] = endpoint
+	events := append([]StoreEvent{{Op: StoreUpdated, Endpoint: endpoint}}, e.expire(endpoint)...)
+	e.Unlock()
+	e.notify(events)
 	return nil
 }
 
+// expire starts the TTL of the endpoint and schedules the sweeper to remove it, returning the events of the endpoints
+// that already expired. It must be called with the lock held.
+func (e *Endpoints) expire(endpoint *MockEndpoint) []StoreEvent {
+	if endpoint.TTL <= 0 {
+		return nil
+	}
+	now := time.Now()
+	endpoint.expiresAt = now.Add(time.Duration(endpoint.TTL))
+	return e.sweep(now)
+}
+
+// index returns the position of the endpoint with the same route as the given one, or -1
+func (e *Endpoints) index(endpoint *MockEndpoint) int {
+	for i, existing := range e.endpoints[endpoint.Method] {
+		if existing.SameRoute(endpoint) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Validate checks that the endpoint has an HTTP method, a valid URL path or path template, limits and matchers, as
+// stores do before creating or updating it
+func (m *MockEndpoint) Validate() error {
+	if strings.Trim(m.Method, " ") == "" {
+		return ErrEmptyHTTPMethod
+	}
+
+	if strings.Trim(m.Endpoint, " ") == "" {
+		return ErrEmptyEndpoint
+	}
+
+	if !validPathTemplate(m.Endpoint) {
+		return ErrInvalidPathTemplate
+	}
+
+	if m.StatusCode != 0 && !validStatusCode(m.StatusCode) {
+		return ErrInvalidStatusCode
+	}
+
+	if m.TTL < 0 || m.MaxMatches < 0 {
+		return ErrInvalidLimit
+	}
+
+	if m.Scenario == "" && (m.RequiredState != "" || m.NewState != "") {
+		return ErrMissingScenario
+	}
+
+	for i := range m.BodyMatchers {
+		if err := m.BodyMatchers[i].validate(); err != nil {
+			return err
+		}
+	}
+
+	if m.ServerSentEvents != nil {
+		if err := m.ServerSentEvents.validate(); err != nil {
+			return err
+		}
+	}
+
+	if m.WebSocket != nil {
+		if err := m.WebSocket.validate(); err != nil {
+			return err
+		}
+	}
+
+	if m.GraphQLResponse != nil {
+		if err := m.GraphQLResponse.validate(); err != nil {
+			return err
+		}
+	}
+
+	if m.SOAP != nil {
+		if err := m.SOAP.validate(); err != nil {
+			return err
+		}
+	}
+
+	if m.GRPC != nil {
+		if err := m.GRPC.validate(); err != nil {
+			return err
+		}
+	}
+
+	if m.RequestSchema != nil {
+		if err := m.RequestSchema.validate(); err != nil {
+			return err
+		}
+	}
+
+	if m.ResponseSchema != nil {
+		return m.ResponseSchema.validate()
+	}
+	return nil
+}
+
+// validStatusCode reports whether the status code is a three digit HTTP status code that can be written
+func validStatusCode(statusCode int) bool {
+	return statusCode >= 100 && statusCode <= 999
+}
+
 // Load allows the bulk creation of a list of endpoints
 func (e *Endpoints) Load(endpoints []*MockEndpoint) error {
 	for i := range endpoints {
@@ -76,3 +350,59 @@ func (e *Endpoints) Load(endpoints []*MockEndpoint) error {
 	}
 	return nil
 }
+
+// List returns all the registered endpoints that have not expired, sorted by HTTP method and URL path
+func (e *Endpoints) List() []*MockEndpoint {
+	e.Lock()
+	now := time.Now()
+	list := []*MockEndpoint{}
+	for _, endpoints := range e.endpoints {
+		for _, endpoint := range endpoints {
+			if !endpoint.expired(now) {
+				list = append(list, endpoint)
+			}
+		}
+	}
+	e.Unlock()
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Method != list[j].Method {
+			return list[i].Method < list[j].Method
+		}
+		return list[i].Endpoint < list[j].Endpoint
+	})
+	return list
+}
+
+// Delete removes the endpoints registered for the HTTP method and URL path
+func (e *Endpoints) Delete(method, path string) error {
+	e.Lock()
+	remaining := []*MockEndpoint{}
+	events := []StoreEvent{}
+	for _, endpoint := range e.endpoints[method] {
+		if endpoint.Endpoint != path {
+			remaining = append(remaining, endpoint)
+		} else {
+			events = append(events, StoreEvent{Op: StoreDeleted, Endpoint: endpoint})
+		}
+	}
+	if len(events) == 0 {
+		e.Unlock()
+		return ErrEndpointDoesNotExist
+	}
+	e.endpoints[method] = remaining
+	e.Unlock()
+	e.notify(events)
+	return nil
+}
+
+// Reset removes all the registered endpoints and moves all the scenarios back to ScenarioStarted
+func (e *Endpoints) Reset() {
+	e.Lock()
+	e.endpoints = make(map[string][]*MockEndpoint)
+	e.scenarios = map[string]string{}
+	e.unmatched = nil
+	e.sweep(time.Now())
+	e.Unlock()
+	e.notify([]StoreEvent{{Op: StoreReset}})
+}
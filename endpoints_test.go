@@ -2,7 +2,9 @@ package mockservice_test
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/wchan2/mock_service"
 )
@@ -72,3 +74,111 @@ func TestCreateErrEmptyEndpoint(t *testing.T) {
 		t.Errorf("Expected %s error but got %s", mockservice.ErrEmptyEndpoint, err)
 	}
 }
+
+func TestCreateErrInvalidStatusCode(t *testing.T) {
+	endpoints := mockservice.NewEndpoints()
+	for _, statusCode := range []int{-1, 99, 1000} {
+		mockEndpoint := &mockservice.MockEndpoint{Method: "GET", Endpoint: "/test/endpoint", StatusCode: statusCode}
+		if err := endpoints.Create(mockEndpoint); err != mockservice.ErrInvalidStatusCode {
+			t.Errorf("Expected %s error for status %d but got %v", mockservice.ErrInvalidStatusCode, statusCode, err)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	endpoints := mockservice.NewEndpoints()
+	template := &mockservice.MockEndpoint{Method: http.MethodGet, Endpoint: "/users/{id}", StatusCode: http.StatusOK}
+	literal := &mockservice.MockEndpoint{Method: http.MethodGet, Endpoint: "/users/me", StatusCode: http.StatusOK}
+	withBody := &mockservice.MockEndpoint{Method: http.MethodPost, Endpoint: "/users", RequestBody: `{"name":"gopher"}`}
+	partial := &mockservice.MockEndpoint{Method: http.MethodGet, Endpoint: "/files/{name}.json", StatusCode: http.StatusOK}
+	withQuery := &mockservice.MockEndpoint{Method: http.MethodGet, Endpoint: "/search", QueryParameters: map[string]string{"q": "go", "page": ""}}
+	endpoints.Load([]*mockservice.MockEndpoint{literal, template, withBody, partial, withQuery})
+
+	cases := []struct {
+		name     string
+		req      *http.Request
+		body     string
+		expected *mockservice.MockEndpoint
+	}{
+		{"Path_template", httptest.NewRequest(http.MethodGet, "/users/42", nil), "", template},
+		{"Literal_preferred_over_template", httptest.NewRequest(http.MethodGet, "/users/me", nil), "", literal},
+		{"Empty_template_segment", httptest.NewRequest(http.MethodGet, "/users/", nil), "", nil},
+		{"Extra_segment", httptest.NewRequest(http.MethodGet, "/users/42/posts", nil), "", nil},
+		{"Parameter_within_segment", httptest.NewRequest(http.MethodGet, "/files/report.json", nil), "", partial},
+		{"Empty_parameter_within_segment", httptest.NewRequest(http.MethodGet, "/files/.json", nil), "", nil},
+		{"Different_suffix", httptest.NewRequest(http.MethodGet, "/files/report.xml", nil), "", nil},
+		{"Request_body", httptest.NewRequest(http.MethodPost, "/users", nil), `{"name":"gopher"}`, withBody},
+		{"Different_request_body", httptest.NewRequest(http.MethodPost, "/users", nil), `{}`, nil},
+		{"Query_parameters", httptest.NewRequest(http.MethodGet, "/search?page=&sort=asc&q=rust&q=go", nil), "", withQuery},
+		{"Missing_query_parameter", httptest.NewRequest(http.MethodGet, "/search?q=go", nil), "", nil},
+		{"Different_query_parameter", httptest.NewRequest(http.MethodGet, "/search?q=rust&page=", nil), "", nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			endpoint, err := endpoints.Match(c.req, []byte(c.body))
+			if c.expected == nil && err != mockservice.ErrEndpointDoesNotExist {
+				t.Errorf("Expected %s error but got %v", mockservice.ErrEndpointDoesNotExist, err)
+			}
+
+			if endpoint != c.expected {
+				t.Errorf("Expected %+v endpoint but got %+v", c.expected, endpoint)
+			}
+		})
+	}
+
+	params := mockservice.PathParameters("/users/{id}/posts/{post}", "/users/42/posts/7")
+	if params["id"] != "42" || params["post"] != "7" {
+		t.Errorf("Expected path parameters id=42 and post=7 but got %v", params)
+	}
+	if params := mockservice.PathParameters("/files/{name}.json", "/files/report.json"); params["name"] != "report" {
+		t.Errorf("Expected path parameter name=report but got %v", params)
+	}
+}
+
+func TestMatch_Limits(t *testing.T) {
+	endpoints := mockservice.NewEndpoints()
+	defer endpoints.Close()
+	fallback := mockservice.Get("/dependency").WillReturn(http.StatusServiceUnavailable).MustBuild()
+	limited := mockservice.Get("/dependency").WithPriority(1).Times(2).WillReturn(http.StatusOK).MustBuild()
+	endpoints.Load([]*mockservice.MockEndpoint{limited, fallback})
+
+	for i, expected := range []*mockservice.MockEndpoint{limited, limited, fallback, fallback} {
+		endpoint, err := endpoints.Match(httptest.NewRequest(http.MethodGet, "/dependency", nil), nil)
+		if err != nil || endpoint != expected {
+			t.Errorf("Expected request %d to match the endpoint responding %d but got %+v, %v", i+1, expected.StatusCode, endpoint, err)
+		}
+	}
+	if list := endpoints.List(); len(list) != 1 || list[0] != fallback {
+		t.Errorf("Expected the endpoint matched its maximum number of times to be removed but got %+v", list)
+	}
+
+	if err := endpoints.Create(&mockservice.MockEndpoint{Method: http.MethodGet, Endpoint: "/a", MaxMatches: -1}); err != mockservice.ErrInvalidLimit {
+		t.Errorf("Expected %s error but got %v", mockservice.ErrInvalidLimit, err)
+	}
+}
+
+func TestMatch_TTL(t *testing.T) {
+	endpoints := mockservice.NewEndpoints()
+	defer endpoints.Close()
+	endpoints.Create(mockservice.Get("/short").WithTTL(20 * time.Millisecond).MustBuild())
+	endpoints.Create(mockservice.Get("/long").WithTTL(time.Hour).MustBuild())
+	endpoints.Create(mockservice.Get("/forever").MustBuild())
+
+	if _, err := endpoints.Match(httptest.NewRequest(http.MethodGet, "/short", nil), nil); err != nil {
+		t.Errorf("Expected the endpoint to match before it expires but got %s", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(endpoints.List()) != 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, err := endpoints.Match(httptest.NewRequest(http.MethodGet, "/short", nil), nil); err != mockservice.ErrEndpointDoesNotExist {
+		t.Errorf("Expected the expired endpoint not to match but got %v", err)
+	}
+	if _, err := endpoints.Lookup(http.MethodGet, "/short"); err != mockservice.ErrEndpointDoesNotExist {
+		t.Errorf("Expected the expired endpoint to be gone but got %v", err)
+	}
+	if list := endpoints.List(); len(list) != 2 {
+		t.Errorf("Expected the endpoints that have not expired to remain but got %d", len(list))
+	}
+}
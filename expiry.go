@@ -0,0 +1,79 @@
+package mockservice
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidLimit is returned when adding a mock endpoint with a negative TTL or maximum number of matches
+var ErrInvalidLimit = errors.New("Invalid TTL or maximum number of matches provided")
+
+// expired reports whether the endpoint outlived its TTL
+func (m *MockEndpoint) expired(now time.Time) bool {
+	return !m.expiresAt.IsZero() && !now.Before(m.expiresAt)
+}
+
+// exhausted reports whether the endpoint was matched its maximum number of times. It must be called with the lock held.
+func (m *MockEndpoint) exhausted() bool {
+	return m.MaxMatches > 0 && m.matchCount >= m.MaxMatches
+}
+
+// remove deletes the endpoint, which is no longer matched. It must be called with the lock held.
+func (e *Endpoints) remove(endpoint *MockEndpoint) {
+	remaining := []*MockEndpoint{}
+	for _, existing := range e.endpoints[endpoint.Method] {
+		if existing != endpoint {
+			remaining = append(remaining, existing)
+		}
+	}
+	e.endpoints[endpoint.Method] = remaining
+}
+
+// sweep removes the expired endpoints, returning their events, and schedules the sweeper for the next endpoint to
+// expire. It must be called with the lock held.
+func (e *Endpoints) sweep(now time.Time) []StoreEvent {
+	var next time.Time
+	events := []StoreEvent{}
+	for method, endpoints := range e.endpoints {
+		remaining := []*MockEndpoint{}
+		for _, endpoint := range endpoints {
+			if endpoint.expired(now) {
+				if endpoint.Expected && endpoint.matchCount == 0 {
+					e.unmatched = append(e.unmatched, endpoint)
+				}
+				events = append(events, StoreEvent{Op: StoreDeleted, Endpoint: endpoint})
+				continue
+			}
+			remaining = append(remaining, endpoint)
+			if !endpoint.expiresAt.IsZero() && (next.IsZero() || endpoint.expiresAt.Before(next)) {
+				next = endpoint.expiresAt
+			}
+		}
+		e.endpoints[method] = remaining
+	}
+
+	if e.sweeper != nil {
+		e.sweeper.Stop()
+		e.sweeper = nil
+	}
+	if !next.IsZero() && !e.closed {
+		e.sweeper = time.AfterFunc(next.Sub(now), func() {
+			e.Lock()
+			events := e.sweep(time.Now())
+			e.Unlock()
+			e.notify(events)
+		})
+	}
+	return events
+}
+
+// Close stops the background sweeper removing expired endpoints. Expired endpoints are still never matched.
+func (e *Endpoints) Close() {
+	e.Lock()
+	e.closed = true
+	if e.sweeper != nil {
+		e.sweeper.Stop()
+		e.sweeper = nil
+	}
+	e.Unlock()
+}
@@ -0,0 +1,530 @@
+package mockservice
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrInvalidResponseSchema is returned when attempting to add a mock endpoint whose response schema is not a JSON
+// schema, or whose reference cannot be resolved
+var ErrInvalidResponseSchema = errors.New("Invalid response schema provided")
+
+// fakeMaxDepth is how deep optional properties and array items are generated, which bounds recursive schemas
+const fakeMaxDepth = 6
+
+var (
+	fakeFirstNames = []string{"Ada", "Alan", "Amara", "Ben", "Carla", "Chen", "Diego", "Elena", "Farah", "Grace", "Hiro", "Ines", "Jonas", "Kofi", "Lena", "Marco", "Nadia", "Omar", "Priya", "Sofia"}
+	fakeLastNames  = []string{"Adams", "Bauer", "Costa", "Dubois", "Evans", "Fischer", "Garcia", "Hughes", "Ito", "Jensen", "Kim", "Lopez", "Moreau", "Novak", "Okafor", "Patel", "Rossi", "Silva", "Tanaka", "Weber"}
+	fakeCities     = []string{"Amsterdam", "Austin", "Berlin", "Cape Town", "Dublin", "Lagos", "Lisbon", "Melbourne", "Montreal", "Osaka", "Oslo", "Seoul", "Toronto", "Valencia", "Zurich"}
+	fakeCountries  = []string{"Australia", "Brazil", "Canada", "France", "Germany", "India", "Ireland", "Japan", "Kenya", "Mexico", "Netherlands", "Norway", "Portugal", "Spain", "United States"}
+	fakeCodes      = []string{"AU", "BR", "CA", "FR", "DE", "IN", "IE", "JP", "KE", "MX", "NL", "NO", "PT", "ES", "US"}
+	fakeStreets    = []string{"Maple Avenue", "Harbour Road", "King Street", "Elm Street", "Station Road", "Park Lane", "Mill Street", "Church Road", "Oak Drive", "River Walk"}
+	fakeCompanies  = []string{"Acme", "Globex", "Initech", "Umbrella", "Hooli", "Stark Industries", "Wayne Enterprises", "Soylent", "Vandelay", "Tyrell"}
+	fakeColors     = []string{"red", "orange", "yellow", "green", "teal", "blue", "indigo", "violet", "black", "white"}
+	fakeWords      = []string{"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit", "sed", "do", "eiusmod", "tempor", "incididunt", "ut", "labore", "et", "dolore", "magna", "aliqua", "enim", "minim", "veniam", "quis", "nostrud", "exercitation", "ullamco", "laboris", "nisi", "aliquip", "commodo"}
+)
+
+// ResponseSchema generates the response body from a JSON schema instead of the literal response body, filling it with
+// realistic fake values such as names, emails, dates and UUIDs chosen by the property names and string formats
+type ResponseSchema struct {
+	// Schema is the JSON schema of the response body, which may refer to definitions within itself with local $refs
+	Schema json.RawMessage `json:"schema,omitempty" xml:"schema,omitempty"`
+	// OpenAPI is an OpenAPI 3 document, in JSON or YAML, holding the schema Ref points to
+	OpenAPI string `json:"openAPI,omitempty" xml:"openAPI,omitempty"`
+	// Ref is the local reference of the schema within OpenAPI or Schema, such as "#/components/schemas/Pet"
+	Ref string `json:"ref,omitempty" xml:"ref,omitempty"`
+	// Seed makes the generated responses deterministic: the nth response is the same on every run with the same seed.
+	// Without a seed every response is random.
+	Seed int64 `json:"seed,omitempty" xml:"seed,omitempty"`
+
+	mu        sync.Mutex
+	doc       *openAPIDocument
+	schema    map[string]interface{}
+	responses int64
+}
+
+// fakeGenerator generates values conforming to JSON schemas
+type fakeGenerator struct {
+	rand *rand.Rand
+	doc  *openAPIDocument
+}
+
+func (r *ResponseSchema) validate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.prepare()
+}
+
+// prepare parses the schema once, resolving its reference
+func (r *ResponseSchema) prepare() error {
+	if r.schema != nil {
+		return nil
+	}
+	if (len(r.Schema) == 0) == (r.OpenAPI == "") {
+		return ErrInvalidResponseSchema
+	}
+
+	var doc *openAPIDocument
+	if r.OpenAPI != "" {
+		parsed, err := parseOpenAPI([]byte(r.OpenAPI))
+		if err != nil || r.Ref == "" {
+			return ErrInvalidResponseSchema
+		}
+		doc = parsed
+	} else {
+		doc = &openAPIDocument{}
+		if err := decodeJSON(r.Schema, &doc.root); err != nil {
+			return ErrInvalidResponseSchema
+		}
+	}
+
+	schema := doc.root
+	if r.Ref != "" {
+		if !strings.HasPrefix(r.Ref, "#/") {
+			return ErrInvalidResponseSchema
+		}
+		schema, _ = doc.lookup(r.Ref).(map[string]interface{})
+	}
+	if schema == nil {
+		return ErrInvalidResponseSchema
+	}
+	r.doc, r.schema = doc, schema
+	return nil
+}
+
+// generate returns a JSON response body conforming to the schema
+func (r *ResponseSchema) generate() (string, error) {
+	r.mu.Lock()
+	if err := r.prepare(); err != nil {
+		r.mu.Unlock()
+		return "", err
+	}
+	seed := time.Now().UnixNano()
+	if r.Seed != 0 {
+		seed = r.Seed + r.responses
+	}
+	r.responses++
+	doc, schema := r.doc, r.schema
+	r.mu.Unlock()
+
+	g := &fakeGenerator{rand: rand.New(rand.NewSource(seed)), doc: doc}
+	body, err := json.Marshal(g.value(schema, "", 0))
+	if err != nil {
+		return "", fmt.Errorf("Unable to marshal the generated response: %s", err)
+	}
+	return string(body), nil
+}
+
+// value generates a value for the schema of the property with the given name
+func (g *fakeGenerator) value(node interface{}, name string, depth int) interface{} {
+	schema := g.doc.resolve(node)
+	if schema == nil || depth > 2*fakeMaxDepth {
+		return nil
+	}
+	if value, ok := schema["const"]; ok {
+		return value
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[g.rand.Intn(len(enum))]
+	}
+	if allOf, ok := schema["allOf"].([]interface{}); ok && len(allOf) > 0 {
+		return g.value(g.merge(schema, allOf), name, depth)
+	}
+	for _, keyword := range []string{"oneOf", "anyOf"} {
+		if options, ok := schema[keyword].([]interface{}); ok && len(options) > 0 {
+			return g.value(options[g.rand.Intn(len(options))], name, depth)
+		}
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		return g.object(schema, depth)
+	case "array":
+		return g.array(schema, name, depth)
+	case "integer":
+		return g.integer(schema, name)
+	case "number":
+		return g.number(schema, name)
+	case "boolean":
+		return g.rand.Intn(2) == 0
+	case "null":
+		return nil
+	}
+	return g.string(schema, name)
+}
+
+// schemaType returns the first type of the schema other than null, inferring it from the keywords when not given
+func schemaType(schema map[string]interface{}) string {
+	for _, t := range schemaTypes(schema) {
+		if t != "null" {
+			return t
+		}
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	if _, ok := schema["additionalProperties"]; ok {
+		return "object"
+	}
+	if _, ok := schema["items"]; ok {
+		return "array"
+	}
+	if len(schemaTypes(schema)) > 0 {
+		return "null"
+	}
+	return "string"
+}
+
+// merge combines the schema with the schemas of its allOf into a single schema with all their properties and required properties
+func (g *fakeGenerator) merge(schema map[string]interface{}, allOf []interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	properties := map[string]interface{}{}
+	required := []interface{}{}
+	add := func(sub map[string]interface{}) {
+		for key, val := range sub {
+			switch key {
+			case "allOf":
+			case "properties":
+				subProperties, _ := val.(map[string]interface{})
+				for name, property := range subProperties {
+					properties[name] = property
+				}
+			case "required":
+				subRequired, _ := val.([]interface{})
+				required = append(required, subRequired...)
+			default:
+				merged[key] = val
+			}
+		}
+	}
+
+	add(schema)
+	for _, node := range allOf {
+		sub := g.doc.resolve(node)
+		if nested, ok := sub["allOf"].([]interface{}); ok && len(nested) > 0 {
+			sub = g.merge(sub, nested)
+		}
+		add(sub)
+	}
+	if len(properties) > 0 {
+		merged["properties"] = properties
+	}
+	if len(required) > 0 {
+		merged["required"] = required
+	}
+	return merged
+}
+
+func (g *fakeGenerator) object(schema map[string]interface{}, depth int) map[string]interface{} {
+	obj := map[string]interface{}{}
+	required := map[string]bool{}
+	requiredList, _ := schema["required"].([]interface{})
+	for _, name := range requiredList {
+		if name, ok := name.(string); ok {
+			required[name] = true
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property := g.doc.resolve(properties[name])
+		if writeOnly, _ := property["writeOnly"].(bool); writeOnly {
+			continue
+		}
+		if depth >= fakeMaxDepth && !required[name] {
+			continue
+		}
+		obj[name] = g.value(property, name, depth+1)
+	}
+
+	if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok && len(properties) == 0 && depth < fakeMaxDepth {
+		for i := 1 + g.rand.Intn(3); i > 0; i-- {
+			key := g.word()
+			obj[key] = g.value(additional, key, depth+1)
+		}
+	}
+	return obj
+}
+
+func (g *fakeGenerator) array(schema map[string]interface{}, name string, depth int) []interface{} {
+	min, max := 1, 3
+	if n, ok := schemaNumber(schema, "minItems"); ok {
+		min = int(n)
+		if min > max {
+			max = min + 2
+		}
+	}
+	if n, ok := schemaNumber(schema, "maxItems"); ok {
+		max = int(n)
+		if min > max {
+			min = max
+		}
+	}
+	count := min + g.rand.Intn(max-min+1)
+	if depth >= fakeMaxDepth {
+		count = min
+	}
+
+	unique, _ := schema["uniqueItems"].(bool)
+	itemName := strings.TrimSuffix(name, "s")
+	array := make([]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		item := g.value(schema["items"], itemName, depth+1)
+		for attempt := 0; unique && attempt < 10 && containsJSON(array, item); attempt++ {
+			item = g.value(schema["items"], itemName, depth+1)
+		}
+		array = append(array, item)
+	}
+	return array
+}
+
+// bounds returns the range of numbers the schema allows, defaulting to the given range
+func bounds(schema map[string]interface{}, defaultMin, defaultMax float64) (float64, float64, bool, bool) {
+	min, hasMin := schemaNumber(schema, "minimum")
+	max, hasMax := schemaNumber(schema, "maximum")
+	exclusiveMin, _ := schema["exclusiveMinimum"].(bool)
+	exclusiveMax, _ := schema["exclusiveMaximum"].(bool)
+	if n, ok := schemaNumber(schema, "exclusiveMinimum"); ok {
+		min, hasMin, exclusiveMin = n, true, true
+	}
+	if n, ok := schemaNumber(schema, "exclusiveMaximum"); ok {
+		max, hasMax, exclusiveMax = n, true, true
+	}
+	switch {
+	case !hasMin && !hasMax:
+		min, max = defaultMin, defaultMax
+	case !hasMin:
+		min = math.Min(defaultMin, max-(defaultMax-defaultMin))
+	case !hasMax:
+		max = math.Max(defaultMax, min+(defaultMax-defaultMin))
+	}
+	return min, max, exclusiveMin, exclusiveMax
+}
+
+// numberRange returns the default range of numbers for the property name
+func numberRange(name string) (float64, float64) {
+	name = normalizeName(name)
+	switch {
+	case name == "age":
+		return 18, 90
+	case name == "year":
+		return 1970, 2030
+	case strings.HasSuffix(name, "id"):
+		return 1, 99999
+	case strings.Contains(name, "latitude") || name == "lat":
+		return -90, 90
+	case strings.Contains(name, "longitude") || name == "lng" || name == "lon":
+		return -180, 180
+	case strings.Contains(name, "price") || strings.Contains(name, "amount") || strings.Contains(name, "total"):
+		return 1, 500
+	case strings.Contains(name, "count") || strings.Contains(name, "quantity"):
+		return 0, 100
+	}
+	return 1, 1000
+}
+
+func (g *fakeGenerator) integer(schema map[string]interface{}, name string) int64 {
+	defaultMin, defaultMax := numberRange(name)
+	min, max, exclusiveMin, exclusiveMax := bounds(schema, defaultMin, defaultMax)
+	low, high := math.Ceil(min), math.Floor(max)
+	if exclusiveMin && low == min {
+		low++
+	}
+	if exclusiveMax && high == max {
+		high--
+	}
+	if multiple, ok := schemaNumber(schema, "multipleOf"); ok && multiple >= 1 {
+		low, high = math.Ceil(low/multiple), math.Floor(high/multiple)
+		if high < low {
+			return int64(low * multiple)
+		}
+		return int64((low + float64(g.rand.Int63n(int64(high-low)+1))) * multiple)
+	}
+	if high < low {
+		return int64(low)
+	}
+	return int64(low) + g.rand.Int63n(int64(high-low)+1)
+}
+
+func (g *fakeGenerator) number(schema map[string]interface{}, name string) float64 {
+	defaultMin, defaultMax := numberRange(name)
+	min, max, exclusiveMin, exclusiveMax := bounds(schema, defaultMin, defaultMax)
+	if multiple, ok := schemaNumber(schema, "multipleOf"); ok && multiple > 0 {
+		low, high := math.Ceil(min/multiple), math.Floor(max/multiple)
+		if exclusiveMin && low*multiple == min {
+			low++
+		}
+		if exclusiveMax && high*multiple == max {
+			high--
+		}
+		if high < low {
+			return low * multiple
+		}
+		return (low + float64(g.rand.Int63n(int64(high-low)+1))) * multiple
+	}
+
+	value := math.Round((min+g.rand.Float64()*(max-min))*100) / 100
+	if value < min || (exclusiveMin && value == min) {
+		value = math.Nextafter(min, math.Inf(1))
+	}
+	if value > max || (exclusiveMax && value == max) {
+		value = math.Nextafter(max, math.Inf(-1))
+	}
+	return value
+}
+
+func (g *fakeGenerator) string(schema map[string]interface{}, name string) string {
+	format, _ := schema["format"].(string)
+	value := g.formatted(format)
+	if value == "" {
+		value = g.named(normalizeName(name))
+	}
+
+	if n, ok := schemaNumber(schema, "maxLength"); ok && utf8.RuneCountInString(value) > int(n) {
+		value = string([]rune(value)[:int(n)])
+	}
+	if n, ok := schemaNumber(schema, "minLength"); ok {
+		for utf8.RuneCountInString(value) < int(n) {
+			value += string(rune('a' + g.rand.Intn(26)))
+		}
+	}
+	return value
+}
+
+// formatted generates a string in the format, or returns an empty string for unknown formats
+func (g *fakeGenerator) formatted(format string) string {
+	switch format {
+	case "date-time":
+		return g.time().Format(time.RFC3339)
+	case "date":
+		return g.time().Format("2006-01-02")
+	case "time":
+		return g.time().Format("15:04:05")
+	case "uuid":
+		return g.uuid()
+	case "email":
+		return g.email()
+	case "uri", "url", "uri-reference":
+		return g.url()
+	case "hostname":
+		return g.domain()
+	case "ipv4":
+		return fmt.Sprintf("10.%d.%d.%d", g.rand.Intn(256), g.rand.Intn(256), 1+g.rand.Intn(254))
+	case "ipv6":
+		return fmt.Sprintf("2001:db8::%x:%x", g.rand.Intn(65536), g.rand.Intn(65536))
+	case "byte":
+		return base64.StdEncoding.EncodeToString([]byte(g.sentence(3)))
+	case "password":
+		return fmt.Sprintf("s3cret-%04d", g.rand.Intn(10000))
+	}
+	return ""
+}
+
+// named generates a string that suits the normalized property name
+func (g *fakeGenerator) named(name string) string {
+	switch {
+	case strings.Contains(name, "email"):
+		return g.email()
+	case name == "firstname" || name == "givenname":
+		return g.pick(fakeFirstNames)
+	case name == "lastname" || name == "surname" || name == "familyname":
+		return g.pick(fakeLastNames)
+	case name == "username" || name == "login" || name == "handle":
+		return strings.ToLower(g.pick(fakeFirstNames)) + fmt.Sprint(g.rand.Intn(100))
+	case name == "name" || name == "fullname" || name == "displayname" || name == "author" || name == "owner":
+		return g.pick(fakeFirstNames) + " " + g.pick(fakeLastNames)
+	case strings.Contains(name, "phone") || strings.Contains(name, "mobile"):
+		return fmt.Sprintf("+1-555-%03d-%04d", g.rand.Intn(1000), g.rand.Intn(10000))
+	case strings.Contains(name, "city"):
+		return g.pick(fakeCities)
+	case strings.Contains(name, "countrycode"):
+		return g.pick(fakeCodes)
+	case strings.Contains(name, "country"):
+		return g.pick(fakeCountries)
+	case strings.Contains(name, "street") || strings.Contains(name, "address"):
+		return fmt.Sprintf("%d %s", 1+g.rand.Intn(999), g.pick(fakeStreets))
+	case strings.Contains(name, "zip") || strings.Contains(name, "postal"):
+		return fmt.Sprintf("%05d", g.rand.Intn(100000))
+	case strings.Contains(name, "company") || strings.Contains(name, "organization"):
+		return g.pick(fakeCompanies)
+	case strings.Contains(name, "url") || strings.Contains(name, "website") || strings.Contains(name, "link") || name == "href":
+		return g.url()
+	case strings.Contains(name, "colo"):
+		return g.pick(fakeColors)
+	case name == "id" || strings.HasSuffix(name, "uuid") || strings.HasSuffix(name, "id"):
+		return g.uuid()
+	case strings.HasSuffix(name, "date") || strings.HasSuffix(name, "time") || strings.HasSuffix(name, "timestamp") ||
+		name == "createdat" || name == "updatedat" || name == "deletedat":
+		return g.time().Format(time.RFC3339)
+	case name == "title" || name == "subject":
+		title := g.sentence(2 + g.rand.Intn(3))
+		return strings.ToUpper(title[:1]) + title[1:]
+	case strings.Contains(name, "description") || strings.Contains(name, "summary") || strings.Contains(name, "comment") ||
+		strings.Contains(name, "message") || strings.Contains(name, "text") || name == "bio":
+		sentence := g.sentence(6 + g.rand.Intn(6))
+		return strings.ToUpper(sentence[:1]) + sentence[1:] + "."
+	}
+	return g.sentence(1 + g.rand.Intn(2))
+}
+
+// normalizeName lower cases the property name and strips separators so that firstName, first_name and first-name match
+func normalizeName(name string) string {
+	return strings.NewReplacer("_", "", "-", "", " ", "").Replace(strings.ToLower(name))
+}
+
+func (g *fakeGenerator) pick(values []string) string {
+	return values[g.rand.Intn(len(values))]
+}
+
+func (g *fakeGenerator) word() string {
+	return g.pick(fakeWords)
+}
+
+func (g *fakeGenerator) sentence(words int) string {
+	parts := make([]string, words)
+	for i := range parts {
+		parts[i] = g.word()
+	}
+	return strings.Join(parts, " ")
+}
+
+// time returns a time between 2015 and 2030
+func (g *fakeGenerator) time() time.Time {
+	start := time.Date(2015, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return start.Add(time.Duration(g.rand.Int63n(int64(15*365*24*time.Hour))) / time.Second * time.Second)
+}
+
+// uuid returns a random version 4 UUID
+func (g *fakeGenerator) uuid() string {
+	b := make([]byte, 16)
+	g.rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func (g *fakeGenerator) email() string {
+	return strings.ToLower(g.pick(fakeFirstNames)+"."+g.pick(fakeLastNames)) + "@example.com"
+}
+
+func (g *fakeGenerator) url() string {
+	return "https://" + g.domain() + "/" + g.word()
+}
+
+func (g *fakeGenerator) domain() string {
+	return strings.ToLower(strings.Replace(g.pick(fakeCompanies), " ", "", -1)) + ".example.com"
+}
@@ -0,0 +1,208 @@
+package mockservice_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wchan2/mock_service"
+)
+
+const userSchema = `{
+	"type": "object",
+	"required": ["id", "email", "name", "roles"],
+	"properties": {
+		"id": {"type": "string", "format": "uuid"},
+		"name": {"type": "string", "maxLength": 40},
+		"email": {"type": "string", "format": "email"},
+		"age": {"type": "integer", "minimum": 18, "maximum": 21},
+		"score": {"type": "number", "exclusiveMinimum": 0, "maximum": 1},
+		"createdAt": {"type": "string", "format": "date-time"},
+		"code": {"type": "string", "minLength": 8, "maxLength": 8},
+		"roles": {"type": "array", "minItems": 1, "maxItems": 2, "uniqueItems": true, "items": {"enum": ["admin", "member"]}},
+		"address": {"$ref": "#/definitions/Address"},
+		"password": {"type": "string", "writeOnly": true}
+	},
+	"additionalProperties": false,
+	"definitions": {
+		"Address": {
+			"allOf": [
+				{"type": "object", "required": ["city"], "properties": {"city": {"type": "string"}}},
+				{"type": "object", "properties": {"zip": {"type": "string", "pattern": "^[0-9]{5}$"}}}
+			]
+		}
+	}
+}`
+
+var uuidRegexp = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestResponseSchema(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	err = service.Endpoints().Load([]*mockservice.MockEndpoint{
+		mockservice.Get("/users/{id}").WillReturn(http.StatusOK).WithBodySchema(userSchema, 42).MustBuild(),
+		mockservice.Get("/random").WillReturn(http.StatusOK).WithBodySchema(userSchema, 0).MustBuild(),
+		{
+			Method:        http.MethodPost,
+			Endpoint:      "/users",
+			StatusCode:    http.StatusCreated,
+			RequestSchema: &mockservice.RequestSchema{Body: json.RawMessage(userSchema)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected loading the endpoints to succeed but got %s", err)
+	}
+
+	t.Run("Conforms_to_schema", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			recorder := serve(service, http.MethodGet, "/random", "")
+			if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf(`Expected Content-Type "application/json" but got "%s"`, contentType)
+			}
+			if validated := serve(service, http.MethodPost, "/users", recorder.Body.String()); validated.Code != http.StatusCreated {
+				t.Fatalf("Expected the generated user to match its schema but got %s for %s", validated.Body.String(), recorder.Body.String())
+			}
+		}
+	})
+
+	t.Run("Realistic_values", func(t *testing.T) {
+		user := struct {
+			ID        string                 `json:"id"`
+			Name      string                 `json:"name"`
+			Email     string                 `json:"email"`
+			CreatedAt string                 `json:"createdAt"`
+			Password  *string                `json:"password"`
+			Address   map[string]interface{} `json:"address"`
+		}{}
+		recorder := serve(service, http.MethodGet, "/users/1", "")
+		if err := json.Unmarshal(recorder.Body.Bytes(), &user); err != nil {
+			t.Fatalf("Expected a JSON user but got %s", recorder.Body.String())
+		}
+		if !uuidRegexp.MatchString(user.ID) {
+			t.Errorf("Expected the id to be a UUID but got %s", user.ID)
+		}
+		if !strings.Contains(user.Name, " ") {
+			t.Errorf("Expected a full name but got %s", user.Name)
+		}
+		if !strings.HasSuffix(user.Email, "@example.com") {
+			t.Errorf("Expected an example.com email but got %s", user.Email)
+		}
+		if _, err := time.Parse(time.RFC3339, user.CreatedAt); err != nil {
+			t.Errorf("Expected createdAt to be a date-time but got %s", user.CreatedAt)
+		}
+		if user.Password != nil {
+			t.Errorf("Expected the write only password to be left out but got %s", *user.Password)
+		}
+		if city, _ := user.Address["city"].(string); city == "" {
+			t.Errorf("Expected the address to have a city but got %v", user.Address)
+		}
+	})
+
+	t.Run("Deterministic_with_seed", func(t *testing.T) {
+		other, err := mockservice.New("/mocks")
+		if err != nil {
+			t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+		}
+		other.Endpoints().Create(mockservice.Get("/users/{id}").WillReturn(http.StatusOK).WithBodySchema(userSchema, 42).MustBuild())
+
+		first, second := serve(other, http.MethodGet, "/users/1", "").Body.String(), serve(other, http.MethodGet, "/users/2", "").Body.String()
+		if first == second {
+			t.Errorf("Expected consecutive responses to vary but both were %s", first)
+		}
+
+		again, err := mockservice.New("/mocks")
+		if err != nil {
+			t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+		}
+		again.Endpoints().Create(mockservice.Get("/users/{id}").WillReturn(http.StatusOK).WithBodySchema(userSchema, 42).MustBuild())
+		if body := serve(again, http.MethodGet, "/users/1", "").Body.String(); body != first {
+			t.Errorf("Expected the same first response %s for the same seed but got %s", first, body)
+		}
+		if body := serve(again, http.MethodGet, "/users/2", "").Body.String(); body != second {
+			t.Errorf("Expected the same second response %s for the same seed but got %s", second, body)
+		}
+	})
+}
+
+func TestResponseSchema_OpenAPI(t *testing.T) {
+	endpoint := &mockservice.MockEndpoint{
+		Method:     http.MethodGet,
+		Endpoint:   "/pets/{id}",
+		StatusCode: http.StatusOK,
+		ResponseSchema: &mockservice.ResponseSchema{
+			OpenAPI: "openapi: 3.0.0\npaths: {}\ncomponents:\n  schemas:\n    Pet:\n      type: object\n      required: [name, kind]\n      properties:\n        name:\n          type: string\n        kind:\n          type: string\n          enum: [cat]\n",
+			Ref:     "#/components/schemas/Pet",
+			Seed:    7,
+		},
+	}
+	service, err := mockservice.NewWithConf(&mockservice.Conf{RegistrationEndpoint: "/mocks", Endpoints: []*mockservice.MockEndpoint{endpoint}})
+	if err != nil {
+		t.Fatalf("Expected creating the mock service to succeed but got %s", err)
+	}
+
+	pet := map[string]string{}
+	recorder := serve(service, http.MethodGet, "/pets/1", "")
+	if err := json.Unmarshal(recorder.Body.Bytes(), &pet); err != nil || pet["kind"] != "cat" || pet["name"] == "" {
+		t.Errorf("Expected a generated pet but got %s", recorder.Body.String())
+	}
+}
+
+func TestImportOpenAPI_FakeResponses(t *testing.T) {
+	spec := `{
+		"openapi": "3.0.0",
+		"paths": {
+			"/pets": {"get": {"responses": {"200": {"description": "ok", "content": {"application/json": {
+				"schema": {"type": "array", "minItems": 3, "maxItems": 3, "items": {"$ref": "#/components/schemas/Pet"}}
+			}}}}}},
+			"/health": {"get": {"responses": {"200": {"description": "ok", "content": {"application/json": {"example": {"ok": true}}}}}}}
+		},
+		"components": {"schemas": {"Pet": {"type": "object", "properties": {"name": {"type": "string"}}}}}
+	}`
+	conf, err := mockservice.ImportOpenAPI([]byte(spec), &mockservice.OpenAPIOptions{FakeResponses: true, Seed: 1})
+	if err != nil {
+		t.Fatalf("Expected importing the OpenAPI document to succeed but got %s", err)
+	}
+	if conf.Endpoints[0].ResponseSchema != nil || conf.Endpoints[0].ResponseBody != `{"ok":true}` {
+		t.Errorf("Expected the example of /health to be used but got %+v", conf.Endpoints[0])
+	}
+	if schema := conf.Endpoints[1].ResponseSchema; schema == nil || schema.Seed != 1 || strings.Contains(string(schema.Schema), "$ref") {
+		t.Fatalf("Expected /pets to respond with its inlined schema but got %+v", schema)
+	}
+
+	conf.RegistrationEndpoint = "/mocks"
+	service, err := mockservice.NewWithConf(conf)
+	if err != nil {
+		t.Fatalf("Expected creating the mock service to succeed but got %s", err)
+	}
+	pets := []map[string]string{}
+	recorder := serve(service, http.MethodGet, "/pets", "")
+	if err := json.Unmarshal(recorder.Body.Bytes(), &pets); err != nil || len(pets) != 3 || pets[0]["name"] == "" {
+		t.Errorf("Expected 3 generated pets but got %s", recorder.Body.String())
+	}
+}
+
+func TestResponseSchemaValidation(t *testing.T) {
+	cases := []struct {
+		name   string
+		schema *mockservice.ResponseSchema
+	}{
+		{"Empty", &mockservice.ResponseSchema{}},
+		{"Invalid_JSON", &mockservice.ResponseSchema{Schema: json.RawMessage(`{"type":`)}},
+		{"Missing_reference", &mockservice.ResponseSchema{Schema: json.RawMessage(`{}`), Ref: "#/definitions/Missing"}},
+		{"OpenAPI_without_reference", &mockservice.ResponseSchema{OpenAPI: `{"openapi": "3.0.0"}`}},
+		{"Schema_and_OpenAPI", &mockservice.ResponseSchema{Schema: json.RawMessage(`{}`), OpenAPI: `{"openapi": "3.0.0"}`, Ref: "#/"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := mockservice.NewEndpoints().Create(&mockservice.MockEndpoint{Method: http.MethodGet, Endpoint: "/users", ResponseSchema: c.schema})
+			if err != mockservice.ErrInvalidResponseSchema {
+				t.Errorf("Expected %s but got %v", mockservice.ErrInvalidResponseSchema, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package mockservice
+
+import "log"
+
+// FileStore is a Store keeping the endpoints in memory, restoring them from a file when created and saving their
+// Snapshot to it after every change, so they survive restarts
+type FileStore struct {
+	*Endpoints
+	file *snapshotFile
+	stop func()
+}
+
+// NewFileStore creates a store saving its endpoints to the file, restoring those already saved in it
+func NewFileStore(file string) (*FileStore, error) {
+	s := &FileStore{Endpoints: NewEndpoints(), file: &snapshotFile{name: file}}
+	if err := s.file.read(s.Endpoints); err != nil {
+		return nil, err
+	}
+	if err := s.Save(); err != nil {
+		return nil, err
+	}
+	s.stop = s.Endpoints.Watch(func(event StoreEvent) {
+		if !event.persisted() {
+			return
+		}
+		if err := s.Save(); err != nil {
+			log.Printf("%s", err)
+		}
+	})
+	return s, nil
+}
+
+// Save writes the snapshot of the endpoints and scenario states to the file
+func (s *FileStore) Save() error {
+	return s.file.write(s.Endpoints)
+}
+
+// Close stops saving the changes of the endpoints and the background sweeper, after saving the match counts of the
+// endpoints without a maximum number of matches
+func (s *FileStore) Close() {
+	s.stop()
+	s.Endpoints.Close()
+	if err := s.Save(); err != nil {
+		log.Printf("%s", err)
+	}
+}
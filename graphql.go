@@ -0,0 +1,406 @@
+package mockservice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrInvalidGraphQLResponse is returned when attempting to add a mock endpoint whose GraphQL data or errors are not valid JSON
+var ErrInvalidGraphQLResponse = errors.New("Invalid GraphQL response provided")
+
+// GraphQLMatcher matches GraphQL requests, POSTed as JSON or sent as GET query parameters, by the operation they execute.
+// All the non-empty properties must match.
+type GraphQLMatcher struct {
+	// OperationName matches the name of the executed operation
+	OperationName string `json:"operationName,omitempty" xml:"operationName,omitempty"`
+	// Fields must all be selected at the top level of the executed operation, such as "user" in { user(id: 1) { name } }
+	Fields []string `json:"fields,omitempty" xml:"fields,omitempty"`
+	// Variables must all be present in the request with semantically equal JSON values; other variables are ignored
+	Variables map[string]json.RawMessage `json:"variables,omitempty" xml:"-"`
+}
+
+// GraphQLResponse is the data and errors returned to a GraphQL request
+type GraphQLResponse struct {
+	Data   json.RawMessage `json:"data,omitempty" xml:"data,omitempty"`
+	Errors json.RawMessage `json:"errors,omitempty" xml:"errors,omitempty"`
+}
+
+// graphQLRequest is a GraphQL request and the operation it executes
+type graphQLRequest struct {
+	Query         string                     `json:"query"`
+	OperationName string                     `json:"operationName"`
+	Variables     map[string]json.RawMessage `json:"variables"`
+
+	fields []string
+}
+
+func (g *GraphQLMatcher) matches(req *http.Request, body []byte) bool {
+	request, err := parseGraphQLRequest(req, body)
+	if err != nil {
+		return false
+	}
+
+	if g.OperationName != "" && g.OperationName != request.OperationName {
+		return false
+	}
+	for _, field := range g.Fields {
+		if !containsString(request.fields, field) {
+			return false
+		}
+	}
+	for name, val := range g.Variables {
+		actual, ok := request.Variables[name]
+		if !ok || !equalJSON(val, actual) {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *GraphQLResponse) validate() error {
+	if len(g.Data) > 0 && !json.Valid(g.Data) {
+		return ErrInvalidGraphQLResponse
+	}
+	if len(g.Errors) > 0 && !json.Valid(g.Errors) {
+		return ErrInvalidGraphQLResponse
+	}
+	return nil
+}
+
+// writeGraphQLResponse writes the endpoint's GraphQL data and errors as a JSON response
+func writeGraphQLResponse(w http.ResponseWriter, endpoint *MockEndpoint) {
+	response := map[string]json.RawMessage{"data": json.RawMessage("null")}
+	if len(endpoint.GraphQLResponse.Data) > 0 {
+		response["data"] = endpoint.GraphQLResponse.Data
+	}
+	if len(endpoint.GraphQLResponse.Errors) > 0 {
+		response["errors"] = endpoint.GraphQLResponse.Errors
+	}
+
+	for headerKey, headerVal := range endpoint.ResponseHeaders {
+		w.Header().Add(headerKey, headerVal)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	statusCode := endpoint.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseGraphQLRequest reads the GraphQL request from the JSON body or, for GET requests, the query parameters, and
+// resolves the name and top-level fields of the operation it executes
+func parseGraphQLRequest(req *http.Request, body []byte) (*graphQLRequest, error) {
+	request := &graphQLRequest{}
+	if req.Method == http.MethodGet {
+		query := req.URL.Query()
+		request.Query, request.OperationName = query.Get("query"), query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				return nil, err
+			}
+		}
+	} else if err := json.Unmarshal(body, request); err != nil {
+		return nil, err
+	}
+
+	document, err := parseGraphQLDocument(request.Query)
+	if err != nil {
+		return nil, err
+	}
+	operation, err := document.operation(request.OperationName)
+	if err != nil {
+		return nil, err
+	}
+	request.OperationName = operation.name
+	request.fields = document.fields(operation.selections, map[string]bool{})
+	return request, nil
+}
+
+// graphQLSelections are the top-level fields and fragment spreads of a selection set
+type graphQLSelections struct {
+	fields  []string
+	spreads []string
+}
+
+type graphQLOperation struct {
+	name       string
+	selections graphQLSelections
+}
+
+// graphQLDocument holds the operations and fragments of a GraphQL query document
+type graphQLDocument struct {
+	operations []*graphQLOperation
+	fragments  map[string]graphQLSelections
+}
+
+// operation returns the operation with the name, or the only operation of the document when no name is given
+func (d *graphQLDocument) operation(name string) (*graphQLOperation, error) {
+	if name == "" {
+		if len(d.operations) != 1 {
+			return nil, fmt.Errorf("an operation name is required for a document with %d operations", len(d.operations))
+		}
+		return d.operations[0], nil
+	}
+	for _, operation := range d.operations {
+		if operation.name == name {
+			return operation, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %s", name)
+}
+
+// fields returns the top-level fields of the selections, including those selected through fragment spreads
+func (d *graphQLDocument) fields(selections graphQLSelections, visited map[string]bool) []string {
+	fields := append([]string{}, selections.fields...)
+	for _, spread := range selections.spreads {
+		if visited[spread] {
+			continue
+		}
+		visited[spread] = true
+		fields = append(fields, d.fields(d.fragments[spread], visited)...)
+	}
+	return fields
+}
+
+// parseGraphQLDocument parses the operations and fragments of a GraphQL query document, keeping only what is needed
+// to match requests: operation names and top-level fields
+func parseGraphQLDocument(query string) (*graphQLDocument, error) {
+	tokens, err := graphQLTokens(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &graphQLParser{tokens: tokens}
+	document := &graphQLDocument{fragments: map[string]graphQLSelections{}}
+	for !p.done() {
+		switch token := p.next(); token {
+		case "{":
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			document.operations = append(document.operations, &graphQLOperation{selections: selections})
+		case "query", "mutation", "subscription":
+			operation := &graphQLOperation{}
+			if p.peek() != "(" && p.peek() != "@" && p.peek() != "{" {
+				operation.name = p.next()
+			}
+			if operation.selections, err = p.definitionBody(); err != nil {
+				return nil, err
+			}
+			document.operations = append(document.operations, operation)
+		case "fragment":
+			name := p.next()
+			if p.next() != "on" {
+				return nil, fmt.Errorf("expected type condition of fragment %s", name)
+			}
+			p.next()
+			if document.fragments[name], err = p.definitionBody(); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q in GraphQL document", token)
+		}
+	}
+	if len(document.operations) == 0 {
+		return nil, errors.New("GraphQL document has no operations")
+	}
+	return document, nil
+}
+
+// graphQLParser walks the tokens of a GraphQL document
+type graphQLParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *graphQLParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *graphQLParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *graphQLParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+// definitionBody skips the variable definitions and directives of a definition and parses its selection set
+func (p *graphQLParser) definitionBody() (graphQLSelections, error) {
+	if p.peek() == "(" {
+		if err := p.skipBalanced(); err != nil {
+			return graphQLSelections{}, err
+		}
+	}
+	if err := p.skipDirectives(); err != nil {
+		return graphQLSelections{}, err
+	}
+	if p.next() != "{" {
+		return graphQLSelections{}, errors.New("expected selection set in GraphQL document")
+	}
+	return p.selectionSet()
+}
+
+// selectionSet parses the selections after an opening brace up to its closing brace
+func (p *graphQLParser) selectionSet() (graphQLSelections, error) {
+	selections := graphQLSelections{}
+	for {
+		switch token := p.next(); token {
+		case "}":
+			return selections, nil
+		case "":
+			return selections, errors.New("unterminated selection set in GraphQL document")
+		case "...":
+			if p.peek() == "on" || p.peek() == "@" || p.peek() == "{" {
+				if p.peek() == "on" {
+					p.pos += 2
+				}
+				inline, err := p.definitionBody()
+				if err != nil {
+					return selections, err
+				}
+				selections.fields = append(selections.fields, inline.fields...)
+				selections.spreads = append(selections.spreads, inline.spreads...)
+				continue
+			}
+			selections.spreads = append(selections.spreads, p.next())
+			if err := p.skipDirectives(); err != nil {
+				return selections, err
+			}
+		default:
+			if p.peek() == ":" {
+				p.pos++
+				token = p.next()
+			}
+			selections.fields = append(selections.fields, token)
+			if p.peek() == "(" {
+				if err := p.skipBalanced(); err != nil {
+					return selections, err
+				}
+			}
+			if err := p.skipDirectives(); err != nil {
+				return selections, err
+			}
+			if p.peek() == "{" {
+				if err := p.skipBalanced(); err != nil {
+					return selections, err
+				}
+			}
+		}
+	}
+}
+
+func (p *graphQLParser) skipDirectives() error {
+	for p.peek() == "@" {
+		p.pos += 2
+		if p.peek() == "(" {
+			if err := p.skipBalanced(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// skipBalanced skips from an opening bracket to its matching closing bracket
+func (p *graphQLParser) skipBalanced() error {
+	depth := 0
+	for !p.done() {
+		switch p.next() {
+		case "(", "{", "[":
+			depth++
+		case ")", "}", "]":
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+	return errors.New("unbalanced brackets in GraphQL document")
+}
+
+// graphQLTokens splits a GraphQL document into names, punctuators and literal values, dropping whitespace, commas and comments
+func graphQLTokens(query string) ([]string, error) {
+	tokens := []string{}
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(query[i:], "..."):
+			tokens = append(tokens, "...")
+			i += 3
+		case strings.HasPrefix(query[i:], `"""`):
+			end := strings.Index(query[i+3:], `"""`)
+			for end >= 0 && query[i+3+end-1] == '\\' {
+				next := strings.Index(query[i+3+end+1:], `"""`)
+				if next < 0 {
+					end = -1
+				} else {
+					end += next + 1
+				}
+			}
+			if end < 0 {
+				return nil, errors.New("unterminated block string in GraphQL document")
+			}
+			tokens = append(tokens, query[i:i+3+end+3])
+			i += 3 + end + 3
+		case c == '"':
+			j := i + 1
+			for j < len(query) && query[j] != '"' {
+				if query[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(query) {
+				return nil, errors.New("unterminated string in GraphQL document")
+			}
+			tokens = append(tokens, query[i:j+1])
+			i = j + 1
+		case strings.IndexByte("!$&()/:=@[]{}|", c) >= 0:
+			tokens = append(tokens, string(c))
+			i++
+		default:
+			j := i
+			for j < len(query) && isGraphQLNameByte(query[j]) {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("unexpected character %q in GraphQL document", c)
+			}
+			tokens = append(tokens, query[i:j])
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+// isGraphQLNameByte reports whether the byte can be part of a name or number
+func isGraphQLNameByte(c byte) bool {
+	return c == '_' || c == '-' || c == '+' || c == '.' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package mockservice_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/wchan2/mock_service"
+)
+
+func TestGraphQL(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	err = service.Endpoints().Load([]*mockservice.MockEndpoint{
+		mockservice.Post("/graphql").WithGraphQLOperation("GetUser", "user").WithGraphQLVariable("id", 1).
+			WillReturn(http.StatusOK).WithGraphQLData(map[string]interface{}{"user": map[string]string{"name": "Ada"}}).MustBuild(),
+		mockservice.Post("/graphql").WithGraphQLOperation("GetUser", "user").WithGraphQLVariable("id", 2).
+			WillReturn(http.StatusOK).WithGraphQLError("user 2 not found").MustBuild(),
+		mockservice.Post("/graphql").WithGraphQLOperation("", "orders", "viewer").
+			WillReturn(http.StatusOK).WithGraphQLData(map[string]interface{}{"orders": []string{}}).MustBuild(),
+		mockservice.Get("/graphql").WithGraphQLOperation("", "health").
+			WillReturn(http.StatusOK).WithGraphQLData(map[string]bool{"health": true}).MustBuild(),
+	})
+	if err != nil {
+		t.Fatalf("Expected loading the endpoints to succeed but got %s", err)
+	}
+
+	post := func(query, operationName string, variables map[string]interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{"query": query, "operationName": operationName, "variables": variables})
+		recorder := httptest.NewRecorder()
+		service.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+		return recorder
+	}
+
+	get := httptest.NewRecorder()
+	service.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape("{ health }"), nil))
+
+	cases := []struct {
+		name     string
+		response *httptest.ResponseRecorder
+		status   int
+		expected string
+	}{
+		{
+			"Operation_Name_And_Variables",
+			post("query GetUser($id: ID!) { user(id: $id) { name } }", "", map[string]interface{}{"id": 1, "verbose": true}),
+			http.StatusOK,
+			`{"data":{"user":{"name":"Ada"}}}`,
+		},
+		{
+			"Errors",
+			post("query GetUser($id: ID!) { user(id: $id) { name } }", "GetUser", map[string]interface{}{"id": 2}),
+			http.StatusOK,
+			`{"data":null,"errors":[{"message":"user 2 not found"}]}`,
+		},
+		{
+			"Selected_Operation_With_Aliases_And_Fragments",
+			post(`
+				# the dashboard queries
+				query Profile { me: viewer { id } }
+				query Dashboard @cached(ttl: 60) {
+					recent: orders(first: 10, filter: {status: "OPEN, PENDING"}) { id }
+					...ViewerFields
+				}
+				fragment ViewerFields on Query { viewer { name } }
+			`, "Dashboard", nil),
+			http.StatusOK,
+			`{"data":{"orders":[]}}`,
+		},
+		{
+			"Unmatched_Variables",
+			post("query GetUser($id: ID!) { user(id: $id) { name } }", "GetUser", map[string]interface{}{"id": 3}),
+			http.StatusNotFound,
+			"Endpoint does not exist\n",
+		},
+		{
+			"Unmatched_Operation",
+			post("mutation DeleteUser { deleteUser(id: 1) }", "", nil),
+			http.StatusNotFound,
+			"Endpoint does not exist\n",
+		},
+		{"GET", get, http.StatusOK, `{"data":{"health":true}}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.response.Code != c.status {
+				t.Errorf("Expected status %d but got %d", c.status, c.response.Code)
+			}
+			if strings.TrimSpace(c.response.Body.String()) != strings.TrimSpace(c.expected) {
+				t.Errorf("Expected body %s but got %s", c.expected, c.response.Body.String())
+			}
+		})
+	}
+}
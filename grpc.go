@@ -0,0 +1,208 @@
+package mockservice
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidGRPCStatus is returned when attempting to add a mock endpoint with a gRPC status code outside of 0-16
+	ErrInvalidGRPCStatus = errors.New("Invalid gRPC status code provided")
+
+	// ErrInvalidGRPCMessage is returned when attempting to add a mock endpoint with a gRPC response message that is not a JSON object
+	ErrInvalidGRPCMessage = errors.New("Invalid gRPC response message provided")
+)
+
+// gRPC status codes used by the mock service itself
+const (
+	grpcOK              = 0
+	grpcInvalidArgument = 3
+	grpcUnimplemented   = 12
+	grpcInternal        = 13
+	grpcMaxStatus       = 16
+)
+
+// GRPCResponse answers gRPC calls to a mock endpoint whose method is POST and whose endpoint is the fully-qualified
+// gRPC method, such as "/helloworld.Greeter/SayHello".
+//
+// The request and response messages are converted between protobuf and JSON using the descriptor sets loaded with
+// MockService.LoadDescriptorSet, so BodyMatchers match the request message rendered as JSON. RequestHeaders match the
+// request metadata, while ResponseHeaders and ResponseTrailers are sent as the response header and trailer metadata.
+type GRPCResponse struct {
+	// Messages are the JSON response messages; server-streaming methods may respond with several.
+	// A unary method responding with an OK status and no messages returns an empty message.
+	Messages []json.RawMessage `json:"messages,omitempty" xml:"messages,omitempty"`
+	// Status is the gRPC status code, such as 5 for NOT_FOUND
+	Status int `json:"status,omitempty" xml:"status,omitempty"`
+	// StatusMessage describes a status other than OK
+	StatusMessage string `json:"statusMessage,omitempty" xml:"statusMessage,omitempty"`
+	// Delay is the time waited before each streamed message is sent
+	Delay Duration `json:"delay,omitempty" xml:"delay,omitempty"`
+}
+
+func (g *GRPCResponse) validate() error {
+	if g.Status < grpcOK || g.Status > grpcMaxStatus {
+		return ErrInvalidGRPCStatus
+	}
+	for _, message := range g.Messages {
+		obj := map[string]json.RawMessage{}
+		if err := json.Unmarshal(message, &obj); err != nil {
+			return ErrInvalidGRPCMessage
+		}
+	}
+	return nil
+}
+
+// isGRPC reports whether the request is a gRPC call
+func isGRPC(req *http.Request) bool {
+	contentType := req.Header.Get("Content-Type")
+	return strings.HasPrefix(contentType, "application/grpc") && !strings.HasPrefix(contentType, "application/grpc-web")
+}
+
+// serveGRPC converts the request messages of the gRPC call to JSON, matches them to a mock endpoint and responds with its messages
+func (m *EndpointService) serveGRPC(w http.ResponseWriter, req *http.Request, body []byte) {
+	method := m.descriptors.method(strings.TrimPrefix(req.URL.Path, "/"))
+	if method == nil {
+		m.record(w, req, body, false)
+		writeGRPCStatus(w, grpcUnimplemented, fmt.Sprintf("Unknown gRPC method %s", req.URL.Path))
+		return
+	}
+
+	messages, err := grpcMessages(method.input, body)
+	if err != nil {
+		m.record(w, req, body, false)
+		writeGRPCStatus(w, grpcInvalidArgument, fmt.Sprintf("Unable to decode request message: %s", err))
+		return
+	}
+	requestJSON := []byte("{}")
+	if method.clientStreaming {
+		requestJSON, _ = json.Marshal(messages)
+	} else if len(messages) > 0 {
+		requestJSON = messages[len(messages)-1]
+	}
+
+	endpoint, err := m.mockedEndpoints.Match(req, requestJSON)
+	m.record(w, req, requestJSON, err == nil)
+	if err != nil {
+		writeGRPCStatus(w, grpcUnimplemented, fmt.Sprintf("No mock matches the call to %s", method.fullName))
+		return
+	}
+	writeGRPCResponse(w, req, method, endpoint)
+}
+
+// grpcMessages splits the length-prefixed messages of a gRPC request body and renders each one as JSON
+func grpcMessages(message *protoMessage, body []byte) ([]json.RawMessage, error) {
+	messages := []json.RawMessage{}
+	for len(body) > 0 {
+		if len(body) < 5 {
+			return nil, errTruncatedProtobuf
+		}
+		if body[0] != 0 {
+			return nil, errors.New("compressed messages are not supported")
+		}
+		length := binary.BigEndian.Uint32(body[1:5])
+		if uint64(len(body)-5) < uint64(length) {
+			return nil, errTruncatedProtobuf
+		}
+		rendered, err := protoToJSON(message, body[5:5+length])
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, rendered)
+		body = body[5+length:]
+	}
+	return messages, nil
+}
+
+// writeGRPCResponse streams the endpoint's messages converted to protobuf, followed by its status in the trailers
+func writeGRPCResponse(w http.ResponseWriter, req *http.Request, method *protoMethod, endpoint *MockEndpoint) {
+	response := endpoint.GRPC
+	if response == nil {
+		response = &GRPCResponse{}
+	}
+	messages := response.Messages
+	if len(messages) == 0 && response.Status == grpcOK && !method.serverStreaming {
+		messages = []json.RawMessage{json.RawMessage("{}")}
+	}
+	if len(messages) > 1 && !method.serverStreaming {
+		writeGRPCStatus(w, grpcInternal, fmt.Sprintf("Mock responds with %d messages to the unary method %s", len(messages), method.fullName))
+		return
+	}
+
+	frames := make([][]byte, 0, len(messages))
+	for _, message := range messages {
+		encoded, err := jsonToProto(method.output, message)
+		if err != nil {
+			log.Printf("Unable to convert gRPC response message for %s: %s", method.fullName, err)
+			writeGRPCStatus(w, grpcInternal, fmt.Sprintf("Unable to convert response message: %s", err))
+			return
+		}
+		frame := make([]byte, 5, 5+len(encoded))
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(encoded)))
+		frames = append(frames, append(frame, encoded...))
+	}
+
+	for headerKey, headerVal := range endpoint.ResponseHeaders {
+		w.Header().Add(headerKey, headerVal)
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	for i, frame := range frames {
+		if i > 0 && response.Delay > 0 {
+			select {
+			case <-time.After(time.Duration(response.Delay)):
+			case <-req.Context().Done():
+				return
+			}
+		}
+		if _, err := w.Write(frame); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	for trailerKey, trailerVal := range endpoint.ResponseTrailers {
+		w.Header().Set(http.TrailerPrefix+trailerKey, trailerVal)
+	}
+	setGRPCStatus(w, response.Status, response.StatusMessage)
+}
+
+// writeGRPCStatus responds to a gRPC call with a status and no messages
+func writeGRPCStatus(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.WriteHeader(http.StatusOK)
+	setGRPCStatus(w, status, message)
+}
+
+// setGRPCStatus sets the grpc-status and grpc-message trailers once the response has been written
+func setGRPCStatus(w http.ResponseWriter, status int, message string) {
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(status))
+	if message != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", encodeGRPCMessage(message))
+	}
+}
+
+// encodeGRPCMessage percent-encodes the status message as required by the gRPC protocol
+func encodeGRPCMessage(message string) string {
+	var b strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
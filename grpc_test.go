@@ -0,0 +1,221 @@
+package mockservice_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/wchan2/mock_service"
+)
+
+// protobuf builds protobuf wire format by hand so the tests do not depend on a protobuf library
+type protobuf []byte
+
+func (p protobuf) varint(number int, val uint64) protobuf {
+	p = binary.AppendUvarint(p, uint64(number)<<3)
+	return binary.AppendUvarint(p, val)
+}
+
+func (p protobuf) bytes(number int, val []byte) protobuf {
+	p = binary.AppendUvarint(p, uint64(number)<<3|2)
+	p = binary.AppendUvarint(p, uint64(len(val)))
+	return append(p, val...)
+}
+
+func (p protobuf) str(number int, val string) protobuf {
+	return p.bytes(number, []byte(val))
+}
+
+// field builds a google.protobuf.FieldDescriptorProto
+func field(name string, number, label, typ int, typeName string) []byte {
+	f := protobuf{}.str(1, name).varint(3, uint64(number)).varint(4, uint64(label)).varint(5, uint64(typ))
+	if typeName != "" {
+		f = f.str(6, typeName)
+	}
+	return f
+}
+
+// greeterDescriptorSet describes:
+//
+//	syntax = "proto3";
+//	package helloworld;
+//	enum Mood { MOOD_UNKNOWN = 0; HAPPY = 1; }
+//	message HelloRequest { string name = 1; repeated int32 lucky_numbers = 2; Mood mood = 3; }
+//	message HelloReply { string message = 1; int64 count = 2; map<string, string> labels = 3; }
+//	service Greeter {
+//	  rpc SayHello (HelloRequest) returns (HelloReply);
+//	  rpc SayHellos (HelloRequest) returns (stream HelloReply);
+//	}
+func greeterDescriptorSet() []byte {
+	request := protobuf{}.str(1, "HelloRequest").
+		bytes(2, field("name", 1, 1, 9, "")).
+		bytes(2, field("lucky_numbers", 2, 3, 5, "")).
+		bytes(2, field("mood", 3, 1, 14, ".helloworld.Mood"))
+	labelsEntry := protobuf{}.str(1, "LabelsEntry").
+		bytes(2, field("key", 1, 1, 9, "")).
+		bytes(2, field("value", 2, 1, 9, "")).
+		bytes(7, protobuf{}.varint(7, 1))
+	reply := protobuf{}.str(1, "HelloReply").
+		bytes(2, field("message", 1, 1, 9, "")).
+		bytes(2, field("count", 2, 1, 3, "")).
+		bytes(2, field("labels", 3, 3, 11, ".helloworld.HelloReply.LabelsEntry")).
+		bytes(3, labelsEntry)
+	mood := protobuf{}.str(1, "Mood").
+		bytes(2, protobuf{}.str(1, "MOOD_UNKNOWN").varint(2, 0)).
+		bytes(2, protobuf{}.str(1, "HAPPY").varint(2, 1))
+	service := protobuf{}.str(1, "Greeter").
+		bytes(2, protobuf{}.str(1, "SayHello").str(2, ".helloworld.HelloRequest").str(3, ".helloworld.HelloReply")).
+		bytes(2, protobuf{}.str(1, "SayHellos").str(2, ".helloworld.HelloRequest").str(3, ".helloworld.HelloReply").varint(6, 1))
+	file := protobuf{}.str(1, "greeter.proto").str(2, "helloworld").
+		bytes(4, request).bytes(4, reply).bytes(5, mood).bytes(6, service).str(12, "proto3")
+	return protobuf{}.bytes(1, file)
+}
+
+// grpcFrame prefixes the message with the gRPC message header
+func grpcFrame(message []byte) []byte {
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	return append(frame, message...)
+}
+
+func callGRPC(t *testing.T, server *httptest.Server, method string, message []byte) (*http.Response, []byte) {
+	req, err := http.NewRequest(http.MethodPost, server.URL+method, bytes.NewReader(grpcFrame(message)))
+	if err != nil {
+		t.Fatalf("Expected creating the request to succeed but got %s", err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	req.Header.Set("X-Tenant", "acme")
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("Expected the gRPC call to succeed but got %s", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Expected reading the response to succeed but got %s", err)
+	}
+	return resp, body
+}
+
+func TestGRPC(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	if err := service.LoadDescriptorSet(greeterDescriptorSet()); err != nil {
+		t.Fatalf("Expected loading the descriptor set to succeed but got %s", err)
+	}
+	err = service.Endpoints().Load([]*mockservice.MockEndpoint{
+		{
+			Method:           http.MethodPost,
+			Endpoint:         "/helloworld.Greeter/SayHello",
+			RequestHeaders:   map[string]string{"X-Tenant": "acme"},
+			BodyMatchers:     []mockservice.BodyMatcher{{EqualToJSON: json.RawMessage(`{"name": "world", "luckyNumbers": [7, 11], "mood": "HAPPY"}`)}},
+			ResponseHeaders:  map[string]string{"X-Greeter": "mock"},
+			ResponseTrailers: map[string]string{"X-Checksum": "abc"},
+			GRPC: &mockservice.GRPCResponse{Messages: []json.RawMessage{
+				json.RawMessage(`{"message": "Hello world", "count": "3", "labels": {"lang": "en"}}`),
+			}},
+		},
+		{
+			Method:       http.MethodPost,
+			Endpoint:     "/helloworld.Greeter/SayHello",
+			BodyMatchers: []mockservice.BodyMatcher{{Contains: `"name":"nobody"`}},
+			GRPC:         &mockservice.GRPCResponse{Status: 5, StatusMessage: "no greeting for nobody"},
+		},
+		{
+			Method:   http.MethodPost,
+			Endpoint: "/helloworld.Greeter/SayHellos",
+			GRPC: &mockservice.GRPCResponse{Messages: []json.RawMessage{
+				json.RawMessage(`{"message": "Hello"}`),
+				json.RawMessage(`{"message": "Bonjour"}`),
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected loading the endpoints to succeed but got %s", err)
+	}
+
+	server := httptest.NewUnstartedServer(service)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	world := protobuf{}.str(1, "world").bytes(2, []byte{7, 11}).varint(3, 1)
+
+	t.Run("Unary", func(t *testing.T) {
+		resp, body := callGRPC(t, server, "/helloworld.Greeter/SayHello", world)
+		expected := grpcFrame(protobuf{}.varint(2, 3).bytes(3, protobuf{}.str(1, "lang").str(2, "en")).str(1, "Hello world"))
+		if !bytes.Equal(body, expected) {
+			t.Errorf("Expected the response %x but got %x", expected, body)
+		}
+		if resp.Header.Get("Content-Type") != "application/grpc" || resp.Header.Get("X-Greeter") != "mock" {
+			t.Errorf("Expected the gRPC content type and header metadata but got %v", resp.Header)
+		}
+		if resp.Trailer.Get("Grpc-Status") != "0" || resp.Trailer.Get("X-Checksum") != "abc" {
+			t.Errorf("Expected the OK status and trailer metadata but got %v", resp.Trailer)
+		}
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		resp, body := callGRPC(t, server, "/helloworld.Greeter/SayHello", protobuf{}.str(1, "nobody"))
+		if len(body) != 0 {
+			t.Errorf("Expected no response messages but got %x", body)
+		}
+		if resp.Trailer.Get("Grpc-Status") != "5" || resp.Trailer.Get("Grpc-Message") != "no greeting for nobody" {
+			t.Errorf("Expected the NOT_FOUND status but got %v", resp.Trailer)
+		}
+	})
+
+	t.Run("Server_Streaming", func(t *testing.T) {
+		resp, body := callGRPC(t, server, "/helloworld.Greeter/SayHellos", world)
+		expected := append(grpcFrame(protobuf{}.str(1, "Hello")), grpcFrame(protobuf{}.str(1, "Bonjour"))...)
+		if !bytes.Equal(body, expected) {
+			t.Errorf("Expected the response %x but got %x", expected, body)
+		}
+		if resp.Trailer.Get("Grpc-Status") != "0" {
+			t.Errorf("Expected the OK status but got %v", resp.Trailer)
+		}
+	})
+
+	t.Run("Unmatched", func(t *testing.T) {
+		for _, method := range []string{"/helloworld.Greeter/SayHello", "/helloworld.Greeter/SayGoodbye"} {
+			resp, _ := callGRPC(t, server, method, protobuf{}.str(1, "stranger"))
+			if resp.Trailer.Get("Grpc-Status") != "12" {
+				t.Errorf("Expected the UNIMPLEMENTED status for %s but got %v", method, resp.Trailer)
+			}
+		}
+	})
+
+	requests := service.Journal().Requests()
+	if len(requests) != 5 {
+		t.Fatalf("Expected 5 recorded requests but got %d", len(requests))
+	}
+	if requests[0].Body != `{"luckyNumbers":[7,11],"mood":"HAPPY","name":"world"}` || !requests[0].Matched {
+		t.Errorf("Expected the request message to be recorded as JSON but got %s", requests[0].Body)
+	}
+}
+
+func TestGRPCResponseValidation(t *testing.T) {
+	endpoints := mockservice.NewEndpoints()
+	for _, c := range []struct {
+		name     string
+		response *mockservice.GRPCResponse
+		expected error
+	}{
+		{"Invalid_Status", &mockservice.GRPCResponse{Status: 17}, mockservice.ErrInvalidGRPCStatus},
+		{"Invalid_Message", &mockservice.GRPCResponse{Messages: []json.RawMessage{json.RawMessage(`"hello"`)}}, mockservice.ErrInvalidGRPCMessage},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			err := endpoints.Create(&mockservice.MockEndpoint{Method: http.MethodPost, Endpoint: "/helloworld.Greeter/SayHello", GRPC: c.response})
+			if err != c.expected {
+				t.Errorf("Expected %s but got %v", c.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package mockservice
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrInvalidHAR is returned when importing a document that is not a HAR log
+var ErrInvalidHAR = errors.New("Invalid HAR document provided")
+
+// volatileHeaders are response headers whose values change from one response to the next
+var volatileHeaders = []string{"Age", "Date", "ETag", "Expires", "Last-Modified", "Report-To", "Server-Timing", "Set-Cookie", "X-Request-Id", "X-Trace-Id", "Cf-Ray"}
+
+// encodingHeaders describe how the recorded response was transferred rather than its decoded body, and are never imported
+var encodingHeaders = []string{"Connection", "Content-Encoding", "Content-Length", "Keep-Alive", "Transfer-Encoding"}
+
+// HAROptions configures the mock endpoints imported from a HAR log
+type HAROptions struct {
+	// Hosts only imports the requests sent to these hosts, with or without their port
+	Hosts []string `json:"hosts,omitempty" xml:"hosts,omitempty"`
+	// PathPrefix only imports the requests whose path starts with the prefix
+	PathPrefix string `json:"pathPrefix,omitempty" xml:"pathPrefix,omitempty"`
+	// RequestHeaders are the request headers that mock endpoints match on when they were recorded
+	RequestHeaders []string `json:"requestHeaders,omitempty" xml:"requestHeaders,omitempty"`
+	// MatchBody makes mock endpoints match the recorded request body, semantically for JSON bodies
+	MatchBody bool `json:"matchBody,omitempty" xml:"matchBody,omitempty"`
+	// Deduplicate drops requests identical to an earlier one, so that the first recorded response is served. Otherwise
+	// all the requests are imported, and the last recorded response is served as loading replaces identical endpoints.
+	Deduplicate bool `json:"deduplicate,omitempty" xml:"deduplicate,omitempty"`
+	// StripVolatileHeaders drops response headers such as Date, ETag or Set-Cookie that change between responses
+	StripVolatileHeaders bool `json:"stripVolatileHeaders,omitempty" xml:"stripVolatileHeaders,omitempty"`
+	// StripHeaders are other response headers to drop
+	StripHeaders []string `json:"stripHeaders,omitempty" xml:"stripHeaders,omitempty"`
+}
+
+// harDocument is a HAR 1.2 document
+type harDocument struct {
+	Log *harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []harPair    `json:"cookies"`
+	Headers     []harPair    `json:"headers"`
+	QueryString []harPair    `json:"queryString"`
+	PostData    *harPostData `json:"postData,omitempty"`
+	HeadersSize int          `json:"headersSize"`
+	BodySize    int          `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status      int        `json:"status"`
+	StatusText  string     `json:"statusText"`
+	HTTPVersion string     `json:"httpVersion"`
+	Cookies     []harPair  `json:"cookies"`
+	Headers     []harPair  `json:"headers"`
+	Content     harContent `json:"content"`
+	RedirectURL string     `json:"redirectURL"`
+	HeadersSize int        `json:"headersSize"`
+	BodySize    int        `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harPair is a header, cookie or query parameter of a HAR request or response
+type harPair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ImportHAR converts the entries of a HAR 1.2 log, as captured by browsers and proxies, into mock endpoints that match
+// the recorded method, path and query and respond with the recorded response. Entries without a response, such as
+// blocked or aborted requests, are skipped. The returned Conf has no registration endpoint.
+func ImportHAR(har []byte, options *HAROptions) (*Conf, error) {
+	if options == nil {
+		options = &HAROptions{}
+	}
+	doc := harDocument{}
+	if err := json.Unmarshal(har, &doc); err != nil {
+		return nil, fmt.Errorf("Unable to parse HAR document: %s", err)
+	}
+	if doc.Log == nil {
+		return nil, ErrInvalidHAR
+	}
+
+	stripped := map[string]bool{}
+	strip := append([]string{}, encodingHeaders...)
+	if options.StripVolatileHeaders {
+		strip = append(strip, volatileHeaders...)
+	}
+	for _, header := range append(strip, options.StripHeaders...) {
+		stripped[http.CanonicalHeaderKey(header)] = true
+	}
+
+	conf := &Conf{Endpoints: []*MockEndpoint{}}
+	for i, entry := range doc.Log.Entries {
+		requestURL, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse the URL of HAR entry %d: %s", i, err)
+		}
+		if entry.Response.Status == 0 || !importHAREntry(requestURL, options) {
+			continue
+		}
+
+		endpoint, err := harEndpoint(&entry, requestURL, options, stripped)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to import HAR entry %d: %s", i, err)
+		}
+		if options.Deduplicate && containsRoute(conf.Endpoints, endpoint) {
+			continue
+		}
+		conf.Endpoints = append(conf.Endpoints, endpoint)
+	}
+	return conf, nil
+}
+
+// importHAREntry reports whether the request URL passes the host and path prefix filters
+func importHAREntry(requestURL *url.URL, options *HAROptions) bool {
+	if requestURL.Scheme != "http" && requestURL.Scheme != "https" {
+		return false
+	}
+	if len(options.Hosts) > 0 && !containsString(options.Hosts, requestURL.Host) && !containsString(options.Hosts, requestURL.Hostname()) {
+		return false
+	}
+	return strings.HasPrefix(requestURL.Path, options.PathPrefix)
+}
+
+// harEndpoint builds the mock endpoint of a HAR entry
+func harEndpoint(entry *harEntry, requestURL *url.URL, options *HAROptions, stripped map[string]bool) (*MockEndpoint, error) {
+	endpoint := &MockEndpoint{
+		Method:          strings.ToUpper(entry.Request.Method),
+		Endpoint:        requestURL.Path,
+		StatusCode:      entry.Response.Status,
+		ResponseHeaders: map[string]string{},
+	}
+	if endpoint.Endpoint == "" {
+		endpoint.Endpoint = "/"
+	}
+	if !validPathTemplate(endpoint.Endpoint) {
+		return nil, fmt.Errorf("unsupported path %s", endpoint.Endpoint)
+	}
+
+	query := requestURL.Query()
+	if len(query) > 0 {
+		endpoint.QueryParameters = map[string]string{}
+		for key, values := range query {
+			endpoint.QueryParameters[key] = values[0]
+		}
+	}
+
+	for _, name := range options.RequestHeaders {
+		for _, header := range entry.Request.Headers {
+			if strings.EqualFold(header.Name, name) {
+				if endpoint.RequestHeaders == nil {
+					endpoint.RequestHeaders = map[string]string{}
+				}
+				endpoint.RequestHeaders[http.CanonicalHeaderKey(name)] = header.Value
+				break
+			}
+		}
+	}
+
+	if postData := entry.Request.PostData; options.MatchBody && postData != nil && postData.Text != "" {
+		mediaType, _, _ := mime.ParseMediaType(postData.MimeType)
+		if isJSONMediaType(mediaType) && json.Valid([]byte(postData.Text)) {
+			endpoint.BodyMatchers = []BodyMatcher{{EqualToJSON: json.RawMessage(postData.Text)}}
+		} else {
+			endpoint.RequestBody = postData.Text
+		}
+	}
+
+	for _, header := range entry.Response.Headers {
+		name := http.CanonicalHeaderKey(header.Name)
+		if stripped[name] || strings.HasPrefix(name, ":") {
+			continue
+		}
+		if val, ok := endpoint.ResponseHeaders[name]; ok {
+			endpoint.ResponseHeaders[name] = val + ", " + header.Value
+		} else {
+			endpoint.ResponseHeaders[name] = header.Value
+		}
+	}
+	if _, ok := endpoint.ResponseHeaders["Content-Type"]; !ok && entry.Response.Content.MimeType != "" {
+		endpoint.ResponseHeaders["Content-Type"] = entry.Response.Content.MimeType
+	}
+
+	endpoint.ResponseBody = entry.Response.Content.Text
+	if entry.Response.Content.Encoding == "base64" {
+		body, err := base64.StdEncoding.DecodeString(entry.Response.Content.Text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 response body: %s", err)
+		}
+		endpoint.ResponseBody = string(body)
+	}
+	return endpoint, nil
+}
+
+// containsRoute reports whether one of the endpoints matches the same requests as the endpoint
+func containsRoute(endpoints []*MockEndpoint, endpoint *MockEndpoint) bool {
+	for _, existing := range endpoints {
+		if existing.SameRoute(endpoint) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExportHAR converts the recorded requests and the responses sent to them into a HAR 1.2 log that browser devtools and
+// other HAR viewers can open. Requests still being responded to, such as open event streams, have no response status.
+func ExportHAR(requests []*RecordedRequest) ([]byte, error) {
+	entries := make([]harEntry, 0, len(requests))
+	for _, req := range requests {
+		requestURL := url.URL{Scheme: req.Scheme, Host: req.Host, Path: req.Endpoint, RawQuery: req.Query}
+		if requestURL.Scheme == "" {
+			requestURL.Scheme = "http"
+		}
+		entry := harEntry{
+			StartedDateTime: req.Time.Format(time.RFC3339Nano),
+			Request:         harRequestOf(req.Method, requestURL.String(), req.Protocol, req.Headers, req.Query, req.Body),
+			Response:        harResponse{Cookies: []harPair{}, Headers: []harPair{}, HeadersSize: -1, BodySize: -1},
+		}
+		if res := req.Response; res != nil {
+			milliseconds := float64(time.Duration(res.Duration)) / float64(time.Millisecond)
+			entry.Time = milliseconds
+			entry.Timings.Wait = milliseconds
+			entry.Response = harResponseOf(res.StatusCode, req.Protocol, res.Headers, res.Body)
+			if res.Truncated {
+				entry.Response.Content.Comment = "Body truncated"
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return marshalHAR(entries)
+}
+
+// ExportEndpointsHAR converts mock endpoints into a HAR 1.2 log with an entry for the request each endpoint matches
+// and the response it returns, requested from the base URL such as "http://localhost:8080"
+func ExportEndpointsHAR(endpoints []*MockEndpoint, baseURL string) ([]byte, error) {
+	entries := make([]harEntry, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		query := url.Values{}
+		for key, value := range endpoint.QueryParameters {
+			query.Set(key, value)
+		}
+		headers := http.Header{}
+		for key, value := range endpoint.RequestHeaders {
+			headers.Set(key, value)
+		}
+		responseHeaders := http.Header{}
+		for key, value := range endpoint.ResponseHeaders {
+			responseHeaders.Set(key, value)
+		}
+
+		requestURL := strings.TrimSuffix(baseURL, "/") + endpoint.Endpoint
+		if len(query) > 0 {
+			requestURL += "?" + query.Encode()
+		}
+		entries = append(entries, harEntry{
+			StartedDateTime: time.Now().Format(time.RFC3339Nano),
+			Request:         harRequestOf(endpoint.Method, requestURL, "HTTP/1.1", headers, query.Encode(), endpoint.RequestBody),
+			Response:        harResponseOf(endpoint.StatusCode, "HTTP/1.1", responseHeaders, endpoint.ResponseBody),
+		})
+	}
+	return marshalHAR(entries)
+}
+
+func marshalHAR(entries []harEntry) ([]byte, error) {
+	return json.MarshalIndent(harDocument{Log: &harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "mockservice", Version: "1.0"},
+		Entries: entries,
+	}}, "", "  ")
+}
+
+func harRequestOf(method, requestURL, protocol string, headers http.Header, rawQuery, body string) harRequest {
+	request := harRequest{
+		Method:      method,
+		URL:         requestURL,
+		HTTPVersion: protocol,
+		Cookies:     harCookies((&http.Request{Header: headers}).Cookies()),
+		Headers:     harPairs(headers),
+		QueryString: []harPair{},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	query, _ := url.ParseQuery(rawQuery)
+	request.QueryString = harPairs(query)
+	if body != "" {
+		request.PostData = &harPostData{MimeType: headers.Get("Content-Type"), Text: body}
+	}
+	return request
+}
+
+func harResponseOf(statusCode int, protocol string, headers http.Header, body string) harResponse {
+	response := harResponse{
+		Status:      statusCode,
+		StatusText:  http.StatusText(statusCode),
+		HTTPVersion: protocol,
+		Cookies:     harCookies((&http.Response{Header: headers}).Cookies()),
+		Headers:     harPairs(headers),
+		Content:     harContent{Size: len(body), MimeType: headers.Get("Content-Type"), Text: body},
+		RedirectURL: headers.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	if !utf8.ValidString(body) {
+		response.Content.Text = base64.StdEncoding.EncodeToString([]byte(body))
+		response.Content.Encoding = "base64"
+	}
+	return response
+}
+
+// harPairs lists the values of headers or query parameters sorted by name
+func harPairs(values map[string][]string) []harPair {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := []harPair{}
+	for _, name := range names {
+		for _, value := range values[name] {
+			pairs = append(pairs, harPair{Name: name, Value: value})
+		}
+	}
+	return pairs
+}
+
+func harCookies(cookies []*http.Cookie) []harPair {
+	pairs := []harPair{}
+	for _, cookie := range cookies {
+		pairs = append(pairs, harPair{Name: cookie.Name, Value: cookie.Value})
+	}
+	return pairs
+}
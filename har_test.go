@@ -0,0 +1,332 @@
+package mockservice_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wchan2/mock_service"
+)
+
+const stagingHAR = `{
+	"log": {
+		"version": "1.2",
+		"creator": {"name": "WebInspector", "version": "537.36"},
+		"entries": [
+			{
+				"request": {
+					"method": "GET",
+					"url": "https://api.staging.example.com/api/users?page=2",
+					"headers": [{"name": "authorization", "value": "Bearer abc"}, {"name": "Accept", "value": "application/json"}]
+				},
+				"response": {
+					"status": 200,
+					"headers": [
+						{"name": "content-type", "value": "application/json"},
+						{"name": "date", "value": "Mon, 19 Oct 2026 10:00:00 GMT"},
+						{"name": "content-encoding", "value": "gzip"},
+						{"name": "x-debug", "value": "1"},
+						{"name": "vary", "value": "Accept"},
+						{"name": "vary", "value": "Origin"}
+					],
+					"content": {"mimeType": "application/json", "text": "[{\"id\":1}]"}
+				}
+			},
+			{
+				"request": {"method": "GET", "url": "https://api.staging.example.com/api/users?page=2", "headers": [{"name": "Authorization", "value": "Bearer abc"}]},
+				"response": {"status": 500, "headers": [], "content": {"text": "later"}}
+			},
+			{
+				"request": {
+					"method": "POST",
+					"url": "https://api.staging.example.com:443/api/users",
+					"headers": [],
+					"postData": {"mimeType": "application/json; charset=utf-8", "text": "{\"name\": \"gopher\"}"}
+				},
+				"response": {"status": 201, "headers": [], "content": {"mimeType": "image/png", "encoding": "base64", "text": "iVBORw=="}}
+			},
+			{
+				"request": {"method": "GET", "url": "https://cdn.example.com/api/logo.png", "headers": []},
+				"response": {"status": 200, "headers": [], "content": {"text": ""}}
+			},
+			{
+				"request": {"method": "GET", "url": "https://api.staging.example.com/static/app.js", "headers": []},
+				"response": {"status": 200, "headers": [], "content": {"text": ""}}
+			},
+			{
+				"request": {"method": "GET", "url": "https://api.staging.example.com/api/blocked", "headers": []},
+				"response": {"status": 0, "headers": [], "content": {}}
+			}
+		]
+	}
+}`
+
+func TestImportHAR(t *testing.T) {
+	conf, err := mockservice.ImportHAR([]byte(stagingHAR), &mockservice.HAROptions{
+		Hosts:                []string{"api.staging.example.com"},
+		PathPrefix:           "/api",
+		RequestHeaders:       []string{"Authorization"},
+		MatchBody:            true,
+		Deduplicate:          true,
+		StripVolatileHeaders: true,
+		StripHeaders:         []string{"X-Debug"},
+	})
+	if err != nil {
+		t.Fatalf("Expected importing the HAR log to succeed but got %s", err)
+	}
+
+	expected := []*mockservice.MockEndpoint{
+		{
+			Method:          http.MethodGet,
+			Endpoint:        "/api/users",
+			StatusCode:      http.StatusOK,
+			ResponseBody:    `[{"id":1}]`,
+			ResponseHeaders: map[string]string{"Content-Type": "application/json", "Vary": "Accept, Origin"},
+			RequestHeaders:  map[string]string{"Authorization": "Bearer abc"},
+			QueryParameters: map[string]string{"page": "2"},
+		},
+		{
+			Method:          http.MethodPost,
+			Endpoint:        "/api/users",
+			StatusCode:      http.StatusCreated,
+			ResponseBody:    "\x89PNG",
+			ResponseHeaders: map[string]string{"Content-Type": "image/png"},
+			BodyMatchers:    []mockservice.BodyMatcher{{EqualToJSON: json.RawMessage(`{"name": "gopher"}`)}},
+		},
+	}
+	if !reflect.DeepEqual(conf.Endpoints, expected) {
+		got, _ := json.Marshal(conf.Endpoints)
+		t.Errorf("Expected the staging API endpoints but got %s", got)
+	}
+}
+
+func TestImportHAR_Replay(t *testing.T) {
+	conf, err := mockservice.ImportHAR([]byte(stagingHAR), nil)
+	if err != nil {
+		t.Fatalf("Expected importing the HAR log to succeed but got %s", err)
+	}
+	if len(conf.Endpoints) != 5 {
+		t.Fatalf("Expected every answered request to be imported but got %d endpoints", len(conf.Endpoints))
+	}
+
+	conf.RegistrationEndpoint = "/mocks"
+	service, err := mockservice.NewWithConf(conf)
+	if err != nil {
+		t.Fatalf("Expected creating the mock service to succeed but got %s", err)
+	}
+
+	recorder := serve(service, http.MethodGet, "/api/users?page=2", "")
+	if recorder.Code != http.StatusInternalServerError || recorder.Body.String() != "later" {
+		t.Errorf("Expected the last recorded response to win without deduplication but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := serve(service, http.MethodGet, "/api/users?page=3", ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected a different query not to match but got %d", recorder.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(`{"name":"other"}`))
+	recorder = httptest.NewRecorder()
+	service.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusCreated {
+		t.Errorf("Expected the request body to be ignored without MatchBody but got %d", recorder.Code)
+	}
+	if date := recorder.Header().Get("Date"); date != "" {
+		t.Errorf("Expected no recorded Date header for an entry without one but got %s", date)
+	}
+}
+
+func TestImportHAR_Errors(t *testing.T) {
+	cases := []struct {
+		name string
+		har  string
+	}{
+		{"Invalid_JSON", `{"log":`},
+		{"Invalid_base64", `{"log": {"entries": [{"request": {"method": "GET", "url": "http://a/b"}, "response": {"status": 200, "content": {"encoding": "base64", "text": "!"}}}]}}`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := mockservice.ImportHAR([]byte(c.har), nil); err == nil {
+				t.Errorf("Expected importing the HAR log to fail")
+			}
+		})
+	}
+
+	if _, err := mockservice.ImportHAR([]byte(`{"entries": []}`), nil); err != mockservice.ErrInvalidHAR {
+		t.Errorf("Expected %s but got %v", mockservice.ErrInvalidHAR, err)
+	}
+}
+
+func TestAdminService_ImportHAR(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+
+	recorder := serve(service, http.MethodPost, "/mocks/har?host=api.staging.example.com&pathPrefix=/api&dedupe=true&matchBody=true", stagingHAR)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected %d status but got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+	}
+	if recorder := serve(service, http.MethodGet, "/api/users?page=2", ""); recorder.Code != http.StatusOK || recorder.Body.String() != `[{"id":1}]` {
+		t.Errorf("Expected the first recorded response with deduplication but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := serve(service, http.MethodPost, "/api/users", `{"name":"other"}`); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected a different request body not to match but got %d", recorder.Code)
+	}
+
+	if recorder := serve(service, http.MethodPost, "/mocks/har", `[]`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected %d status for an invalid HAR log but got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
+
+func TestAdminService_ImportHAR_Atomic(t *testing.T) {
+	invalidHAR := `{"log": {"entries": [
+		{"request": {"method": "GET", "url": "https://api.example.com/first", "headers": []}, "response": {"status": 200, "headers": [], "content": {}}},
+		{"request": {"method": "GET", "url": "https://api.example.com/second", "headers": []}, "response": {"status": 1000, "headers": [], "content": {}}}
+	]}}`
+	stores := map[string]mockservice.Store{
+		"Endpoints":    mockservice.NewEndpoints(),
+		"Custom_store": newSliceStore(),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			service, err := mockservice.NewWithStore("/mocks", store)
+			if err != nil {
+				t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+			}
+			defer service.Close()
+
+			if recorder := serve(service, http.MethodPost, "/mocks/har", invalidHAR); recorder.Code != http.StatusBadRequest {
+				t.Errorf("Expected %d status for an invalid entry but got %d", http.StatusBadRequest, recorder.Code)
+			}
+			if endpoints := store.List(); len(endpoints) != 0 {
+				t.Errorf("Expected no endpoint to be imported but got %+v", endpoints)
+			}
+		})
+	}
+}
+
+func TestExportHAR(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	service.Endpoints().Create(mockservice.Post("/users").WillReturn(http.StatusCreated).
+		WithHeader("Content-Type", "application/json").WithHeader("Set-Cookie", "session=abc").WithBody(`{"id":1}`).MustBuild())
+	service.Endpoints().Create(mockservice.Get("/logo.png").WillReturn(http.StatusOK).WithBody("\x89PNG").MustBuild())
+
+	req := httptest.NewRequest(http.MethodPost, "https://api.example.com/users?invite=true", strings.NewReader(`{"name":"gopher"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Cookie", "theme=dark")
+	service.ServeHTTP(httptest.NewRecorder(), req)
+	serve(service, http.MethodGet, "/logo.png", "")
+	serve(service, http.MethodGet, "/missing", "")
+
+	requests := service.Journal().Requests()
+	if response := requests[0].Response; response == nil || response.StatusCode != http.StatusCreated || response.Body != `{"id":1}` {
+		t.Fatalf("Expected the response to be recorded with the request but got %+v", response)
+	}
+
+	har, err := mockservice.ExportHAR(requests)
+	if err != nil {
+		t.Fatalf("Expected exporting the HAR log to succeed but got %s", err)
+	}
+	doc := struct {
+		Log struct {
+			Version string `json:"version"`
+			Entries []struct {
+				Request struct {
+					Method      string              `json:"method"`
+					URL         string              `json:"url"`
+					Cookies     []map[string]string `json:"cookies"`
+					QueryString []map[string]string `json:"queryString"`
+					PostData    map[string]string   `json:"postData"`
+				} `json:"request"`
+				Response struct {
+					Status     int                 `json:"status"`
+					StatusText string              `json:"statusText"`
+					Cookies    []map[string]string `json:"cookies"`
+					Content    map[string]interface{}
+				} `json:"response"`
+			} `json:"entries"`
+		} `json:"log"`
+	}{}
+	if err := json.Unmarshal(har, &doc); err != nil {
+		t.Fatalf("Expected a JSON HAR log but got %s", har)
+	}
+	if doc.Log.Version != "1.2" || len(doc.Log.Entries) != 3 {
+		t.Fatalf("Expected a HAR 1.2 log with 3 entries but got %s", har)
+	}
+
+	created := doc.Log.Entries[0]
+	if created.Request.URL != "https://api.example.com/users?invite=true" {
+		t.Errorf("Expected the full request URL but got %s", created.Request.URL)
+	}
+	if !reflect.DeepEqual(created.Request.QueryString, []map[string]string{{"name": "invite", "value": "true"}}) {
+		t.Errorf("Expected the query string to be listed but got %v", created.Request.QueryString)
+	}
+	if !reflect.DeepEqual(created.Request.Cookies, []map[string]string{{"name": "theme", "value": "dark"}}) {
+		t.Errorf("Expected the request cookie to be listed but got %v", created.Request.Cookies)
+	}
+	if created.Request.PostData["mimeType"] != "application/json" || created.Request.PostData["text"] != `{"name":"gopher"}` {
+		t.Errorf("Expected the request body in postData but got %v", created.Request.PostData)
+	}
+	if created.Response.Status != http.StatusCreated || created.Response.StatusText != "Created" || created.Response.Content["text"] != `{"id":1}` {
+		t.Errorf("Expected the sent response but got %+v", created.Response)
+	}
+	if !reflect.DeepEqual(created.Response.Cookies, []map[string]string{{"name": "session", "value": "abc"}}) {
+		t.Errorf("Expected the response cookie to be listed but got %v", created.Response.Cookies)
+	}
+	if logo := doc.Log.Entries[1].Response.Content; logo["encoding"] != "base64" || logo["text"] != "iVBORw==" {
+		t.Errorf("Expected the binary body to be base64 encoded but got %v", logo)
+	}
+	if missing := doc.Log.Entries[2].Response; missing.Status != http.StatusNotFound {
+		t.Errorf("Expected the unmatched request to be answered with %d but got %d", http.StatusNotFound, missing.Status)
+	}
+}
+
+func TestExportEndpointsHAR(t *testing.T) {
+	endpoints := []*mockservice.MockEndpoint{
+		mockservice.Get("/users").WithQueryParameter("page", "2").WithHeader("Authorization", "Bearer abc").
+			WillReturn(http.StatusOK).WithHeader("Content-Type", "application/json").WithBody(`[]`).MustBuild(),
+	}
+	har, err := mockservice.ExportEndpointsHAR(endpoints, "http://localhost:8080/")
+	if err != nil {
+		t.Fatalf("Expected exporting the HAR log to succeed but got %s", err)
+	}
+
+	conf, err := mockservice.ImportHAR(har, &mockservice.HAROptions{RequestHeaders: []string{"Authorization"}})
+	if err != nil {
+		t.Fatalf("Expected the exported HAR log to be imported but got %s", err)
+	}
+	if !reflect.DeepEqual(conf.Endpoints, endpoints) {
+		got, _ := json.Marshal(conf.Endpoints)
+		t.Errorf("Expected the endpoints to survive exporting and importing but got %s", got)
+	}
+}
+
+func TestAdminService_ExportHAR(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	service.Endpoints().Create(mockservice.Get("/health").WillReturn(http.StatusOK).WithBody("ok").MustBuild())
+	serve(service, http.MethodGet, "/health", "")
+
+	recorder := serve(service, http.MethodGet, "/mocks/har", "")
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"url": "http://example.com/health"`) {
+		t.Errorf("Expected the recorded request in the HAR log but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf(`Expected Content-Type "application/json" but got "%s"`, contentType)
+	}
+
+	recorder = serve(service, http.MethodGet, "/mocks/har?source=mocks", "")
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"text": "ok"`) {
+		t.Errorf("Expected the mock endpoint in the HAR log but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := serve(service, http.MethodGet, "/mocks/har?source=other", ""); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected %d status for an unknown source but got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
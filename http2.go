@@ -0,0 +1,37 @@
+package mockservice
+
+import (
+	"log"
+	"net/http"
+	"strings"
+)
+
+// ConfigureHTTP2 makes the server speak HTTP/2 alongside HTTP/1.1, over TLS and, when cleartext is true, also over
+// unencrypted connections (h2c) using prior knowledge
+func ConfigureHTTP2(srv *http.Server, cleartext bool) {
+	protocols := &http.Protocols{}
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(cleartext)
+	srv.Protocols = protocols
+}
+
+// matchProtocol reports whether the request protocol, such as "HTTP/2.0", matches the protocol of the mock endpoint,
+// which may also be given by its major version alone, such as "HTTP/2"
+func matchProtocol(protocol string, req *http.Request) bool {
+	protocol = strings.ToUpper(protocol)
+	return protocol == req.Proto || protocol == strings.SplitN(req.Proto, ".", 2)[0]
+}
+
+// push initiates HTTP/2 server pushes for the paths when the connection supports them
+func push(w http.ResponseWriter, paths []string) {
+	pusher, ok := w.(http.Pusher)
+	if !ok {
+		return
+	}
+	for _, path := range paths {
+		if err := pusher.Push(path, nil); err != nil && err != http.ErrNotSupported {
+			log.Printf("Unable to push %s: %s", path, err)
+		}
+	}
+}
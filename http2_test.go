@@ -0,0 +1,83 @@
+package mockservice_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/wchan2/mock_service"
+)
+
+func TestHTTP2(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	service.Endpoints().Load([]*mockservice.MockEndpoint{
+		mockservice.Get("/feed").WillReturn(http.StatusOK).WithBody("any protocol").MustBuild(),
+		mockservice.Get("/feed").WithProtocol("HTTP/2").WillReturn(http.StatusOK).WithBody("http2").MustBuild(),
+	})
+
+	t.Run("HTTP2_over_TLS", func(t *testing.T) {
+		server := httptest.NewUnstartedServer(service)
+		server.EnableHTTP2 = true
+		server.StartTLS()
+		defer server.Close()
+
+		resp, err := server.Client().Get(server.URL + "/feed")
+		if err != nil {
+			t.Fatalf("Expected the request to succeed but got %s", err)
+		}
+		resp.Body.Close()
+		if resp.ProtoMajor != 2 {
+			t.Errorf("Expected an HTTP/2 response but got %s", resp.Proto)
+		}
+	})
+
+	t.Run("h2c", func(t *testing.T) {
+		server := httptest.NewUnstartedServer(service)
+		mockservice.ConfigureHTTP2(server.Config, true)
+		server.Start()
+		defer server.Close()
+
+		protocols := &http.Protocols{}
+		protocols.SetUnencryptedHTTP2(true)
+		h2c := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+		resp, err := h2c.Get(server.URL + "/feed")
+		if err != nil {
+			t.Fatalf("Expected the request to succeed but got %s", err)
+		}
+		resp.Body.Close()
+		if resp.ProtoMajor != 2 {
+			t.Errorf("Expected an HTTP/2 response but got %s", resp.Proto)
+		}
+
+		resp, err = server.Client().Get(server.URL + "/feed")
+		if err != nil {
+			t.Fatalf("Expected the HTTP/1.1 request to succeed but got %s", err)
+		}
+		resp.Body.Close()
+		if resp.ProtoMajor != 1 {
+			t.Errorf("Expected an HTTP/1.1 response but got %s", resp.Proto)
+		}
+	})
+
+	requests := service.Journal().Requests()
+	protocols := []string{}
+	for _, req := range requests {
+		protocols = append(protocols, req.Protocol)
+	}
+	if len(protocols) != 3 || protocols[0] != "HTTP/2.0" || protocols[1] != "HTTP/2.0" || protocols[2] != "HTTP/1.1" {
+		t.Errorf("Expected the journal to record [HTTP/2.0 HTTP/2.0 HTTP/1.1] but got %v", protocols)
+	}
+
+	for _, c := range []struct{ proto, body string }{{"HTTP/2.0", "http2"}, {"HTTP/1.1", "any protocol"}} {
+		req := httptest.NewRequest(http.MethodGet, "/feed", nil)
+		req.Proto, req.ProtoMajor = c.proto, int(c.proto[5]-'0')
+		recorder := httptest.NewRecorder()
+		service.ServeHTTP(recorder, req)
+		if recorder.Body.String() != c.body {
+			t.Errorf(`Expected "%s" response body for %s but got "%s"`, c.body, c.proto, recorder.Body.String())
+		}
+	}
+}
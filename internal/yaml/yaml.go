@@ -0,0 +1,563 @@
+// Package yaml converts YAML documents to JSON.
+//
+// It supports the subset of YAML 1.2 found in configuration files and API specifications: block mappings and
+// sequences, flow collections, plain, quoted and block scalars, and comments. Anchors, aliases, tags beyond being
+// ignored, complex keys and multiple documents are not supported.
+package yaml
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	intPattern   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	floatPattern = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// ToJSON converts the YAML document to JSON
+func ToJSON(data []byte) ([]byte, error) {
+	val, err := Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(val)
+}
+
+// Unmarshal parses the YAML document into the values encoding/json uses for JSON: map[string]interface{},
+// []interface{}, string, bool, nil and json.Number
+func Unmarshal(data []byte) (interface{}, error) {
+	p := newParser(string(data))
+	p.skipBlank()
+	if p.pos < len(p.lines) && p.lines[p.pos].text == "---" {
+		p.pos++
+	}
+	val, err := p.block(0)
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	if !p.done() && p.lines[p.pos].text != "..." {
+		return nil, p.errorf("unexpected content %q", p.lines[p.pos].text)
+	}
+	return val, nil
+}
+
+// line is a line of the document with its indentation and, outside of block scalars, without comments
+type line struct {
+	raw    string
+	indent int
+	text   string
+	blank  bool
+	tab    bool
+}
+
+type parser struct {
+	lines []line
+	pos   int
+}
+
+func newParser(doc string) *parser {
+	p := &parser{}
+	doc = strings.TrimSuffix(strings.ReplaceAll(doc, "\r\n", "\n"), "\n")
+	for _, raw := range strings.Split(doc, "\n") {
+		indent := len(raw) - len(strings.TrimLeft(raw, " "))
+		text := strings.TrimSpace(stripComment(raw[indent:]))
+		p.lines = append(p.lines, line{
+			raw:    raw,
+			indent: indent,
+			text:   text,
+			blank:  text == "",
+			tab:    strings.HasPrefix(raw[indent:], "\t"),
+		})
+	}
+	return p
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.lines) || p.lines[p.pos].text == "---" || p.lines[p.pos].text == "..."
+}
+
+func (p *parser) skipBlank() {
+	for p.pos < len(p.lines) && p.lines[p.pos].blank {
+		p.pos++
+	}
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("yaml: line %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+// block parses the node starting at the next non-blank line, which must be indented by at least minIndent
+func (p *parser) block(minIndent int) (interface{}, error) {
+	p.skipBlank()
+	if p.done() || p.lines[p.pos].indent < minIndent {
+		return nil, nil
+	}
+	current := p.lines[p.pos]
+	if current.tab {
+		return nil, p.errorf("tabs cannot be used for indentation")
+	}
+	if isSequenceItem(current.text) {
+		return p.sequence(current.indent)
+	}
+	if _, _, ok := splitKey(current.text); ok {
+		return p.mapping(current.indent)
+	}
+	p.pos++
+	return p.inline(current.text, current.indent)
+}
+
+func (p *parser) sequence(indent int) (interface{}, error) {
+	list := []interface{}{}
+	for {
+		p.skipBlank()
+		if p.done() || p.lines[p.pos].indent != indent || !isSequenceItem(p.lines[p.pos].text) {
+			return list, nil
+		}
+		if p.lines[p.pos].tab {
+			return nil, p.errorf("tabs cannot be used for indentation")
+		}
+
+		current := p.lines[p.pos]
+		rest := strings.TrimLeft(current.text[1:], " ")
+		var val interface{}
+		var err error
+		if rest == "" {
+			p.pos++
+			val, err = p.block(indent + 1)
+		} else {
+			// parse the rest of the line as if it started a new line at its own column
+			p.lines[p.pos].indent = indent + len(current.text) - len(rest)
+			p.lines[p.pos].text = rest
+			val, err = p.block(p.lines[p.pos].indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, val)
+	}
+}
+
+func (p *parser) mapping(indent int) (interface{}, error) {
+	obj := map[string]interface{}{}
+	for {
+		p.skipBlank()
+		if p.done() || p.lines[p.pos].indent != indent {
+			return obj, nil
+		}
+		if p.lines[p.pos].tab {
+			return nil, p.errorf("tabs cannot be used for indentation")
+		}
+		key, rest, ok := splitKey(p.lines[p.pos].text)
+		if !ok {
+			return nil, p.errorf("expected a mapping key but got %q", p.lines[p.pos].text)
+		}
+		p.pos++
+
+		var val interface{}
+		var err error
+		switch {
+		case rest == "":
+			p.skipBlank()
+			if !p.done() && (p.lines[p.pos].indent > indent || (p.lines[p.pos].indent == indent && isSequenceItem(p.lines[p.pos].text))) {
+				val, err = p.block(p.lines[p.pos].indent)
+			}
+		case rest[0] == '|' || rest[0] == '>':
+			val, err = p.blockScalar(rest, indent)
+		default:
+			val, err = p.inline(rest, indent+1)
+		}
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = val
+	}
+}
+
+// inline parses a value starting on the current line, continuing onto following lines indented by at least minIndent
+// for multi-line flow collections, quoted scalars and plain scalars
+func (p *parser) inline(text string, minIndent int) (interface{}, error) {
+	text = stripTag(text)
+	if text == "" {
+		return nil, nil
+	}
+	switch text[0] {
+	case '&', '*':
+		return nil, p.errorf("anchors and aliases are not supported")
+	case '[', '{', '"', '\'':
+		for !complete(text) {
+			p.skipBlank()
+			if p.done() {
+				return nil, p.errorf("unterminated %c", text[0])
+			}
+			if text[0] == '"' || text[0] == '\'' {
+				text += " " + strings.TrimSpace(p.lines[p.pos].raw)
+			} else {
+				text += " " + p.lines[p.pos].text
+			}
+			p.pos++
+		}
+		s := &flow{text: text}
+		val, err := s.value()
+		if err != nil {
+			return nil, p.errorf("%s", err)
+		}
+		if s.skipSpaces(); s.pos < len(s.text) {
+			return nil, p.errorf("unexpected %q after value", s.text[s.pos:])
+		}
+		return val, nil
+	}
+
+	for {
+		p.skipBlank()
+		if p.done() || p.lines[p.pos].indent < minIndent || isSequenceItem(p.lines[p.pos].text) {
+			break
+		}
+		if _, _, ok := splitKey(p.lines[p.pos].text); ok {
+			break
+		}
+		text += " " + p.lines[p.pos].text
+		p.pos++
+	}
+	return resolve(text), nil
+}
+
+// blockScalar parses a literal (|) or folded (>) block scalar whose header is given, belonging to a key at indent
+func (p *parser) blockScalar(header string, indent int) (interface{}, error) {
+	folded, chomp, contentIndent := header[0] == '>', byte(0), 0
+	for _, c := range header[1:] {
+		switch {
+		case c == '-' || c == '+':
+			chomp = byte(c)
+		case c >= '1' && c <= '9':
+			contentIndent = indent + int(c-'0')
+		default:
+			return nil, p.errorf("invalid block scalar header %q", header)
+		}
+	}
+
+	lines := []string{}
+	for p.pos < len(p.lines) {
+		raw := p.lines[p.pos].raw
+		lineIndent := len(raw) - len(strings.TrimLeft(raw, " "))
+		if strings.TrimSpace(raw) == "" {
+			lines = append(lines, "")
+			p.pos++
+			continue
+		}
+		if contentIndent == 0 {
+			if lineIndent <= indent {
+				break
+			}
+			contentIndent = lineIndent
+		}
+		if lineIndent < contentIndent {
+			break
+		}
+		lines = append(lines, raw[contentIndent:])
+		p.pos++
+	}
+
+	trailing := 0
+	for trailing < len(lines) && lines[len(lines)-1-trailing] == "" {
+		trailing++
+	}
+	lines = lines[:len(lines)-trailing]
+
+	var b strings.Builder
+	for i, l := range lines {
+		if i > 0 {
+			previous := lines[i-1]
+			if folded && previous != "" && l != "" && previous[0] != ' ' && l[0] != ' ' {
+				b.WriteByte(' ')
+			} else if !folded || previous != "" {
+				b.WriteByte('\n')
+			} else if l == "" {
+				b.WriteByte('\n')
+			}
+		}
+		b.WriteString(l)
+	}
+	switch {
+	case len(lines) == 0:
+	case chomp == '+':
+		b.WriteString(strings.Repeat("\n", trailing+1))
+	case chomp != '-':
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+// flow parses flow collections and quoted scalars
+type flow struct {
+	text string
+	pos  int
+}
+
+func (f *flow) skipSpaces() {
+	for f.pos < len(f.text) && (f.text[f.pos] == ' ' || f.text[f.pos] == '\t') {
+		f.pos++
+	}
+}
+
+func (f *flow) value() (interface{}, error) {
+	f.skipSpaces()
+	if f.pos >= len(f.text) {
+		return nil, fmt.Errorf("unexpected end of flow value")
+	}
+	switch f.text[f.pos] {
+	case '[':
+		return f.sequence()
+	case '{':
+		return f.mapping()
+	case '"', '\'':
+		return f.quoted()
+	}
+	start := f.pos
+	for f.pos < len(f.text) && !strings.ContainsRune(",]}", rune(f.text[f.pos])) &&
+		!(f.text[f.pos] == ':' && (f.pos+1 == len(f.text) || strings.ContainsRune(" ,]}", rune(f.text[f.pos+1])))) {
+		f.pos++
+	}
+	return resolve(stripTag(strings.TrimSpace(f.text[start:f.pos]))), nil
+}
+
+func (f *flow) sequence() (interface{}, error) {
+	f.pos++
+	list := []interface{}{}
+	for {
+		f.skipSpaces()
+		if f.pos < len(f.text) && f.text[f.pos] == ']' {
+			f.pos++
+			return list, nil
+		}
+		val, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, val)
+		if err := f.separator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (f *flow) mapping() (interface{}, error) {
+	f.pos++
+	obj := map[string]interface{}{}
+	for {
+		f.skipSpaces()
+		if f.pos < len(f.text) && f.text[f.pos] == '}' {
+			f.pos++
+			return obj, nil
+		}
+		key, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		var val interface{}
+		if f.skipSpaces(); f.pos < len(f.text) && f.text[f.pos] == ':' {
+			f.pos++
+			if val, err = f.value(); err != nil {
+				return nil, err
+			}
+		}
+		obj[keyString(key)] = val
+		if err := f.separator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// separator consumes the comma between flow entries, leaving the closing bracket to be consumed by the caller
+func (f *flow) separator(closing byte) error {
+	f.skipSpaces()
+	if f.pos >= len(f.text) {
+		return fmt.Errorf("unterminated flow collection")
+	}
+	switch f.text[f.pos] {
+	case ',':
+		f.pos++
+		return nil
+	case closing:
+		return nil
+	}
+	return fmt.Errorf("unexpected %q in flow collection", f.text[f.pos])
+}
+
+func (f *flow) quoted() (interface{}, error) {
+	quote := f.text[f.pos]
+	f.pos++
+	var b strings.Builder
+	for f.pos < len(f.text) {
+		c := f.text[f.pos]
+		switch {
+		case c == quote && quote == '\'' && f.pos+1 < len(f.text) && f.text[f.pos+1] == '\'':
+			b.WriteByte('\'')
+			f.pos += 2
+		case c == quote:
+			f.pos++
+			return b.String(), nil
+		case c == '\\' && quote == '"':
+			if f.pos+1 >= len(f.text) {
+				return nil, fmt.Errorf("unterminated escape sequence")
+			}
+			escaped, size, err := unescape(f.text[f.pos+1:])
+			if err != nil {
+				return nil, err
+			}
+			b.WriteString(escaped)
+			f.pos += 1 + size
+		default:
+			b.WriteByte(c)
+			f.pos++
+		}
+	}
+	return nil, fmt.Errorf("unterminated quoted scalar")
+}
+
+// unescape decodes the escape sequence following a backslash, returning the decoded text and the length of the sequence
+func unescape(s string) (string, int, error) {
+	switch s[0] {
+	case 'n':
+		return "\n", 1, nil
+	case 't', '\t':
+		return "\t", 1, nil
+	case 'r':
+		return "\r", 1, nil
+	case '0':
+		return "\x00", 1, nil
+	case 'b':
+		return "\b", 1, nil
+	case 'e':
+		return "\x1b", 1, nil
+	case ' ', '"', '/', '\\':
+		return string(s[0]), 1, nil
+	case 'x', 'u', 'U':
+		size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[s[0]]
+		if len(s) < size+1 {
+			return "", 0, fmt.Errorf("invalid escape sequence \\%s", s)
+		}
+		code, err := strconv.ParseUint(s[1:size+1], 16, 32)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid escape sequence \\%s", s[:size+1])
+		}
+		return string(rune(code)), size + 1, nil
+	}
+	return "", 0, fmt.Errorf("invalid escape sequence \\%c", s[0])
+}
+
+// complete reports whether the flow collection or quoted scalar at the start of the text is terminated
+func complete(text string) bool {
+	f := &flow{text: text}
+	var err error
+	if text[0] == '"' || text[0] == '\'' {
+		_, err = f.quoted()
+	} else {
+		_, err = f.value()
+	}
+	return err == nil || !strings.HasPrefix(err.Error(), "unterminated") && !strings.HasPrefix(err.Error(), "unexpected end")
+}
+
+// resolve converts a plain scalar to null, a boolean, a number or a string
+func resolve(s string) interface{} {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if intPattern.MatchString(s) {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return json.Number(strconv.FormatInt(n, 10))
+		}
+	}
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0o") {
+		if n, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return json.Number(strconv.FormatInt(n, 10))
+		}
+	}
+	if floatPattern.MatchString(s) {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+		}
+	}
+	return s
+}
+
+// splitKey splits a "key: value" line into its key and the rest of the line
+func splitKey(text string) (string, string, bool) {
+	if text == "" || text[0] == '[' || text[0] == '{' || isSequenceItem(text) {
+		return "", "", false
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		f := &flow{text: text}
+		key, err := f.quoted()
+		if err != nil {
+			return "", "", false
+		}
+		rest := strings.TrimLeft(text[f.pos:], " ")
+		if !strings.HasPrefix(rest, ":") || (len(rest) > 1 && rest[1] != ' ') {
+			return "", "", false
+		}
+		return key.(string), strings.TrimSpace(rest[1:]), true
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return keyString(resolve(strings.TrimSpace(text[:i]))), strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// keyString converts a resolved key to the string used as a JSON object key
+func keyString(key interface{}) string {
+	switch k := key.(type) {
+	case nil:
+		return "null"
+	case string:
+		return k
+	}
+	return fmt.Sprint(key)
+}
+
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// stripTag removes a leading tag such as !!str from a value
+func stripTag(text string) string {
+	if !strings.HasPrefix(text, "!") {
+		return text
+	}
+	if i := strings.IndexByte(text, ' '); i >= 0 {
+		return strings.TrimSpace(text[i:])
+	}
+	return ""
+}
+
+// stripComment removes a comment, which starts with a # at the start of the line or after whitespace outside of quotes
+func stripComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.IndexByte(" [{,:", text[i-1]) >= 0):
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return text[:i]
+		}
+	}
+	return text
+}
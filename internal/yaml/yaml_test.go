@@ -0,0 +1,58 @@
+package yaml_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/wchan2/mock_service/internal/yaml"
+)
+
+func TestToJSON(t *testing.T) {
+	cases := []struct {
+		name     string
+		yaml     string
+		expected string
+	}{
+		{"Scalars", "a: 1\nb: 1.5\nc: true\nd: ~\ne: hello world\nf: '3.0.0'\ng: 0x1F\nh: 3.0.0", `{"a":1,"b":1.5,"c":true,"d":null,"e":"hello world","f":"3.0.0","g":31,"h":"3.0.0"}`},
+		{"Nested_Mappings", "paths:\n  /pets/{id}:\n    get:\n      operationId: getPet # a comment\n", `{"paths":{"/pets/{id}":{"get":{"operationId":"getPet"}}}}`},
+		{"Sequences", "tags:\n  - a\n  - b: 1\n    c: 2\n  -\n    - nested\nsame:\n- x\n- y", `{"same":["x","y"],"tags":["a",{"b":1,"c":2},["nested"]]}`},
+		{"Flow_Collections", "a: [1, two, {three: 3, \"four\": [4]}]\nb: {}\nc: [\n  1,\n  2\n]", `{"a":[1,"two",{"four":[4],"three":3}],"b":{},"c":[1,2]}`},
+		{"Quoted", "a: \"tab\\there \\u00e9 # not a comment\"\nb: 'it''s'\n'200': ok\nc: it's plain", `{"200":"ok","a":"tab\there é # not a comment","b":"it's","c":"it's plain"}`},
+		{"Literal_Block", "a: |\n  line 1\n    indented\n  # not a comment\n\nb: end", `{"a":"line 1\n  indented\n# not a comment\n","b":"end"}`},
+		{"Folded_Block", "a: >-\n  one\n  two\n\n  three\nb: |+\n  kept\n\n", `{"a":"one two\nthree","b":"kept\n\n"}`},
+		{"Multiline_Plain", "a: one\n  two\nb: three", `{"a":"one two","b":"three"}`},
+		{"Document_Markers", "---\n- 1\n...\n", `[1]`},
+		{"Scalar_Document", "just text", `"just text"`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := yaml.ToJSON([]byte(c.yaml))
+			if err != nil {
+				t.Fatalf("Expected no error but got %s", err)
+			}
+			var expectedVal, actualVal interface{}
+			json.Unmarshal([]byte(c.expected), &expectedVal)
+			json.Unmarshal(actual, &actualVal)
+			if !reflect.DeepEqual(expectedVal, actualVal) {
+				t.Errorf("Expected %s but got %s", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestToJSONErrors(t *testing.T) {
+	for name, doc := range map[string]string{
+		"Alias":             "a: &anchor 1\nb: *anchor",
+		"Unterminated_Flow": "a: [1, 2",
+		"Tab_Indentation":   "a:\n\tb: 1",
+		"Bad_Indentation":   "a:\n    b: 1\n  c: 2",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := yaml.ToJSON([]byte(doc)); err == nil {
+				t.Errorf("Expected an error converting %q", doc)
+			}
+		})
+	}
+}
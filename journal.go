@@ -0,0 +1,239 @@
+package mockservice
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RecordedRequest is a request received by the mock endpoints
+type RecordedRequest struct {
+	Time     time.Time   `json:"time" xml:"time"`
+	Method   string      `json:"method" xml:"method"`
+	Protocol string      `json:"protocol" xml:"protocol"`
+	Scheme   string      `json:"scheme,omitempty" xml:"scheme,omitempty"`
+	Host     string      `json:"host,omitempty" xml:"host,omitempty"`
+	Endpoint string      `json:"endpoint" xml:"endpoint"`
+	Query    string      `json:"query,omitempty" xml:"query,omitempty"`
+	Headers  http.Header `json:"headers" xml:"-"`
+	Body     string      `json:"body,omitempty" xml:"body,omitempty"`
+	Matched  bool        `json:"matched" xml:"matched"`
+	// ClientCertificate is the TLS client certificate presented with the request, if any
+	ClientCertificate *RecordedCertificate `json:"clientCertificate,omitempty" xml:"clientCertificate,omitempty"`
+	// Response is the response sent to the request, recorded once the mock service is done responding
+	Response *RecordedResponse `json:"response,omitempty" xml:"response,omitempty"`
+}
+
+// Verification describes the requests expected to have been received by the mock endpoints
+type Verification struct {
+	Method   string `json:"method" xml:"method"`
+	Endpoint string `json:"endpoint" xml:"endpoint"`
+	// Count is the exact number of expected requests; a nil Count expects at least one request
+	Count *int `json:"count,omitempty" xml:"count,omitempty"`
+}
+
+// VerificationResult is the response of the verify administration route
+type VerificationResult struct {
+	Count int    `json:"count" xml:"count"`
+	Error string `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+// VerificationError is returned when the received requests do not satisfy a Verification
+type VerificationError struct {
+	Verification Verification
+	Actual       int
+}
+
+func (e *VerificationError) Error() string {
+	expected := "at least once"
+	if e.Verification.Count != nil {
+		expected = fmt.Sprintf("%d times", *e.Verification.Count)
+	}
+	return fmt.Sprintf(
+		"Expected %s %s to be requested %s but it was requested %d times",
+		e.Verification.Method, e.Verification.Endpoint, expected, e.Actual,
+	)
+}
+
+// DefaultJournalLimit is the number of requests and WebSocket messages kept by a journal, see Journal.Limit
+const DefaultJournalLimit = 1000
+
+// Journal records the requests received by the mock endpoints
+type Journal struct {
+	requests []*RecordedRequest
+	frames   []*RecordedFrame
+	limit    int
+	// dropped is the number of requests dropped beyond the limit since the journal was last reset
+	dropped int
+	// droppedRoutes counts the dropped requests by HTTP method and URL path, so that Count and Verify still include them
+	droppedRoutes map[journalRoute]int
+	// droppedUnmatched is the number of dropped requests that did not match any mock endpoint
+	droppedUnmatched int
+	sync.Mutex
+}
+
+// journalRoute is the HTTP method and URL path of a recorded request
+type journalRoute struct {
+	method   string
+	endpoint string
+}
+
+// NewJournal creates an empty journal keeping the last DefaultJournalLimit requests and WebSocket messages
+func NewJournal() *Journal {
+	return &Journal{
+		requests:      []*RecordedRequest{},
+		frames:        []*RecordedFrame{},
+		limit:         DefaultJournalLimit,
+		droppedRoutes: map[journalRoute]int{},
+	}
+}
+
+// Limit sets the number of requests and WebSocket messages kept by the journal, which drops the oldest ones first.
+// Zero keeps them all. Count, Verify and UnmatchedCount still include the dropped requests.
+func (j *Journal) Limit(max int) {
+	j.Lock()
+	j.limit = max
+	j.trim()
+	j.Unlock()
+}
+
+// trim drops the oldest requests and WebSocket messages beyond the limit. It must be called with the lock held.
+func (j *Journal) trim() {
+	if j.limit <= 0 {
+		return
+	}
+	for len(j.requests) > j.limit {
+		req := j.requests[0]
+		j.droppedRoutes[journalRoute{method: req.Method, endpoint: req.Endpoint}]++
+		if !req.Matched {
+			j.droppedUnmatched++
+		}
+		j.requests[0] = nil
+		j.requests = j.requests[1:]
+		j.dropped++
+	}
+	for len(j.frames) > j.limit {
+		j.frames[0] = nil
+		j.frames = j.frames[1:]
+	}
+}
+
+// Record adds a received request to the journal
+func (j *Journal) Record(req *RecordedRequest) {
+	j.Lock()
+	j.requests = append(j.requests, req)
+	j.trim()
+	j.Unlock()
+}
+
+// respond records the response sent to a recorded request
+func (j *Journal) respond(req *RecordedRequest, res *RecordedResponse) {
+	j.Lock()
+	req.Response = res
+	j.Unlock()
+}
+
+// Requests lists copies of the recorded requests in the order they were received
+func (j *Journal) Requests() []*RecordedRequest {
+	return j.RequestsSince(0)
+}
+
+// RequestsSince lists copies of the recorded requests after skipping the first offset requests received since the
+// journal was last reset, counting the requests it dropped beyond its limit
+func (j *Journal) RequestsSince(offset int) []*RecordedRequest {
+	j.Lock()
+	defer j.Unlock()
+	start := offset - j.dropped
+	if start < 0 {
+		start = 0
+	}
+	if start > len(j.requests) {
+		start = len(j.requests)
+	}
+	requests := make([]*RecordedRequest, len(j.requests)-start)
+	for i, req := range j.requests[start:] {
+		copied := *req
+		requests[i] = &copied
+	}
+	return requests
+}
+
+// RecordFrame adds a WebSocket message received from a client to the journal
+func (j *Journal) RecordFrame(frame *RecordedFrame) {
+	j.Lock()
+	j.frames = append(j.frames, frame)
+	j.trim()
+	j.Unlock()
+}
+
+// Frames lists the recorded WebSocket messages in the order they were received
+func (j *Journal) Frames() []*RecordedFrame {
+	j.Lock()
+	defer j.Unlock()
+	return append([]*RecordedFrame{}, j.frames...)
+}
+
+// Reset removes all the recorded requests and WebSocket messages
+func (j *Journal) Reset() {
+	j.Lock()
+	j.requests = []*RecordedRequest{}
+	j.frames = []*RecordedFrame{}
+	j.dropped = 0
+	j.droppedRoutes = map[journalRoute]int{}
+	j.droppedUnmatched = 0
+	j.Unlock()
+}
+
+// Unmatched lists the kept recorded requests that did not match any mock endpoint
+func (j *Journal) Unmatched() []*RecordedRequest {
+	unmatched := []*RecordedRequest{}
+	for _, req := range j.Requests() {
+		if !req.Matched {
+			unmatched = append(unmatched, req)
+		}
+	}
+	return unmatched
+}
+
+// UnmatchedCount returns the number of received requests that did not match any mock endpoint, including those the
+// journal dropped beyond its limit
+func (j *Journal) UnmatchedCount() int {
+	j.Lock()
+	defer j.Unlock()
+	count := j.droppedUnmatched
+	for _, req := range j.requests {
+		if !req.Matched {
+			count++
+		}
+	}
+	return count
+}
+
+// Count returns the number of received requests for the HTTP method and URL path or path template, including those
+// the journal dropped beyond its limit
+func (j *Journal) Count(method, endpoint string) int {
+	j.Lock()
+	defer j.Unlock()
+	count := 0
+	for route, dropped := range j.droppedRoutes {
+		if route.method == method && matchPath(endpoint, route.endpoint) {
+			count += dropped
+		}
+	}
+	for _, req := range j.requests {
+		if req.Method == method && matchPath(endpoint, req.Endpoint) {
+			count++
+		}
+	}
+	return count
+}
+
+// Verify returns the number of matching recorded requests, or a VerificationError if the verification is not satisfied
+func (j *Journal) Verify(v Verification) (int, error) {
+	count := j.Count(v.Method, v.Endpoint)
+	if (v.Count == nil && count == 0) || (v.Count != nil && *v.Count != count) {
+		return count, &VerificationError{Verification: v, Actual: count}
+	}
+	return count, nil
+}
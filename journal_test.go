@@ -0,0 +1,107 @@
+package mockservice_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/wchan2/mock_service"
+)
+
+func TestJournal_Verify(t *testing.T) {
+	journal := mockservice.NewJournal()
+	journal.Record(&mockservice.RecordedRequest{Method: http.MethodGet, Endpoint: "/test"})
+	journal.Record(&mockservice.RecordedRequest{Method: http.MethodGet, Endpoint: "/test"})
+	journal.Record(&mockservice.RecordedRequest{Method: http.MethodPost, Endpoint: "/test"})
+
+	t.Run("At_least_once", func(t *testing.T) {
+		count, err := journal.Verify(mockservice.Verification{Method: http.MethodGet, Endpoint: "/test"})
+		if err != nil {
+			t.Errorf("Expected verification error to be nil but got %s", err)
+		}
+
+		if count != 2 {
+			t.Errorf("Expected a count of 2 but got %d", count)
+		}
+	})
+
+	t.Run("Exact_count", func(t *testing.T) {
+		expected := 1
+		if _, err := journal.Verify(mockservice.Verification{Method: http.MethodPost, Endpoint: "/test", Count: &expected}); err != nil {
+			t.Errorf("Expected verification error to be nil but got %s", err)
+		}
+	})
+
+	t.Run("Never_requested", func(t *testing.T) {
+		_, err := journal.Verify(mockservice.Verification{Method: http.MethodDelete, Endpoint: "/test"})
+		verificationErr, ok := err.(*mockservice.VerificationError)
+		if !ok {
+			t.Fatalf("Expected a verification error but got %v", err)
+		}
+
+		if verificationErr.Actual != 0 {
+			t.Errorf("Expected the actual count to be 0 but got %d", verificationErr.Actual)
+		}
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		journal.Reset()
+		if requests := journal.Requests(); len(requests) != 0 {
+			t.Errorf("Expected no recorded requests after a reset but got %d", len(requests))
+		}
+	})
+}
+
+func TestJournal_Limit(t *testing.T) {
+	journal := mockservice.NewJournal()
+	journal.Limit(2)
+	for _, endpoint := range []string{"/first", "/second", "/third"} {
+		journal.Record(&mockservice.RecordedRequest{Method: http.MethodGet, Endpoint: endpoint})
+		journal.RecordFrame(&mockservice.RecordedFrame{Endpoint: endpoint})
+	}
+
+	if requests := journal.Requests(); len(requests) != 2 || requests[0].Endpoint != "/second" || requests[1].Endpoint != "/third" {
+		t.Errorf("Expected the oldest request to be dropped but got %+v", requests)
+	}
+	if frames := journal.Frames(); len(frames) != 2 || frames[0].Endpoint != "/second" {
+		t.Errorf("Expected the oldest WebSocket message to be dropped but got %+v", frames)
+	}
+	if requests := journal.RequestsSince(2); len(requests) != 1 || requests[0].Endpoint != "/third" {
+		t.Errorf("Expected the offset to count the dropped request but got %+v", requests)
+	}
+	if requests := journal.RequestsSince(3); len(requests) != 0 {
+		t.Errorf("Expected no request after the last one but got %+v", requests)
+	}
+
+	journal.Reset()
+	journal.Record(&mockservice.RecordedRequest{Method: http.MethodGet, Endpoint: "/fourth"})
+	if requests := journal.RequestsSince(0); len(requests) != 1 || requests[0].Endpoint != "/fourth" {
+		t.Errorf("Expected the offset to restart after a reset but got %+v", requests)
+	}
+}
+
+func TestJournal_CountBeyondLimit(t *testing.T) {
+	journal := mockservice.NewJournal()
+	for i := 0; i < mockservice.DefaultJournalLimit+500; i++ {
+		journal.Record(&mockservice.RecordedRequest{Method: http.MethodGet, Endpoint: "/a", Matched: i%2 == 0})
+	}
+	journal.Record(&mockservice.RecordedRequest{Method: http.MethodGet, Endpoint: "/users/1", Matched: true})
+
+	if requests := journal.Requests(); len(requests) != mockservice.DefaultJournalLimit {
+		t.Errorf("Expected %d requests to be kept but got %d", mockservice.DefaultJournalLimit, len(requests))
+	}
+	expected := mockservice.DefaultJournalLimit + 500
+	if count, err := journal.Verify(mockservice.Verification{Method: http.MethodGet, Endpoint: "/a", Count: &expected}); err != nil || count != expected {
+		t.Errorf("Expected %d requests to be verified including the dropped ones but got %d: %v", expected, count, err)
+	}
+	if count := journal.Count(http.MethodGet, "/users/{id}"); count != 1 {
+		t.Errorf("Expected the path template to count 1 request but got %d", count)
+	}
+	if count := journal.UnmatchedCount(); count != expected/2 {
+		t.Errorf("Expected %d unmatched requests including the dropped ones but got %d", expected/2, count)
+	}
+
+	journal.Reset()
+	if count := journal.Count(http.MethodGet, "/a"); count != 0 || journal.UnmatchedCount() != 0 {
+		t.Errorf("Expected no request to be counted after a reset but got %d", count)
+	}
+}
@@ -0,0 +1,149 @@
+package mockservice
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// Matches reports whether the request and its body satisfy the endpoint's path and request matchers, ignoring its
+// scenario, priority and limits
+func (m *MockEndpoint) Matches(req *http.Request, body []byte) bool {
+	if !matchPath(m.Endpoint, req.URL.Path) {
+		return false
+	}
+
+	for key, val := range m.RequestHeaders {
+		if req.Header.Get(key) != val {
+			return false
+		}
+	}
+
+	if len(m.QueryParameters) > 0 {
+		query := req.URL.Query()
+		for key, val := range m.QueryParameters {
+			if !containsString(query[key], val) {
+				return false
+			}
+		}
+	}
+
+	if m.Protocol != "" && !matchProtocol(m.Protocol, req) {
+		return false
+	}
+
+	if m.ClientCertificate != nil && !m.ClientCertificate.matches(req) {
+		return false
+	}
+
+	if m.GraphQL != nil && !m.GraphQL.matches(req, body) {
+		return false
+	}
+
+	if m.SOAP != nil && !m.SOAP.matches(req, body) {
+		return false
+	}
+
+	if !matchBody(m.BodyMatchers, body) {
+		return false
+	}
+
+	return m.RequestBody == "" || m.RequestBody == string(body)
+}
+
+// SameRoute reports whether both endpoints would match the same requests, so that one replaces the other
+func (m *MockEndpoint) SameRoute(other *MockEndpoint) bool {
+	return m.Method == other.Method &&
+		m.Endpoint == other.Endpoint &&
+		reflect.DeepEqual(canonicalHeaders(m.RequestHeaders), canonicalHeaders(other.RequestHeaders)) &&
+		(len(m.QueryParameters) == 0 && len(other.QueryParameters) == 0 || reflect.DeepEqual(m.QueryParameters, other.QueryParameters)) &&
+		m.RequestBody == other.RequestBody &&
+		strings.EqualFold(m.Protocol, other.Protocol) &&
+		reflect.DeepEqual(m.BodyMatchers, other.BodyMatchers) &&
+		reflect.DeepEqual(m.ClientCertificate, other.ClientCertificate) &&
+		reflect.DeepEqual(m.GraphQL, other.GraphQL) &&
+		reflect.DeepEqual(m.SOAP, other.SOAP) &&
+		m.Scenario == other.Scenario &&
+		m.RequiredState == other.RequiredState &&
+		m.Priority == other.Priority
+}
+
+// hasTags reports whether the endpoint has all the tags
+func (m *MockEndpoint) hasTags(tags []string) bool {
+	for _, tag := range tags {
+		if !containsString(m.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func canonicalHeaders(headers map[string]string) map[string]string {
+	canonical := map[string]string{}
+	for key, val := range headers {
+		canonical[http.CanonicalHeaderKey(key)] = val
+	}
+	return canonical
+}
+
+// matchPath reports whether the URL path matches the path template, where a {parameter} matches any non-empty text
+// within a single segment, either on its own such as /users/{id} or surrounded by literal text such as /files/{name}.json
+func matchPath(template, path string) bool {
+	if !isPathTemplate(template) {
+		return template == path
+	}
+
+	templateSegments, pathSegments := strings.Split(template, "/"), strings.Split(path, "/")
+	if len(templateSegments) != len(pathSegments) {
+		return false
+	}
+	for i, segment := range templateSegments {
+		if prefix, _, suffix, ok := segmentParameter(segment); ok {
+			val := pathSegments[i]
+			if len(val) <= len(prefix)+len(suffix) || !strings.HasPrefix(val, prefix) || !strings.HasSuffix(val, suffix) {
+				return false
+			}
+		} else if segment != pathSegments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// PathParameters extracts the values of the {parameter}s of the path template from the URL path
+func PathParameters(template, path string) map[string]string {
+	params := map[string]string{}
+	if !matchPath(template, path) {
+		return params
+	}
+	pathSegments := strings.Split(path, "/")
+	for i, segment := range strings.Split(template, "/") {
+		if prefix, name, suffix, ok := segmentParameter(segment); ok {
+			params[name] = pathSegments[i][len(prefix) : len(pathSegments[i])-len(suffix)]
+		}
+	}
+	return params
+}
+
+func isPathTemplate(path string) bool {
+	return strings.Contains(path, "{")
+}
+
+// segmentParameter splits a path template segment with a single {parameter} into its literal prefix, parameter name and literal suffix
+func segmentParameter(segment string) (prefix, name, suffix string, ok bool) {
+	open, close := strings.IndexByte(segment, '{'), strings.IndexByte(segment, '}')
+	if open < 0 || close < open+2 || strings.Count(segment, "{") != 1 || strings.Count(segment, "}") != 1 {
+		return "", "", "", false
+	}
+	return segment[:open], segment[open+1 : close], segment[close+1:], true
+}
+
+// validPathTemplate reports whether every brace in the path belongs to a single {parameter} within its segment
+func validPathTemplate(path string) bool {
+	for _, segment := range strings.Split(path, "/") {
+		if _, _, _, ok := segmentParameter(segment); strings.ContainsAny(segment, "{}") && !ok {
+			return false
+		}
+	}
+	return true
+}
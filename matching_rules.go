@@ -0,0 +1,311 @@
+package mockservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MatchingRules match the JSON values at a path of a body by their matchers instead of comparing them to the expected
+// value, as in Pact contracts
+type MatchingRules struct {
+	// Combine is "AND", the default, when all the matchers must match, or "OR" when any one of them must match
+	Combine string `json:"combine,omitempty" xml:"combine,omitempty"`
+	// Matchers are the matchers applied to the values
+	Matchers []MatchingRule `json:"matchers" xml:"matchers"`
+}
+
+// MatchingRule is a matcher of a JSON value
+type MatchingRule struct {
+	// Match is the kind of matcher: "type" matches values of the same type as the expected value, which also applies to
+	// the values nested in it, "equality" matches equal values again, and "regex", "include", "integer", "decimal",
+	// "number", "boolean", "null", "date", "time" and "timestamp" match values of that form
+	Match string `json:"match" xml:"match"`
+	// Regex is the regular expression that the whole value must match for "regex"
+	Regex string `json:"regex,omitempty" xml:"regex,omitempty"`
+	// Value is the text that the value must contain for "include"
+	Value string `json:"value,omitempty" xml:"value,omitempty"`
+	// Min and Max bound the number of items of arrays matched by "type"
+	Min *int `json:"min,omitempty" xml:"min,omitempty"`
+	Max *int `json:"max,omitempty" xml:"max,omitempty"`
+	// Format is the Java date format of "date", "time" and "timestamp", such as "yyyy-MM-dd"; ISO 8601 when empty
+	Format string `json:"format,omitempty" xml:"format,omitempty"`
+}
+
+// dateFormatTokens converts Java date format tokens to Go time layouts, longest tokens first
+var dateFormatTokens = strings.NewReplacer(
+	"yyyy", "2006", "yy", "06", "MMMM", "January", "MMM", "Jan", "MM", "01", "dd", "02", "EEEE", "Monday", "EEE", "Mon",
+	"HH", "15", "hh", "03", "mm", "04", "ss", "05", "SSS", "000", "XXX", "Z07:00", "XX", "Z0700", "Z", "-0700", "a", "PM", "'T'", "T",
+)
+
+// defaultDateLayouts are the ISO 8601 layouts of the date, time and timestamp matchers without a format
+var defaultDateLayouts = map[string][]string{
+	"date":      {"2006-01-02"},
+	"time":      {"15:04:05", "15:04:05.999999999", "15:04:05Z07:00"},
+	"timestamp": {time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04:05.999999999"},
+}
+
+// validate checks the kinds and regular expressions of the matchers
+func (r *MatchingRules) validate(path string) error {
+	if r == nil {
+		return fmt.Errorf("Missing matching rules for %s", path)
+	}
+	if _, err := parseRulePath(path); err != nil {
+		return err
+	}
+	if r.Combine != "" && r.Combine != "AND" && r.Combine != "OR" {
+		return fmt.Errorf("Invalid matching rule combination %q for %s", r.Combine, path)
+	}
+	for _, rule := range r.Matchers {
+		switch rule.Match {
+		case "type", "equality", "include", "integer", "decimal", "number", "boolean", "null", "date", "time", "timestamp":
+		case "regex":
+			if _, err := regexp.Compile(rule.Regex); err != nil {
+				return fmt.Errorf("Invalid matching rule regular expression %q for %s: %s", rule.Regex, path, err)
+			}
+		default:
+			return fmt.Errorf("Unsupported matching rule %q for %s", rule.Match, path)
+		}
+	}
+	return nil
+}
+
+// parseRulePath splits a JSON path such as $.items[*].id or $['a key'] into its tokens, starting with $
+func parseRulePath(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("Invalid matching rule path %q", path)
+	}
+	tokens := []string{"$"}
+	for rest := path[1:]; rest != ""; {
+		switch {
+		case strings.HasPrefix(rest, "['"):
+			end := strings.Index(rest, "']")
+			if end < 0 {
+				return nil, fmt.Errorf("Invalid matching rule path %q", path)
+			}
+			tokens = append(tokens, rest[2:end])
+			rest = rest[end+2:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("Invalid matching rule path %q", path)
+			}
+			tokens = append(tokens, rest[1:end])
+			rest = rest[end+1:]
+		case strings.HasPrefix(rest, "."):
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			tokens = append(tokens, rest[1:end+1])
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("Invalid matching rule path %q", path)
+		}
+	}
+	return tokens, nil
+}
+
+// ruleMatcher compares JSON values to expected values under matching rules
+type ruleMatcher struct {
+	paths []rulePath
+}
+
+// rulePath is the parsed path of matching rules
+type rulePath struct {
+	tokens []string
+	rules  *MatchingRules
+}
+
+// matchJSONRules reports whether the body is the expected JSON value, where the values at the paths of the rules
+// are matched by the rules instead
+func matchJSONRules(expected json.RawMessage, body []byte, rules map[string]*MatchingRules) bool {
+	var expectedVal, actualVal interface{}
+	if decodeJSON(expected, &expectedVal) != nil || decodeJSON(body, &actualVal) != nil {
+		return false
+	}
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	matcher := &ruleMatcher{}
+	for _, name := range names {
+		tokens, err := parseRulePath(name)
+		if err != nil {
+			return false
+		}
+		matcher.paths = append(matcher.paths, rulePath{tokens: tokens, rules: rules[name]})
+	}
+	return matcher.match(expectedVal, actualVal, []string{"$"}, false)
+}
+
+// rulesAt returns the rules of the most specific path matching the path, where * matches any key or index
+func (m *ruleMatcher) rulesAt(path []string) *MatchingRules {
+	var best *MatchingRules
+	bestWeight := 0
+	for _, candidate := range m.paths {
+		if len(candidate.tokens) != len(path) {
+			continue
+		}
+		weight := 1
+		for i, token := range candidate.tokens {
+			if token == path[i] {
+				weight *= 2
+			} else if token != "*" {
+				weight = 0
+				break
+			}
+		}
+		if weight > bestWeight {
+			best, bestWeight = candidate.rules, weight
+		}
+	}
+	return best
+}
+
+// match compares the actual value to the expected one at the path, by type when a parent was matched by type
+func (m *ruleMatcher) match(expected, actual interface{}, path []string, byType bool) bool {
+	rules := m.rulesAt(path)
+	if rules == nil {
+		return m.matchStructure(expected, actual, path, byType, nil)
+	}
+	for i := range rules.Matchers {
+		matched := m.matchRule(&rules.Matchers[i], expected, actual, path)
+		if rules.Combine == "OR" && matched {
+			return true
+		}
+		if rules.Combine != "OR" && !matched {
+			return false
+		}
+	}
+	return rules.Combine != "OR" || len(rules.Matchers) == 0
+}
+
+func (m *ruleMatcher) matchRule(rule *MatchingRule, expected, actual interface{}, path []string) bool {
+	switch rule.Match {
+	case "type":
+		return m.matchStructure(expected, actual, path, true, rule)
+	case "equality":
+		return m.matchStructure(expected, actual, path, false, nil)
+	case "regex":
+		text, ok := ruleText(actual)
+		matched, _ := regexp.MatchString("^(?:"+rule.Regex+")$", text)
+		return ok && matched
+	case "include":
+		text, ok := ruleText(actual)
+		return ok && strings.Contains(text, rule.Value)
+	case "number":
+		_, ok := actual.(json.Number)
+		return ok
+	case "integer", "decimal":
+		number, ok := actual.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := strconv.ParseInt(number.String(), 10, 64)
+		return (err == nil) == (rule.Match == "integer")
+	case "boolean":
+		_, ok := actual.(bool)
+		return ok
+	case "null":
+		return actual == nil
+	case "date", "time", "timestamp":
+		text, ok := actual.(string)
+		return ok && matchDate(rule, text)
+	}
+	return false
+}
+
+// matchStructure compares containers item by item, and other values by equality or by type
+func (m *ruleMatcher) matchStructure(expected, actual interface{}, path []string, byType bool, rule *MatchingRule) bool {
+	switch expected := expected.(type) {
+	case map[string]interface{}:
+		actual, ok := actual.(map[string]interface{})
+		if !ok || len(actual) != len(expected) {
+			return false
+		}
+		for key, value := range expected {
+			item, ok := actual[key]
+			if !ok || !m.match(value, item, append(path[:len(path):len(path)], key), byType) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		actual, ok := actual.([]interface{})
+		if !ok {
+			return false
+		}
+		if rule != nil && (rule.Min != nil && len(actual) < *rule.Min || rule.Max != nil && len(actual) > *rule.Max) {
+			return false
+		}
+		if !byType && len(actual) != len(expected) || byType && len(expected) == 0 && len(actual) > 0 {
+			return false
+		}
+		for i, item := range actual {
+			// items matched by type are all like the first expected item
+			template := expected[0]
+			if !byType {
+				template = expected[i]
+			}
+			if !m.match(template, item, append(path[:len(path):len(path)], strconv.Itoa(i)), byType) {
+				return false
+			}
+		}
+		return true
+	}
+	if byType {
+		return sameJSONType(expected, actual)
+	}
+	return sameJSONValue(expected, actual)
+}
+
+// sameJSONType reports whether both decoded JSON values have the same type
+func sameJSONType(a, b interface{}) bool {
+	switch a.(type) {
+	case nil:
+		return b == nil
+	case bool:
+		_, ok := b.(bool)
+		return ok
+	case string:
+		_, ok := b.(string)
+		return ok
+	case json.Number:
+		_, ok := b.(json.Number)
+		return ok
+	}
+	return false
+}
+
+// ruleText formats a string, number or boolean value as text for the regex and include matchers
+func ruleText(value interface{}) (string, bool) {
+	switch value := value.(type) {
+	case string:
+		return value, true
+	case json.Number:
+		return value.String(), true
+	case bool:
+		return strconv.FormatBool(value), true
+	}
+	return "", false
+}
+
+// matchDate reports whether the text is a date, time or timestamp in the format of the rule
+func matchDate(rule *MatchingRule, text string) bool {
+	layouts := defaultDateLayouts[rule.Match]
+	if rule.Format != "" {
+		layouts = []string{dateFormatTokens.Replace(rule.Format)}
+	}
+	for _, layout := range layouts {
+		if _, err := time.Parse(layout, text); err == nil {
+			return true
+		}
+	}
+	return false
+}
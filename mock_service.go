@@ -7,7 +7,8 @@ import (
 )
 
 var (
-	// ErrRegistrationEndpointConflict happens when attempting to register a mock endpoint that is the same as the registration endpoint
+	// ErrRegistrationEndpointConflict happens when attempting to register a mock endpoint that is the registration endpoint
+	// or one of the administration routes under it
 	ErrRegistrationEndpointConflict = errors.New("Endpoint conflicts with registration endpoint")
 
 	// ErrEmptyRegistrationEndpoint happens when an empty registration endpoint is used to create a new mock service
@@ -16,29 +17,42 @@ var (
 
 // MockService is a service that allows endpoints to be mocked
 type MockService struct {
-	mockRegistrationEndpoint string
-	registrationService      *RegistrationService
-	endpointService          *EndpointService
+	adminService    *AdminService
+	endpointService *EndpointService
+	store           Store
+	journal         *Journal
+	// sessions are the isolated mock services of the sessions, nil for the mock service of a session
+	sessions       *sessionRegistry
+	persistence    *snapshotFile
+	stopPersisting func()
 }
 
 // Conf is a quick and easy way to configure the mock service with the registration endpoint and pre-determined mock endpoints
 type Conf struct {
 	RegistrationEndpoint string          `json:"regisgtrationEndpoint" xml:"registrationEndpoint"`
 	Endpoints            []*MockEndpoint `json:"endpoints" xml:"endpoints"`
+	// PersistenceFile restores the mock endpoints saved in the file in place of the configured ones and saves them to it,
+	// see Persist
+	PersistenceFile string `json:"persistenceFile,omitempty" xml:"persistenceFile,omitempty"`
+	// Store keeps the mock endpoints, the configured ones included, instead of Endpoints, see NewWithStore
+	Store Store `json:"-" xml:"-"`
 }
 
 // New creates a mock service
 func New(mockRegistrationEndpoint string) (*MockService, error) {
+	return NewWithStore(mockRegistrationEndpoint, NewEndpoints())
+}
+
+// NewWithStore creates a mock service keeping its mock endpoints in the store, such as a FileStore or a store shared
+// between instances. The mock endpoints of sessions are kept in memory.
+func NewWithStore(mockRegistrationEndpoint string, store Store) (*MockService, error) {
 	if strings.Trim(mockRegistrationEndpoint, " ") == "" {
 		return nil, ErrEmptyRegistrationEndpoint
 	}
 
-	mockEndpoints := NewEndpoints()
-	return &MockService{
-		mockRegistrationEndpoint: mockRegistrationEndpoint,
-		registrationService:      NewRegistrationService(mockEndpoints),
-		endpointService:          NewEndpointService(mockEndpoints),
-	}, nil
+	service := newMockService(mockRegistrationEndpoint, store)
+	service.sessions = newSessionRegistry()
+	return service, nil
 }
 
 // NewWithConf creates a mock service with a pre-determined configuration
@@ -46,28 +60,121 @@ func NewWithConf(conf *Conf) (*MockService, error) {
 	if strings.Trim(conf.RegistrationEndpoint, " ") == "" {
 		return nil, ErrEmptyRegistrationEndpoint
 	}
-	mockEndpoints := NewEndpoints()
-	if err := mockEndpoints.Load(conf.Endpoints); err != nil {
+	if err := registrationConflict(conf.RegistrationEndpoint, conf.Endpoints...); err != nil {
+		return nil, err
+	}
+	var mockEndpoints Store = NewEndpoints()
+	if conf.Store != nil {
+		mockEndpoints = conf.Store
+	}
+	if err := loadEndpoints(mockEndpoints, conf.Endpoints); err != nil {
+		return nil, err
+	}
+	service, err := NewWithStore(conf.RegistrationEndpoint, mockEndpoints)
+	if err != nil {
 		return nil, err
 	}
-	registrationService := NewRegistrationService(mockEndpoints)
+	if conf.PersistenceFile != "" {
+		if err := service.Persist(conf.PersistenceFile); err != nil {
+			return nil, err
+		}
+	}
+	return service, nil
+}
+
+func newMockService(mockRegistrationEndpoint string, mockEndpoints Store) *MockService {
+	journal := NewJournal()
 	endpointService := NewEndpointService(mockEndpoints)
+	endpointService.journal = journal
+	adminService := NewAdminService(mockRegistrationEndpoint, mockEndpoints, journal)
+	adminService.events = endpointService.events
+	adminService.sockets = endpointService.sockets
 
-	m := &MockService{
-		mockRegistrationEndpoint: conf.RegistrationEndpoint,
-		endpointService:          endpointService,
-		registrationService:      registrationService,
+	return &MockService{
+		adminService:    adminService,
+		endpointService: endpointService,
+		store:           mockEndpoints,
+		journal:         journal,
 	}
+}
+
+// Endpoints returns the mock endpoints served by the mock service, or nil when its store is not built on Endpoints, such
+// as a Store of another package given to NewWithStore; Store returns the store of any mock service
+func (m *MockService) Endpoints() *Endpoints {
+	return memoryOf(m.store)
+}
 
-	return m, nil
+// Store returns the store of the mock endpoints served by the mock service
+func (m *MockService) Store() Store {
+	return m.store
 }
 
-// ServeHTTP serves HTTP requests to the registration and mock endpoints
+// PactReport reports how many requests exercised each mock endpoint imported from a Pact interaction
+func (m *MockService) PactReport() *PactReport {
+	return pactReport(m.store)
+}
+
+// Journal returns the journal of requests received by the mock endpoints
+func (m *MockService) Journal() *Journal {
+	return m.journal
+}
+
+// ServeHTTP serves HTTP requests to the registration, administration and mock endpoints, handing requests of a session
+// selected by SessionPathPrefix or SessionHeader to the mock service of the session
 func (m *MockService) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	if req.Method == http.MethodPost && req.URL.Path == m.mockRegistrationEndpoint {
-		m.registrationService.ServeHTTP(w, req)
+	if m.sessions != nil {
+		if req.URL.Path == strings.TrimSuffix(m.adminService.registrationEndpoint, "/")+"/sessions" {
+			m.serveSessions(w, req)
+			return
+		}
+		if id, sessionReq := sessionOf(req); id != "" {
+			m.serveSession(w, sessionReq, id)
+			return
+		}
+	}
+
+	if m.adminService.Handles(req) {
+		m.adminService.ServeHTTP(w, req)
 		return
 	}
 
 	m.endpointService.ServeHTTP(w, req)
 }
+
+// Close stops the background sweepers removing the expired mock endpoints of the mock service and its sessions, and
+// saves the mock endpoints when they are persisted
+func (m *MockService) Close() error {
+	if m.stopPersisting != nil {
+		m.stopPersisting()
+	}
+	if closer, ok := m.store.(interface{ Close() }); ok {
+		closer.Close()
+	}
+	if m.sessions != nil {
+		m.sessions.Lock()
+		for _, session := range m.sessions.services {
+			session.Close()
+		}
+		m.sessions.Unlock()
+	}
+	return m.save()
+}
+
+// LoadDescriptorSet adds the services and message types of a serialized protobuf FileDescriptorSet, such as one
+// generated by "protoc --include_imports --descriptor_set_out", so their gRPC methods can be mocked, see GRPCResponse
+func (m *MockService) LoadDescriptorSet(data []byte) error {
+	return m.endpointService.descriptors.load(data)
+}
+
+// UseCertificateAuthority exports the certificate authority that issued the mock service's certificate through the
+// ca.pem administration route, so clients under test can be configured to trust it
+func (m *MockService) UseCertificateAuthority(ca *CertificateAuthority) {
+	m.adminService.certificateAuthority = ca
+	if m.sessions != nil {
+		m.sessions.Lock()
+		for _, session := range m.sessions.services {
+			session.adminService.certificateAuthority = ca
+		}
+		m.sessions.Unlock()
+	}
+}
@@ -124,6 +124,22 @@ func TestServeMockHTTP_MockEndpointDoesNotExist(t *testing.T) {
 	}
 }
 
+func TestServeMockHTTP_MockWithoutStatusCode(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Errorf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+
+	serve(service, http.MethodPost, "/mocks", `{"method": "GET", "endpoint": "/mock/test", "responseBody": "no status"}`)
+	recorder := serve(service, http.MethodGet, "/mock/test", "")
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected %d status but got %d", http.StatusOK, recorder.Code)
+	}
+	if recorder.Body.String() != "no status" {
+		t.Errorf(`Expected "no status" response body but got "%s"`, recorder.Body.String())
+	}
+}
+
 func TestMockService_EmptyRegistrationEndpoint(t *testing.T) {
 	service, err := mockservice.New(" ")
 	if err != mockservice.ErrEmptyRegistrationEndpoint {
@@ -153,6 +169,37 @@ func TestMockService_RegisterEmptyEndpoint(t *testing.T) {
 	}
 }
 
+func TestMockService_RegisterRegistrationEndpointConflict(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Errorf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+
+	for _, endpoint := range []string{"/mocks", "/mocks/users"} {
+		recorder := serve(service, http.MethodPost, "/mocks", `{"method": "GET", "endpoint": "`+endpoint+`", "httpStatusCode": 200}`)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected %d status for %s but got %d", http.StatusBadRequest, endpoint, recorder.Code)
+		}
+		if recorder.Body.String() != mockservice.ErrRegistrationEndpointConflict.Error()+"\n" {
+			t.Errorf(`Expected "%s" response body for %s but got "%s"`, mockservice.ErrRegistrationEndpointConflict, endpoint, recorder.Body.String())
+		}
+	}
+	if recorder := serve(service, http.MethodPost, "/mocks/har", `{"log": {"entries": [{"request": {"method": "GET", "url": "http://localhost/mocks/users", "headers": []}, "response": {"status": 200, "headers": [], "content": {}}}]}}`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected %d status for an import under the registration endpoint but got %d", http.StatusBadRequest, recorder.Code)
+	}
+	if recorder := serve(service, http.MethodPost, "/mocks", `{"method": "GET", "endpoint": "/mocksusers", "httpStatusCode": 200}`); recorder.Code != http.StatusCreated {
+		t.Errorf("Expected %d status for an endpoint only sharing a prefix but got %d", http.StatusCreated, recorder.Code)
+	}
+
+	_, err = mockservice.NewWithConf(&mockservice.Conf{
+		RegistrationEndpoint: "/mocks",
+		Endpoints:            []*mockservice.MockEndpoint{mockservice.Get("/mocks/users").WillReturn(http.StatusOK).MustBuild()},
+	})
+	if err != mockservice.ErrRegistrationEndpointConflict {
+		t.Errorf(`Expected "%s" error for a configured endpoint under the registration endpoint but got %v`, mockservice.ErrRegistrationEndpointConflict, err)
+	}
+}
+
 func TestMockService_RegisterEmptyMethod(t *testing.T) {
 	service, err := mockservice.New("/mocks")
 	if err != nil {
@@ -0,0 +1,451 @@
+package mockservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wchan2/mock_service/internal/yaml"
+)
+
+// ErrUnsupportedOpenAPIVersion is returned when importing a document that is not an OpenAPI 3 specification
+var ErrUnsupportedOpenAPIVersion = errors.New("Only OpenAPI 3 documents are supported")
+
+// openAPIRecursionDepth is how many times recursive schemas are inlined
+const openAPIRecursionDepth = 3
+
+// openAPIMethods are the operations of an OpenAPI path item
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// OpenAPIOptions configures the mock endpoints generated from an OpenAPI document
+type OpenAPIOptions struct {
+	// StatusCodes selects the response of operations, keyed by operationId or by method and path such as "GET /pets/{id}".
+	// Other operations respond with their lowest 2xx response, or else their default or lowest declared response.
+	StatusCodes map[string]int `json:"statusCodes,omitempty" xml:"-"`
+	// BasePath prefixes the path of every operation, defaulting to the path of the document's first server URL
+	BasePath string `json:"basePath,omitempty" xml:"basePath,omitempty"`
+	// ValidateRequests gives every endpoint the RequestSchema of its operation, so that requests breaking the document's
+	// parameters or JSON request body schema are answered with 400 Bad Request and the list of violations
+	ValidateRequests bool `json:"validateRequests,omitempty" xml:"validateRequests,omitempty"`
+	// FakeResponses gives JSON responses that declare a schema but no example a ResponseSchema, so that they respond
+	// with fake data generated from the schema
+	FakeResponses bool `json:"fakeResponses,omitempty" xml:"fakeResponses,omitempty"`
+	// Seed is the seed of the generated responses, see ResponseSchema
+	Seed int64 `json:"seed,omitempty" xml:"seed,omitempty"`
+}
+
+// openAPIDocument is a parsed OpenAPI document
+type openAPIDocument struct {
+	root map[string]interface{}
+}
+
+// openAPIOperation is an operation of an OpenAPI document along with the parameters it inherits from its path item
+type openAPIOperation struct {
+	method     string
+	path       string
+	id         string
+	operation  map[string]interface{}
+	parameters []map[string]interface{}
+}
+
+// ImportOpenAPI generates a mock endpoint for every operation of an OpenAPI 3 document in JSON or YAML. Each responds
+// with the example declared for the selected response, and the document's path templates match requests as they do
+// for any mock endpoint. The returned Conf has no registration endpoint.
+func ImportOpenAPI(spec []byte, options *OpenAPIOptions) (*Conf, error) {
+	if options == nil {
+		options = &OpenAPIOptions{}
+	}
+	doc, err := parseOpenAPI(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	basePath := options.BasePath
+	if basePath == "" {
+		basePath = doc.basePath()
+	}
+	conf := &Conf{Endpoints: []*MockEndpoint{}}
+	for _, operation := range doc.operations() {
+		endpoint, err := doc.mockEndpoint(operation, basePath, options)
+		if err != nil {
+			return nil, err
+		}
+		conf.Endpoints = append(conf.Endpoints, endpoint)
+	}
+	return conf, nil
+}
+
+// parseOpenAPI parses an OpenAPI 3 document in JSON or YAML
+func parseOpenAPI(spec []byte) (*openAPIDocument, error) {
+	if !json.Valid(spec) {
+		converted, err := yaml.ToJSON(spec)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse OpenAPI document: %s", err)
+		}
+		spec = converted
+	}
+
+	doc := &openAPIDocument{}
+	decoder := json.NewDecoder(bytes.NewReader(spec))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc.root); err != nil {
+		return nil, fmt.Errorf("Unable to parse OpenAPI document: %s", err)
+	}
+	if version, _ := doc.root["openapi"].(string); !strings.HasPrefix(version, "3.") {
+		return nil, ErrUnsupportedOpenAPIVersion
+	}
+	return doc, nil
+}
+
+// basePath returns the path of the first server URL
+func (d *openAPIDocument) basePath() string {
+	servers, _ := d.root["servers"].([]interface{})
+	if len(servers) == 0 {
+		return ""
+	}
+	server, _ := servers[0].(map[string]interface{})
+	serverURL, _ := server["url"].(string)
+	parsed, err := url.Parse(serverURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(parsed.Path, "/")
+}
+
+// resolve follows the local $ref of the node, such as "#/components/schemas/Pet"
+func (d *openAPIDocument) resolve(node interface{}) map[string]interface{} {
+	obj, _ := node.(map[string]interface{})
+	for i := 0; i < 32 && obj != nil; i++ {
+		ref, ok := obj["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return obj
+		}
+		obj, _ = d.lookup(ref).(map[string]interface{})
+	}
+	return obj
+}
+
+// lookup returns the node a local reference points to
+func (d *openAPIDocument) lookup(ref string) interface{} {
+	var target interface{} = d.root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		parent, _ := target.(map[string]interface{})
+		target = parent[token]
+	}
+	return target
+}
+
+// inline returns a copy of the node with its local references replaced by what they point to. Recursive references
+// are followed openAPIRecursionDepth times, then replaced by an empty schema, which accepts any value.
+func (d *openAPIDocument) inline(node interface{}, refs []string) interface{} {
+	switch node := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := node["$ref"].(string); ok && strings.HasPrefix(ref, "#/") {
+			seen := 0
+			for _, parent := range refs {
+				if parent == ref {
+					seen++
+				}
+			}
+			if seen >= openAPIRecursionDepth {
+				return map[string]interface{}{}
+			}
+			return d.inline(d.lookup(ref), append(refs, ref))
+		}
+		copied := make(map[string]interface{}, len(node))
+		for key, val := range node {
+			copied[key] = d.inline(val, refs)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(node))
+		for i, val := range node {
+			copied[i] = d.inline(val, refs)
+		}
+		return copied
+	}
+	return node
+}
+
+// operations returns the operations of the document sorted by path and method
+func (d *openAPIDocument) operations() []*openAPIOperation {
+	paths, _ := d.root["paths"].(map[string]interface{})
+	keys := make([]string, 0, len(paths))
+	for path := range paths {
+		keys = append(keys, path)
+	}
+	sort.Strings(keys)
+
+	operations := []*openAPIOperation{}
+	for _, path := range keys {
+		pathItem := d.resolve(paths[path])
+		for _, method := range openAPIMethods {
+			operation, ok := pathItem[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id, _ := operation["operationId"].(string)
+			operations = append(operations, &openAPIOperation{
+				method:     strings.ToUpper(method),
+				path:       path,
+				id:         id,
+				operation:  operation,
+				parameters: d.parameters(pathItem["parameters"], operation["parameters"]),
+			})
+		}
+	}
+	return operations
+}
+
+// parameters merges the parameters of a path item with those of its operation, which override them by name and location
+func (d *openAPIDocument) parameters(pathParameters, operationParameters interface{}) []map[string]interface{} {
+	merged, index := []map[string]interface{}{}, map[string]int{}
+	for _, list := range []interface{}{pathParameters, operationParameters} {
+		items, _ := list.([]interface{})
+		for _, item := range items {
+			parameter := d.resolve(item)
+			if parameter == nil {
+				continue
+			}
+			key := fmt.Sprint(parameter["in"], ":", parameter["name"])
+			if i, ok := index[key]; ok {
+				merged[i] = parameter
+			} else {
+				index[key] = len(merged)
+				merged = append(merged, parameter)
+			}
+		}
+	}
+	return merged
+}
+
+// mockEndpoint generates the mock endpoint of the operation
+func (d *openAPIDocument) mockEndpoint(operation *openAPIOperation, basePath string, options *OpenAPIOptions) (*MockEndpoint, error) {
+	endpoint := &MockEndpoint{
+		Method:          operation.method,
+		Endpoint:        basePath + operation.path,
+		StatusCode:      http.StatusOK,
+		ResponseHeaders: map[string]string{},
+	}
+	if !validPathTemplate(endpoint.Endpoint) {
+		return nil, fmt.Errorf("Unsupported path template %s in OpenAPI document", operation.path)
+	}
+
+	if options.ValidateRequests {
+		schema, err := d.requestSchema(operation)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read the request schema of %s %s: %s", operation.method, operation.path, err)
+		}
+		endpoint.RequestSchema = schema
+	}
+
+	responses, _ := operation.operation["responses"].(map[string]interface{})
+	statusKey, statusCode := selectStatus(responses, operation, options)
+	endpoint.StatusCode = statusCode
+	response := d.resolve(responses[statusKey])
+	if response == nil {
+		return endpoint, nil
+	}
+
+	headers, _ := response["headers"].(map[string]interface{})
+	for name, header := range headers {
+		if example, ok := d.headerExample(d.resolve(header)); ok {
+			endpoint.ResponseHeaders[name] = example
+		}
+	}
+
+	content, _ := response["content"].(map[string]interface{})
+	mediaType := selectMediaType(content)
+	if mediaType == "" {
+		return endpoint, nil
+	}
+	endpoint.ResponseHeaders["Content-Type"] = mediaType
+	media := d.resolve(content[mediaType])
+	if example, ok := d.mediaExample(media); ok {
+		body, err := renderExample(example, mediaType)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to render the example of %s %s: %s", operation.method, operation.path, err)
+		}
+		endpoint.ResponseBody = body
+	} else if options.FakeResponses && isJSONMediaType(mediaType) && media["schema"] != nil {
+		schema, err := d.inlineJSON(media["schema"])
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read the response schema of %s %s: %s", operation.method, operation.path, err)
+		}
+		endpoint.ResponseSchema = &ResponseSchema{Schema: schema, Seed: options.Seed}
+	}
+	return endpoint, nil
+}
+
+// requestSchema builds the request schema of the operation from its parameters and request body
+func (d *openAPIDocument) requestSchema(operation *openAPIOperation) (*RequestSchema, error) {
+	schema := &RequestSchema{Parameters: []SchemaParameter{}}
+	for _, parameter := range operation.parameters {
+		name, _ := parameter["name"].(string)
+		in, _ := parameter["in"].(string)
+		if in == "header" && (strings.EqualFold(name, "Accept") || strings.EqualFold(name, "Content-Type") || strings.EqualFold(name, "Authorization")) {
+			continue
+		}
+		required, _ := parameter["required"].(bool)
+		style, ok := parameter["style"].(string)
+		if !ok && (in == "query" || in == "cookie") {
+			style = "form"
+		}
+		explode, ok := parameter["explode"].(bool)
+		if !ok {
+			explode = style == "form"
+		}
+		parameterSchema, err := d.inlineJSON(parameter["schema"])
+		if err != nil {
+			return nil, err
+		}
+		schema.Parameters = append(schema.Parameters, SchemaParameter{
+			Name:     name,
+			In:       in,
+			Required: required,
+			Explode:  explode,
+			Schema:   parameterSchema,
+		})
+	}
+
+	requestBody := d.resolve(operation.operation["requestBody"])
+	if requestBody == nil {
+		return schema, nil
+	}
+	schema.BodyRequired, _ = requestBody["required"].(bool)
+	content, _ := requestBody["content"].(map[string]interface{})
+	for mediaType := range content {
+		schema.ContentTypes = append(schema.ContentTypes, mediaType)
+	}
+	sort.Strings(schema.ContentTypes)
+	if mediaType := selectMediaType(content); isJSONMediaType(mediaType) {
+		media := d.resolve(content[mediaType])
+		body, err := d.inlineJSON(media["schema"])
+		if err != nil {
+			return nil, err
+		}
+		schema.Body = body
+	}
+	return schema, nil
+}
+
+// inlineJSON marshals the node with its references inlined, or returns nil for a missing node
+func (d *openAPIDocument) inlineJSON(node interface{}) (json.RawMessage, error) {
+	if node == nil {
+		return nil, nil
+	}
+	return json.Marshal(d.inline(node, nil))
+}
+
+// selectStatus picks the response of the operation, returning its key in the responses object and its status code
+func selectStatus(responses map[string]interface{}, operation *openAPIOperation, options *OpenAPIOptions) (string, int) {
+	for _, key := range []string{operation.id, operation.method + " " + operation.path} {
+		if statusCode, ok := options.StatusCodes[key]; ok && key != "" {
+			for _, candidate := range []string{strconv.Itoa(statusCode), fmt.Sprintf("%dXX", statusCode/100), "default"} {
+				if _, ok := responses[candidate]; ok {
+					return candidate, statusCode
+				}
+			}
+			return "", statusCode
+		}
+	}
+
+	codes := []int{}
+	for key := range responses {
+		if code, err := strconv.Atoi(key); err == nil {
+			codes = append(codes, code)
+		}
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		if code >= 200 && code <= 299 {
+			return strconv.Itoa(code), code
+		}
+	}
+	for _, key := range []string{"2XX", "default"} {
+		if _, ok := responses[key]; ok {
+			return key, http.StatusOK
+		}
+	}
+	if len(codes) > 0 {
+		return strconv.Itoa(codes[0]), codes[0]
+	}
+	return "", http.StatusOK
+}
+
+// selectMediaType prefers JSON content, then the first media type by name
+func selectMediaType(content map[string]interface{}) string {
+	mediaTypes := make([]string, 0, len(content))
+	for mediaType := range content {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Strings(mediaTypes)
+	for _, mediaType := range mediaTypes {
+		if mediaType == "application/json" {
+			return mediaType
+		}
+	}
+	for _, mediaType := range mediaTypes {
+		if isJSONMediaType(mediaType) {
+			return mediaType
+		}
+	}
+	if len(mediaTypes) > 0 {
+		return mediaTypes[0]
+	}
+	return ""
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return strings.Contains(mediaType, "json")
+}
+
+// mediaExample returns the example of a media type object, the first of its named examples, or the example of its schema
+func (d *openAPIDocument) mediaExample(media map[string]interface{}) (interface{}, bool) {
+	if example, ok := media["example"]; ok {
+		return example, true
+	}
+	if examples, ok := media["examples"].(map[string]interface{}); ok && len(examples) > 0 {
+		names := make([]string, 0, len(examples))
+		for name := range examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if example, ok := d.resolve(examples[names[0]])["value"]; ok {
+			return example, true
+		}
+	}
+	if schema := d.resolve(media["schema"]); schema != nil {
+		example, ok := schema["example"]
+		return example, ok
+	}
+	return nil, false
+}
+
+// headerExample returns the example of a header object or of its schema as a header value
+func (d *openAPIDocument) headerExample(header map[string]interface{}) (string, bool) {
+	example, ok := header["example"]
+	if !ok {
+		example, ok = d.resolve(header["schema"])["example"]
+	}
+	if !ok || example == nil {
+		return "", false
+	}
+	return fmt.Sprint(example), true
+}
+
+// renderExample renders an example as the response body of the media type, using string examples of non-JSON media types as they are
+func renderExample(example interface{}, mediaType string) (string, error) {
+	if s, ok := example.(string); ok && !isJSONMediaType(mediaType) {
+		return s, nil
+	}
+	body, err := json.Marshal(example)
+	return string(body), err
+}
@@ -0,0 +1,230 @@
+package mockservice_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/wchan2/mock_service"
+)
+
+const petstoreSpec = `openapi: 3.0.3
+info:
+  title: Petstore
+  version: 1.0.0
+servers:
+  - url: https://petstore.example.com/v1/
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      responses:
+        '200':
+          description: A page of pets
+          headers:
+            X-Next:
+              schema:
+                type: string
+                example: /pets?page=2
+          content:
+            application/json:
+              example:
+                - id: 1
+                  name: Rex
+            application/xml:
+              example: <pets/>
+        default:
+          description: Error
+    post:
+      operationId: createPet
+      responses:
+        '201':
+          description: Created
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+        '400':
+          $ref: '#/components/responses/BadRequest'
+  /pets/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: integer
+    get:
+      operationId: getPet
+      responses:
+        '200':
+          description: A pet
+          content:
+            application/json:
+              examples:
+                rex:
+                  $ref: '#/components/examples/Rex'
+        '404':
+          description: Not found
+          content:
+            text/plain:
+              example: no such pet
+  /files/{name}.txt:
+    get:
+      responses:
+        2XX:
+          description: A file
+          content:
+            text/plain:
+              example: contents
+components:
+  schemas:
+    Pet:
+      type: object
+      example:
+        id: 2
+        name: Tom
+  responses:
+    BadRequest:
+      description: Bad request
+      content:
+        application/problem+json:
+          example:
+            title: Invalid pet
+  examples:
+    Rex:
+      value:
+        id: 1
+        name: Rex
+`
+
+func TestImportOpenAPI(t *testing.T) {
+	conf, err := mockservice.ImportOpenAPI([]byte(petstoreSpec), nil)
+	if err != nil {
+		t.Fatalf("Expected importing the OpenAPI document to succeed but got %s", err)
+	}
+
+	cases := []struct {
+		method      string
+		endpoint    string
+		status      int
+		contentType string
+		body        string
+	}{
+		{http.MethodGet, "/v1/files/{name}.txt", http.StatusOK, "text/plain", "contents"},
+		{http.MethodGet, "/v1/pets", http.StatusOK, "application/json", `[{"id":1,"name":"Rex"}]`},
+		{http.MethodPost, "/v1/pets", http.StatusCreated, "application/json", `{"id":2,"name":"Tom"}`},
+		{http.MethodGet, "/v1/pets/{id}", http.StatusOK, "application/json", `{"id":1,"name":"Rex"}`},
+	}
+	if len(conf.Endpoints) != len(cases) {
+		t.Fatalf("Expected %d endpoints but got %d", len(cases), len(conf.Endpoints))
+	}
+	for i, c := range cases {
+		endpoint := conf.Endpoints[i]
+		if endpoint.Method != c.method || endpoint.Endpoint != c.endpoint || endpoint.StatusCode != c.status {
+			t.Errorf("Expected %s %s returning %d but got %s %s returning %d", c.method, c.endpoint, c.status, endpoint.Method, endpoint.Endpoint, endpoint.StatusCode)
+		}
+		if contentType := endpoint.ResponseHeaders["Content-Type"]; contentType != c.contentType {
+			t.Errorf(`Expected Content-Type "%s" for %s %s but got "%s"`, c.contentType, c.method, c.endpoint, contentType)
+		}
+		if endpoint.ResponseBody != c.body {
+			t.Errorf("Expected body %s for %s %s but got %s", c.body, c.method, c.endpoint, endpoint.ResponseBody)
+		}
+	}
+	if next := conf.Endpoints[1].ResponseHeaders["X-Next"]; next != "/pets?page=2" {
+		t.Errorf("Expected the X-Next header example but got %s", next)
+	}
+}
+
+func TestImportOpenAPI_Options(t *testing.T) {
+	conf, err := mockservice.ImportOpenAPI([]byte(petstoreSpec), &mockservice.OpenAPIOptions{
+		BasePath: "/api",
+		StatusCodes: map[string]int{
+			"getPet":     http.StatusNotFound,
+			"POST /pets": http.StatusBadRequest,
+			"listPets":   http.StatusInternalServerError,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected importing the OpenAPI document to succeed but got %s", err)
+	}
+
+	cases := []struct {
+		endpoint    string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"/api/files/{name}.txt", http.StatusOK, "text/plain", "contents"},
+		{"/api/pets", http.StatusInternalServerError, "", ""},
+		{"/api/pets", http.StatusBadRequest, "application/problem+json", `{"title":"Invalid pet"}`},
+		{"/api/pets/{id}", http.StatusNotFound, "text/plain", "no such pet"},
+	}
+	for i, c := range cases {
+		endpoint := conf.Endpoints[i]
+		if endpoint.Endpoint != c.endpoint || endpoint.StatusCode != c.status {
+			t.Errorf("Expected %s returning %d but got %s returning %d", c.endpoint, c.status, endpoint.Endpoint, endpoint.StatusCode)
+		}
+		if contentType := endpoint.ResponseHeaders["Content-Type"]; contentType != c.contentType {
+			t.Errorf(`Expected Content-Type "%s" for %s but got "%s"`, c.contentType, c.endpoint, contentType)
+		}
+		if endpoint.ResponseBody != c.body {
+			t.Errorf("Expected body %s for %s but got %s", c.body, c.endpoint, endpoint.ResponseBody)
+		}
+	}
+}
+
+func TestImportOpenAPI_Errors(t *testing.T) {
+	cases := []struct {
+		name string
+		spec string
+	}{
+		{"Swagger_2", `{"swagger": "2.0", "paths": {}}`},
+		{"Invalid_YAML", "openapi: 3.0.0\npaths: [unterminated"},
+		{"Unsupported_path_template", `{"openapi": "3.0.0", "paths": {"/{a}{b}": {"get": {"responses": {}}}}}`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := mockservice.ImportOpenAPI([]byte(c.spec), nil); err == nil {
+				t.Errorf("Expected importing the OpenAPI document to fail")
+			}
+		})
+	}
+
+	if _, err := mockservice.ImportOpenAPI([]byte(`{"swagger": "2.0"}`), nil); err != mockservice.ErrUnsupportedOpenAPIVersion {
+		t.Errorf("Expected %s but got %v", mockservice.ErrUnsupportedOpenAPIVersion, err)
+	}
+}
+
+func TestAdminService_ImportOpenAPI(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+
+	recorder := serve(service, http.MethodPost, "/mocks/openapi?status=getPet:404", petstoreSpec)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected %d status but got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+	}
+	endpoints := []*mockservice.MockEndpoint{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &endpoints); err != nil || len(endpoints) != 4 {
+		t.Errorf("Expected the 4 created endpoints but got %s (%v)", recorder.Body.String(), err)
+	}
+
+	if recorder := serve(service, http.MethodGet, "/v1/pets/7", ""); recorder.Code != http.StatusNotFound || recorder.Body.String() != "no such pet" {
+		t.Errorf("Expected the not found example but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := serve(service, http.MethodGet, "/v1/files/notes.txt", ""); recorder.Code != http.StatusOK || recorder.Body.String() != "contents" {
+		t.Errorf("Expected the file example but got %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	for _, url := range []string{"/mocks/openapi?status=getPet", "/mocks/openapi?status=getPet:abc"} {
+		if recorder := serve(service, http.MethodPost, url, petstoreSpec); recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected %d status for %s but got %d", http.StatusBadRequest, url, recorder.Code)
+		}
+	}
+	if recorder := serve(service, http.MethodPost, "/mocks/openapi", `{"swagger": "2.0"}`); recorder.Code != http.StatusBadRequest ||
+		!strings.Contains(recorder.Body.String(), mockservice.ErrUnsupportedOpenAPIVersion.Error()) {
+		t.Errorf("Expected a bad request for a Swagger 2 document but got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
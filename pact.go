@@ -0,0 +1,308 @@
+package mockservice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidPact is returned when importing a document that is not a Pact contract
+var ErrInvalidPact = errors.New("Invalid Pact contract provided")
+
+// PactInteraction identifies the Pact interaction a mock endpoint was imported from
+type PactInteraction struct {
+	Consumer    string `json:"consumer" xml:"consumer"`
+	Provider    string `json:"provider" xml:"provider"`
+	Description string `json:"description" xml:"description"`
+	// ProviderStates are the states the provider is in for the interaction, which are the required scenario state
+	ProviderStates []string `json:"providerStates,omitempty" xml:"providerStates,omitempty"`
+}
+
+// PactReport tells which of the imported Pact interactions were exercised
+type PactReport struct {
+	Interactions []PactInteractionReport `json:"interactions" xml:"interactions"`
+	// Exercised is true when every interaction was exercised at least once
+	Exercised bool `json:"exercised" xml:"exercised"`
+}
+
+// PactInteractionReport tells how many requests exercised a Pact interaction
+type PactInteractionReport struct {
+	PactInteraction
+	Method   string `json:"method" xml:"method"`
+	Endpoint string `json:"endpoint" xml:"endpoint"`
+	Count    int    `json:"count" xml:"count"`
+}
+
+// pactContract is the subset of a Pact v2 or v3 contract needed to build mock endpoints
+type pactContract struct {
+	Consumer struct {
+		Name string `json:"name"`
+	} `json:"consumer"`
+	Provider struct {
+		Name string `json:"name"`
+	} `json:"provider"`
+	Interactions []pactInteraction `json:"interactions"`
+}
+
+type pactInteraction struct {
+	Description    string `json:"description"`
+	ProviderState  string `json:"providerState"`
+	ProviderStates []struct {
+		Name string `json:"name"`
+	} `json:"providerStates"`
+	Request struct {
+		Method        string            `json:"method"`
+		Path          string            `json:"path"`
+		Query         json.RawMessage   `json:"query"`
+		Headers       map[string]string `json:"headers"`
+		Body          json.RawMessage   `json:"body"`
+		MatchingRules json.RawMessage   `json:"matchingRules"`
+	} `json:"request"`
+	Response struct {
+		Status  int               `json:"status"`
+		Headers map[string]string `json:"headers"`
+		Body    json.RawMessage   `json:"body"`
+	} `json:"response"`
+}
+
+// pactRules are the matching rules of a Pact v3 request, by category and path
+type pactRules struct {
+	Path   *MatchingRules            `json:"path"`
+	Query  map[string]*MatchingRules `json:"query"`
+	Header map[string]*MatchingRules `json:"header"`
+	Body   map[string]*MatchingRules `json:"body"`
+}
+
+// ImportPact converts the interactions of a Pact v2 or v3 consumer contract into mock endpoints that match the
+// interaction request and respond with its response. JSON request bodies are matched with the body matching rules of the
+// interaction; headers and query parameters with matching rules are not matched, and path matching rules are ignored
+// in favour of the example path. The provider states of an interaction are the required state of a scenario named
+// after the consumer and provider, so that interactions differing only by provider state can be selected by setting
+// the scenario state. The returned Conf has no registration endpoint.
+func ImportPact(pact []byte) (*Conf, error) {
+	contract := pactContract{}
+	if err := json.Unmarshal(pact, &contract); err != nil {
+		return nil, fmt.Errorf("Unable to parse Pact contract: %s", err)
+	}
+	if contract.Interactions == nil {
+		return nil, ErrInvalidPact
+	}
+
+	conf := &Conf{Endpoints: []*MockEndpoint{}}
+	for i := range contract.Interactions {
+		endpoint, err := pactEndpoint(&contract, &contract.Interactions[i])
+		if err != nil {
+			return nil, fmt.Errorf("Unable to import Pact interaction %q: %s", contract.Interactions[i].Description, err)
+		}
+		conf.Endpoints = append(conf.Endpoints, endpoint)
+	}
+	return conf, nil
+}
+
+// PactScenario returns the name of the scenario whose state selects the provider state of the interactions between
+// the consumer and provider
+func PactScenario(consumer, provider string) string {
+	return consumer + " -> " + provider
+}
+
+// pactEndpoint builds the mock endpoint of a Pact interaction
+func pactEndpoint(contract *pactContract, interaction *pactInteraction) (*MockEndpoint, error) {
+	request := &interaction.Request
+	rules, err := parsePactRules(request.MatchingRules)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := &MockEndpoint{
+		Method:          strings.ToUpper(request.Method),
+		Endpoint:        request.Path,
+		StatusCode:      interaction.Response.Status,
+		ResponseHeaders: map[string]string{},
+		Pact: &PactInteraction{
+			Consumer:    contract.Consumer.Name,
+			Provider:    contract.Provider.Name,
+			Description: interaction.Description,
+		},
+	}
+	if endpoint.Endpoint == "" {
+		endpoint.Endpoint = "/"
+	}
+	if interaction.ProviderState != "" {
+		endpoint.Pact.ProviderStates = []string{interaction.ProviderState}
+	}
+	for _, state := range interaction.ProviderStates {
+		endpoint.Pact.ProviderStates = append(endpoint.Pact.ProviderStates, state.Name)
+	}
+	if len(endpoint.Pact.ProviderStates) > 0 {
+		endpoint.Scenario = PactScenario(contract.Consumer.Name, contract.Provider.Name)
+		endpoint.RequiredState = strings.Join(endpoint.Pact.ProviderStates, ", ")
+	}
+
+	query, err := parsePactQuery(request.Query)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range query {
+		if _, ok := rules.Query[key]; ok || len(values) == 0 {
+			continue
+		}
+		if endpoint.QueryParameters == nil {
+			endpoint.QueryParameters = map[string]string{}
+		}
+		endpoint.QueryParameters[key] = values[0]
+	}
+
+	for key, value := range request.Headers {
+		if containsRules(rules.Header, key) {
+			continue
+		}
+		if endpoint.RequestHeaders == nil {
+			endpoint.RequestHeaders = map[string]string{}
+		}
+		endpoint.RequestHeaders[http.CanonicalHeaderKey(key)] = value
+	}
+
+	if body := request.Body; len(body) > 0 && string(body) != "null" {
+		text := ""
+		if json.Unmarshal(body, &text) == nil && !json.Valid([]byte(text)) {
+			endpoint.RequestBody = text
+		} else {
+			matcher := BodyMatcher{EqualToJSON: body, MatchingRules: rules.Body}
+			if text != "" {
+				matcher.EqualToJSON = json.RawMessage(text)
+			}
+			endpoint.BodyMatchers = []BodyMatcher{matcher}
+		}
+	}
+
+	for key, value := range interaction.Response.Headers {
+		endpoint.ResponseHeaders[http.CanonicalHeaderKey(key)] = value
+	}
+	if body := interaction.Response.Body; len(body) > 0 && string(body) != "null" {
+		text := ""
+		if json.Unmarshal(body, &text) == nil {
+			endpoint.ResponseBody = text
+		} else {
+			endpoint.ResponseBody = string(body)
+			if _, ok := endpoint.ResponseHeaders["Content-Type"]; !ok {
+				endpoint.ResponseHeaders["Content-Type"] = "application/json"
+			}
+		}
+	}
+	return endpoint, nil
+}
+
+// parsePactRules reads the request matching rules of Pact v3, or converts the flat rules of Pact v2 such as
+// {"$.body.name": {"match": "type"}}
+func parsePactRules(data json.RawMessage) (*pactRules, error) {
+	rules := &pactRules{}
+	if len(data) == 0 {
+		return rules, nil
+	}
+	flat := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &flat); err != nil {
+		return nil, fmt.Errorf("invalid matching rules: %s", err)
+	}
+	v2 := false
+	for key := range flat {
+		v2 = v2 || strings.HasPrefix(key, "$.")
+	}
+	if !v2 {
+		if err := json.Unmarshal(data, rules); err != nil {
+			return nil, fmt.Errorf("invalid matching rules: %s", err)
+		}
+		return rules, nil
+	}
+
+	rules.Query = map[string]*MatchingRules{}
+	rules.Header = map[string]*MatchingRules{}
+	rules.Body = map[string]*MatchingRules{}
+	for key, raw := range flat {
+		rule := MatchingRule{}
+		if err := json.Unmarshal(raw, &rule); err != nil {
+			return nil, fmt.Errorf("invalid matching rule %s: %s", key, err)
+		}
+		if rule.Match == "" && rule.Regex != "" {
+			rule.Match = "regex"
+		} else if rule.Match == "" && (rule.Min != nil || rule.Max != nil) {
+			rule.Match = "type"
+		}
+		set := &MatchingRules{Matchers: []MatchingRule{rule}}
+		switch {
+		case key == "$.body" || strings.HasPrefix(key, "$.body.") || strings.HasPrefix(key, "$.body["):
+			rules.Body["$"+strings.TrimPrefix(key, "$.body")] = set
+		case strings.HasPrefix(key, "$.headers."):
+			rules.Header[strings.TrimPrefix(key, "$.headers.")] = set
+		case strings.HasPrefix(key, "$.query."):
+			rules.Query[strings.TrimPrefix(key, "$.query.")] = set
+		case key == "$.path":
+			rules.Path = set
+		}
+	}
+	return rules, nil
+}
+
+// parsePactQuery reads the query of a Pact v3 request, a map of values, or of a Pact v2 request, a query string
+func parsePactQuery(data json.RawMessage) (url.Values, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	raw := ""
+	if json.Unmarshal(data, &raw) == nil {
+		return url.ParseQuery(raw)
+	}
+	query := url.Values{}
+	if err := json.Unmarshal(data, &query); err != nil {
+		return nil, fmt.Errorf("invalid query: %s", err)
+	}
+	return query, nil
+}
+
+// containsRules reports whether there are matching rules for the header, whose name is case insensitive
+func containsRules(rules map[string]*MatchingRules, header string) bool {
+	for key := range rules {
+		if strings.EqualFold(key, header) {
+			return true
+		}
+	}
+	return false
+}
+
+// PactReport tells how many requests exercised each of the endpoints imported from Pact interactions, sorted by
+// consumer, provider and description
+func (e *Endpoints) PactReport() *PactReport {
+	return pactReport(e)
+}
+
+// pactReport reports how many requests matched the endpoints of the store imported from Pact interactions
+func pactReport(store Store) *PactReport {
+	report := &PactReport{Interactions: []PactInteractionReport{}, Exercised: true}
+	for _, endpoint := range store.List() {
+		if endpoint.Pact == nil {
+			continue
+		}
+		count := store.MatchCount(endpoint)
+		report.Interactions = append(report.Interactions, PactInteractionReport{
+			PactInteraction: *endpoint.Pact,
+			Method:          endpoint.Method,
+			Endpoint:        endpoint.Endpoint,
+			Count:           count,
+		})
+		report.Exercised = report.Exercised && count > 0
+	}
+	sort.SliceStable(report.Interactions, func(i, j int) bool {
+		a, b := report.Interactions[i], report.Interactions[j]
+		if a.Consumer != b.Consumer {
+			return a.Consumer < b.Consumer
+		}
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.Description < b.Description
+	})
+	return report
+}
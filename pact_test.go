@@ -0,0 +1,183 @@
+package mockservice_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wchan2/mock_service"
+)
+
+const webUsersPact = `{
+	"consumer": {"name": "web"},
+	"provider": {"name": "users"},
+	"interactions": [
+		{
+			"description": "a request for user 1",
+			"providerStates": [{"name": "user 1 exists", "params": {"id": 1}}],
+			"request": {"method": "GET", "path": "/users/1", "headers": {"Accept": "application/json"}},
+			"response": {"status": 200, "headers": {"Content-Type": "application/json"}, "body": {"id": 1, "name": "ada"}}
+		},
+		{
+			"description": "a request for a missing user 1",
+			"providerStates": [{"name": "user 1 does not exist"}],
+			"request": {"method": "GET", "path": "/users/1", "headers": {"Accept": "application/json"}},
+			"response": {"status": 404}
+		},
+		{
+			"description": "a request to create a user",
+			"request": {
+				"method": "POST",
+				"path": "/users",
+				"query": {"notify": ["true"], "trace": ["abc"]},
+				"headers": {"Content-Type": "application/json", "X-Request-Id": "42"},
+				"body": {"name": "ada", "roles": ["admin"]},
+				"matchingRules": {
+					"body": {"$.name": {"matchers": [{"match": "type"}]}, "$.roles": {"matchers": [{"match": "type", "min": 1}]}},
+					"header": {"X-Request-Id": {"matchers": [{"match": "regex", "regex": "[0-9]+"}]}},
+					"query": {"trace": {"matchers": [{"match": "type"}]}}
+				}
+			},
+			"response": {"status": 201, "body": "created"}
+		}
+	],
+	"metadata": {"pactSpecification": {"version": "3.0.0"}}
+}`
+
+func TestImportPact(t *testing.T) {
+	conf, err := mockservice.ImportPact([]byte(webUsersPact))
+	if err != nil {
+		t.Fatalf("Expected importing the Pact contract to succeed but got %s", err)
+	}
+
+	create, one := conf.Endpoints[2], 1
+	expected := &mockservice.MockEndpoint{
+		Method:          http.MethodPost,
+		Endpoint:        "/users",
+		StatusCode:      http.StatusCreated,
+		ResponseBody:    "created",
+		ResponseHeaders: map[string]string{},
+		RequestHeaders:  map[string]string{"Content-Type": "application/json"},
+		QueryParameters: map[string]string{"notify": "true"},
+		BodyMatchers: []mockservice.BodyMatcher{{
+			EqualToJSON: json.RawMessage(`{"name": "ada", "roles": ["admin"]}`),
+			MatchingRules: map[string]*mockservice.MatchingRules{
+				"$.name":  {Matchers: []mockservice.MatchingRule{{Match: "type"}}},
+				"$.roles": {Matchers: []mockservice.MatchingRule{{Match: "type", Min: &one}}},
+			},
+		}},
+		Pact: &mockservice.PactInteraction{Consumer: "web", Provider: "users", Description: "a request to create a user"},
+	}
+	if !reflect.DeepEqual(create, expected) {
+		got, _ := json.Marshal(create)
+		t.Errorf("Expected the interaction without matched headers and query parameters but got %s", got)
+	}
+
+	found := conf.Endpoints[0]
+	if found.Scenario != "web -> users" || found.RequiredState != "user 1 exists" || found.ResponseBody != `{"id": 1, "name": "ada"}` {
+		t.Errorf("Expected the provider state to be the required scenario state but got %+v", found)
+	}
+}
+
+func TestImportPact_V2(t *testing.T) {
+	pact := `{
+		"consumer": {"name": "web"},
+		"provider": {"name": "users"},
+		"interactions": [{
+			"description": "a search",
+			"providerState": "users exist",
+			"request": {
+				"method": "post",
+				"path": "/search",
+				"query": "q=ada&page=1",
+				"body": {"terms": ["ada"]},
+				"matchingRules": {"$.body.terms": {"min": 1}, "$.query.page": {"match": "type"}}
+			},
+			"response": {"status": 200, "body": []}
+		}]
+	}`
+	conf, err := mockservice.ImportPact([]byte(pact))
+	if err != nil {
+		t.Fatalf("Expected importing the Pact contract to succeed but got %s", err)
+	}
+
+	endpoint := conf.Endpoints[0]
+	if endpoint.Method != http.MethodPost || endpoint.RequiredState != "users exist" || !reflect.DeepEqual(endpoint.QueryParameters, map[string]string{"q": "ada"}) {
+		t.Errorf("Expected the Pact v2 interaction but got %+v", endpoint)
+	}
+	if rules := endpoint.BodyMatchers[0].MatchingRules["$.terms"]; rules == nil || rules.Matchers[0].Match != "type" {
+		t.Errorf("Expected the v2 body rule to be converted but got %+v", endpoint.BodyMatchers[0].MatchingRules)
+	}
+}
+
+func TestImportPact_Errors(t *testing.T) {
+	if _, err := mockservice.ImportPact([]byte(`{"consumer":`)); err == nil {
+		t.Errorf("Expected importing invalid JSON to fail")
+	}
+	if _, err := mockservice.ImportPact([]byte(`{"consumer": {"name": "web"}}`)); err != mockservice.ErrInvalidPact {
+		t.Errorf("Expected %s but got %v", mockservice.ErrInvalidPact, err)
+	}
+	pact := `{"interactions": [{"request": {"method": "GET", "path": "/", "matchingRules": []}, "response": {"status": 200}}]}`
+	if _, err := mockservice.ImportPact([]byte(pact)); err == nil {
+		t.Errorf("Expected importing invalid matching rules to fail")
+	}
+}
+
+func TestAdminService_Pact(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	if recorder := serve(service, http.MethodPost, "/mocks/pact", webUsersPact); recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected %d status but got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+	}
+
+	getUser := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		req.Header.Set("Accept", "application/json")
+		recorder := httptest.NewRecorder()
+		service.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+	if code := getUser(); code != http.StatusNotFound {
+		t.Errorf("Expected no interaction to match before a provider state is set but got %d", code)
+	}
+	serve(service, http.MethodPut, "/mocks/scenarios", `{"name": "web -> users", "state": "user 1 does not exist"}`)
+	if code := getUser(); code != http.StatusNotFound {
+		t.Errorf("Expected the missing user interaction but got %d", code)
+	}
+	serve(service, http.MethodPut, "/mocks/scenarios", `{"name": "web -> users", "state": "user 1 exists"}`)
+	if code := getUser(); code != http.StatusOK {
+		t.Errorf("Expected the existing user interaction but got %d", code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/users?notify=true&trace=xyz", strings.NewReader(`{"name": "grace", "roles": ["dev", "ops"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	service.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusCreated {
+		t.Errorf("Expected the body to match the interaction by type but got %d", recorder.Code)
+	}
+
+	report := mockservice.PactReport{}
+	recorder = serve(service, http.MethodGet, "/mocks/pact", "")
+	if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Expected a JSON Pact report but got %s", recorder.Body.String())
+	}
+	counts := map[string]int{}
+	for _, interaction := range report.Interactions {
+		counts[interaction.Description] = interaction.Count
+	}
+	expected := map[string]int{"a request for user 1": 1, "a request for a missing user 1": 1, "a request to create a user": 1}
+	if !reflect.DeepEqual(counts, expected) || !report.Exercised {
+		t.Errorf("Expected every interaction to be exercised once but got %s", recorder.Body.String())
+	}
+
+	service.Endpoints().Create(mockservice.Get("/other").MustBuild())
+	if report := service.Endpoints().PactReport(); len(report.Interactions) != 3 {
+		t.Errorf("Expected endpoints not imported from Pact contracts to be left out but got %d interactions", len(report.Interactions))
+	}
+}
@@ -0,0 +1,337 @@
+package mockservice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidPostmanCollection is returned when importing a document that is not a Postman collection
+var ErrInvalidPostmanCollection = errors.New("Invalid Postman collection provided")
+
+// postmanVariablePattern matches a {{variable}} reference
+var postmanVariablePattern = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// postmanMaxResolveDepth limits the resolution of variables whose values reference other variables
+const postmanMaxResolveDepth = 5
+
+// postmanPreviewTypes are the content types of the preview languages of example responses without a Content-Type header
+var postmanPreviewTypes = map[string]string{
+	"json": "application/json",
+	"xml":  "application/xml",
+	"html": "text/html",
+	"text": "text/plain",
+}
+
+// PostmanOptions configures the mock endpoints imported from a Postman collection
+type PostmanOptions struct {
+	// Variables override the collection variables, such as the values of a Postman environment
+	Variables map[string]string `json:"variables,omitempty" xml:"-"`
+	// RequestHeaders are the request headers that mock endpoints match on when an example request sets them
+	RequestHeaders []string `json:"requestHeaders,omitempty" xml:"requestHeaders,omitempty"`
+	// MatchBody makes mock endpoints match the raw body of example requests, semantically for JSON bodies
+	MatchBody bool `json:"matchBody,omitempty" xml:"matchBody,omitempty"`
+}
+
+// postmanCollection is the subset of a Postman Collection v2.1 document needed to build mock endpoints
+type postmanCollection struct {
+	Info *struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanVariable `json:"variable"`
+}
+
+// postmanItem is either a folder of items or a request with its saved example responses
+type postmanItem struct {
+	Name     string            `json:"name"`
+	Item     []postmanItem     `json:"item"`
+	Request  *postmanRequest   `json:"request"`
+	Response []postmanResponse `json:"response"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	Header []postmanVariable `json:"header"`
+	URL    postmanURL        `json:"url"`
+	Body   *struct {
+		Mode    string `json:"mode"`
+		Raw     string `json:"raw"`
+		Options struct {
+			Raw struct {
+				Language string `json:"language"`
+			} `json:"raw"`
+		} `json:"options"`
+	} `json:"body"`
+}
+
+type postmanResponse struct {
+	Name            string            `json:"name"`
+	OriginalRequest *postmanRequest   `json:"originalRequest"`
+	Code            int               `json:"code"`
+	Header          []postmanVariable `json:"header"`
+	Body            string            `json:"body"`
+	PreviewLanguage string            `json:"_postman_previewlanguage"`
+}
+
+// postmanVariable is a variable, header or query parameter of a collection
+type postmanVariable struct {
+	Key      string      `json:"key"`
+	Value    interface{} `json:"value"`
+	Disabled bool        `json:"disabled"`
+}
+
+// postmanURL is a request URL, given in a collection either as a string or as an object
+type postmanURL struct {
+	Raw   string            `json:"raw"`
+	Path  interface{}       `json:"path"`
+	Query []postmanVariable `json:"query"`
+}
+
+// UnmarshalJSON accepts both forms of a request URL
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	if raw := ""; json.Unmarshal(data, &raw) == nil {
+		*u = postmanURL{Raw: raw}
+		return nil
+	}
+	type plain postmanURL
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+// ImportPostman converts the saved example responses of a Postman Collection v2.1 document into mock endpoints that
+// match the method, path and query of the example request and respond with the example. {{variables}} are resolved
+// from the options and collection variables, path segments such as :id or an unresolved {{id}} become path parameters,
+// and the names of the folders holding a request become the tags of its endpoints. Requests without examples are
+// skipped, and when several examples share the same request the first one is served. The returned Conf has no
+// registration endpoint.
+func ImportPostman(collection []byte, options *PostmanOptions) (*Conf, error) {
+	if options == nil {
+		options = &PostmanOptions{}
+	}
+	doc := postmanCollection{}
+	if err := json.Unmarshal(collection, &doc); err != nil {
+		return nil, fmt.Errorf("Unable to parse Postman collection: %s", err)
+	}
+	if doc.Info == nil || doc.Item == nil {
+		return nil, ErrInvalidPostmanCollection
+	}
+
+	variables := map[string]string{}
+	for _, variable := range doc.Variable {
+		if !variable.Disabled {
+			variables[variable.Key] = postmanString(variable.Value)
+		}
+	}
+	for key, value := range options.Variables {
+		variables[key] = value
+	}
+
+	importer := &postmanImporter{options: options, variables: variables, conf: &Conf{Endpoints: []*MockEndpoint{}}}
+	if err := importer.items(doc.Item, nil); err != nil {
+		return nil, err
+	}
+	return importer.conf, nil
+}
+
+// postmanImporter walks the items of a collection and collects their mock endpoints
+type postmanImporter struct {
+	options   *PostmanOptions
+	variables map[string]string
+	conf      *Conf
+}
+
+func (p *postmanImporter) items(items []postmanItem, folders []string) error {
+	for _, item := range items {
+		if item.Request == nil {
+			if err := p.items(item.Item, append(append([]string{}, folders...), item.Name)); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, example := range item.Response {
+			request := example.OriginalRequest
+			if request == nil {
+				request = item.Request
+			}
+			endpoint, err := p.endpoint(request, &example)
+			if err != nil {
+				return fmt.Errorf("Unable to import example %q of %q: %s", example.Name, item.Name, err)
+			}
+			if len(folders) > 0 {
+				endpoint.Tags = append([]string{}, folders...)
+			}
+			if !containsRoute(p.conf.Endpoints, endpoint) {
+				p.conf.Endpoints = append(p.conf.Endpoints, endpoint)
+			}
+		}
+	}
+	return nil
+}
+
+// endpoint builds the mock endpoint of an example response and the request it was saved for
+func (p *postmanImporter) endpoint(request *postmanRequest, example *postmanResponse) (*MockEndpoint, error) {
+	method := strings.ToUpper(request.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	statusCode := example.Code
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	endpoint := &MockEndpoint{
+		Method:          method,
+		Endpoint:        p.path(&request.URL),
+		StatusCode:      statusCode,
+		ResponseBody:    p.resolve(example.Body),
+		ResponseHeaders: map[string]string{},
+	}
+	if !validPathTemplate(endpoint.Endpoint) {
+		return nil, fmt.Errorf("unsupported path %s", endpoint.Endpoint)
+	}
+
+	for key, value := range p.query(&request.URL) {
+		if postmanVariablePattern.MatchString(value) {
+			continue
+		}
+		if endpoint.QueryParameters == nil {
+			endpoint.QueryParameters = map[string]string{}
+		}
+		endpoint.QueryParameters[key] = value
+	}
+
+	for _, name := range p.options.RequestHeaders {
+		if value := p.header(request, name); value != "" && !postmanVariablePattern.MatchString(value) {
+			if endpoint.RequestHeaders == nil {
+				endpoint.RequestHeaders = map[string]string{}
+			}
+			endpoint.RequestHeaders[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+
+	if body := request.Body; p.options.MatchBody && body != nil && body.Mode == "raw" && body.Raw != "" {
+		raw := p.resolve(body.Raw)
+		mediaType, _, _ := mime.ParseMediaType(p.header(request, "Content-Type"))
+		if (body.Options.Raw.Language == "json" || isJSONMediaType(mediaType)) && json.Valid([]byte(raw)) {
+			endpoint.BodyMatchers = []BodyMatcher{{EqualToJSON: json.RawMessage(raw)}}
+		} else {
+			endpoint.RequestBody = raw
+		}
+	}
+
+	for _, header := range example.Header {
+		if header.Disabled || containsString(encodingHeaders, http.CanonicalHeaderKey(header.Key)) {
+			continue
+		}
+		endpoint.ResponseHeaders[http.CanonicalHeaderKey(header.Key)] = p.resolve(postmanString(header.Value))
+	}
+	if _, ok := endpoint.ResponseHeaders["Content-Type"]; !ok && postmanPreviewTypes[example.PreviewLanguage] != "" {
+		endpoint.ResponseHeaders["Content-Type"] = postmanPreviewTypes[example.PreviewLanguage]
+	}
+	return endpoint, nil
+}
+
+// header returns the resolved value of an enabled request header
+func (p *postmanImporter) header(request *postmanRequest, name string) string {
+	for _, header := range request.Header {
+		if !header.Disabled && strings.EqualFold(header.Key, name) {
+			return p.resolve(postmanString(header.Value))
+		}
+	}
+	return ""
+}
+
+// path resolves the path of a request URL into a path template, preferring the raw URL whose host variable may hold a
+// base path
+func (p *postmanImporter) path(u *postmanURL) string {
+	segments := []string{}
+	if raw := p.resolve(u.Raw); raw != "" {
+		if i := strings.IndexAny(raw, "?#"); i >= 0 {
+			raw = raw[:i]
+		}
+		if i := strings.Index(raw, "://"); i >= 0 {
+			raw = raw[i+3:]
+		}
+		if i := strings.Index(raw, "/"); i >= 0 {
+			segments = strings.Split(raw[i:], "/")
+		}
+	} else if path, ok := u.Path.(string); ok {
+		segments = strings.Split(path, "/")
+	} else if path, ok := u.Path.([]interface{}); ok {
+		for _, segment := range path {
+			segments = append(segments, postmanString(segment))
+		}
+	}
+
+	resolved := []string{}
+	for _, segment := range segments {
+		segment = p.resolve(segment)
+		if strings.HasPrefix(segment, ":") {
+			segment = "{" + segment[1:] + "}"
+		}
+		segment = postmanVariablePattern.ReplaceAllString(segment, "{$1}")
+		for _, part := range strings.Split(strings.Trim(segment, "/"), "/") {
+			if part != "" {
+				resolved = append(resolved, part)
+			}
+		}
+	}
+	return "/" + strings.Join(resolved, "/")
+}
+
+// query returns the enabled query parameters of a request URL with their variables resolved
+func (p *postmanImporter) query(u *postmanURL) map[string]string {
+	query := map[string]string{}
+	if u.Query != nil {
+		for _, param := range u.Query {
+			if !param.Disabled && param.Key != "" {
+				query[p.resolve(param.Key)] = p.resolve(postmanString(param.Value))
+			}
+		}
+		return query
+	}
+	raw := p.resolve(u.Raw)
+	if i := strings.Index(raw, "?"); i >= 0 {
+		raw = strings.SplitN(raw[i+1:], "#", 2)[0]
+		values, _ := url.ParseQuery(raw)
+		for key, value := range values {
+			query[key] = value[0]
+		}
+	}
+	return query
+}
+
+// resolve replaces the {{variables}} of the text with their values, leaving unknown and dynamic variables as they are
+func (p *postmanImporter) resolve(text string) string {
+	for i := 0; i < postmanMaxResolveDepth && strings.Contains(text, "{{"); i++ {
+		resolved := postmanVariablePattern.ReplaceAllStringFunc(text, func(reference string) string {
+			name := postmanVariablePattern.FindStringSubmatch(reference)[1]
+			if value, ok := p.variables[name]; ok {
+				return value
+			}
+			return reference
+		})
+		if resolved == text {
+			break
+		}
+		text = resolved
+	}
+	return text
+}
+
+// postmanString formats a variable or header value, which collections may store as JSON numbers or booleans
+func postmanString(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
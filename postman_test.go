@@ -0,0 +1,158 @@
+package mockservice_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/wchan2/mock_service"
+)
+
+const partnerCollection = `{
+	"info": {"name": "Partner API", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+	"variable": [
+		{"key": "baseUrl", "value": "https://partner.example.com/{{version}}"},
+		{"key": "version", "value": "v1"},
+		{"key": "token", "value": "secret"},
+		{"key": "limit", "value": 10}
+	],
+	"item": [
+		{
+			"name": "Users",
+			"item": [
+				{
+					"name": "Get user",
+					"request": {"method": "GET", "url": {"raw": "{{baseUrl}}/users/:id", "host": ["{{baseUrl}}"], "path": ["users", ":id"]}},
+					"response": [
+						{
+							"name": "Found",
+							"originalRequest": {
+								"method": "GET",
+								"header": [{"key": "Authorization", "value": "Bearer {{token}}"}, {"key": "X-Debug", "value": "1", "disabled": true}],
+								"url": {"raw": "{{baseUrl}}/users/:id?limit={{limit}}&cursor={{cursor}}", "query": [{"key": "limit", "value": "{{limit}}"}, {"key": "cursor", "value": "{{cursor}}"}, {"key": "debug", "value": "1", "disabled": true}]}
+							},
+							"code": 200,
+							"header": [{"key": "Content-Length", "value": "15"}, {"key": "x-version", "value": "{{version}}"}],
+							"_postman_previewlanguage": "json",
+							"body": "{\"name\": \"ada\"}"
+						},
+						{
+							"name": "Also found",
+							"originalRequest": {"method": "GET", "url": "{{baseUrl}}/users/:id?limit=10", "header": [{"key": "Authorization", "value": "Bearer secret"}]},
+							"code": 203,
+							"body": "ignored"
+						}
+					]
+				},
+				{
+					"name": "Admin",
+					"item": [
+						{
+							"name": "Create user",
+							"request": {
+								"method": "POST",
+								"url": "{{baseUrl}}/users",
+								"body": {"mode": "raw", "raw": "{\"name\": \"{{name}}\"}", "options": {"raw": {"language": "json"}}}
+							},
+							"response": [{"name": "Created", "code": 201, "header": [{"key": "Content-Type", "value": "application/json"}], "body": "{}"}]
+						}
+					]
+				}
+			]
+		},
+		{"name": "Health", "request": {"method": "GET", "url": "{{baseUrl}}/health"}, "response": []},
+		{"name": "Files", "request": {"method": "GET", "url": "{{host}}/files/{{file}}"}, "response": [{"name": "File", "code": 200, "body": "text"}]}
+	]
+}`
+
+func TestImportPostman(t *testing.T) {
+	conf, err := mockservice.ImportPostman([]byte(partnerCollection), &mockservice.PostmanOptions{
+		Variables:      map[string]string{"name": "grace"},
+		RequestHeaders: []string{"Authorization"},
+		MatchBody:      true,
+	})
+	if err != nil {
+		t.Fatalf("Expected importing the Postman collection to succeed but got %s", err)
+	}
+
+	expected := []*mockservice.MockEndpoint{
+		{
+			Method:          http.MethodGet,
+			Endpoint:        "/v1/users/{id}",
+			StatusCode:      http.StatusOK,
+			ResponseBody:    `{"name": "ada"}`,
+			ResponseHeaders: map[string]string{"Content-Type": "application/json", "X-Version": "v1"},
+			RequestHeaders:  map[string]string{"Authorization": "Bearer secret"},
+			QueryParameters: map[string]string{"limit": "10"},
+			Tags:            []string{"Users"},
+		},
+		{
+			Method:          http.MethodPost,
+			Endpoint:        "/v1/users",
+			StatusCode:      http.StatusCreated,
+			ResponseBody:    "{}",
+			ResponseHeaders: map[string]string{"Content-Type": "application/json"},
+			BodyMatchers:    []mockservice.BodyMatcher{{EqualToJSON: json.RawMessage(`{"name": "grace"}`)}},
+			Tags:            []string{"Users", "Admin"},
+		},
+		{
+			Method:          http.MethodGet,
+			Endpoint:        "/files/{file}",
+			StatusCode:      http.StatusOK,
+			ResponseBody:    "text",
+			ResponseHeaders: map[string]string{},
+		},
+	}
+	if !reflect.DeepEqual(conf.Endpoints, expected) {
+		got, _ := json.Marshal(conf.Endpoints)
+		t.Errorf("Expected the examples of the collection but got %s", got)
+	}
+}
+
+func TestImportPostman_Errors(t *testing.T) {
+	if _, err := mockservice.ImportPostman([]byte(`{"info":`), nil); err == nil {
+		t.Errorf("Expected importing invalid JSON to fail")
+	}
+	if _, err := mockservice.ImportPostman([]byte(`{"openapi": "3.0.0"}`), nil); err != mockservice.ErrInvalidPostmanCollection {
+		t.Errorf("Expected %s but got %v", mockservice.ErrInvalidPostmanCollection, err)
+	}
+	collection := `{"info": {"name": "Bad"}, "item": [{"name": "Bad", "request": {"url": "http://a/{b"}, "response": [{"code": 200}]}]}`
+	if _, err := mockservice.ImportPostman([]byte(collection), nil); err == nil {
+		t.Errorf("Expected importing an unsupported path to fail")
+	}
+}
+
+func TestAdminService_ImportPostman(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+
+	recorder := serve(service, http.MethodPost, "/mocks/postman?variable=version=v2&variable=token=test&header=Authorization", partnerCollection)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected %d status but got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/users/7?limit=10", nil)
+	req.Header.Set("Authorization", "Bearer test")
+	recorder = httptest.NewRecorder()
+	service.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK || recorder.Body.String() != `{"name": "ada"}` {
+		t.Errorf("Expected the example response but got %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	endpoints := []*mockservice.MockEndpoint{}
+	recorder = serve(service, http.MethodGet, "/mocks?tag=Users&tag=Admin", "")
+	if err := json.Unmarshal(recorder.Body.Bytes(), &endpoints); err != nil || len(endpoints) != 1 || endpoints[0].Method != http.MethodPost {
+		t.Errorf("Expected only the endpoint tagged Users and Admin but got %s", recorder.Body.String())
+	}
+
+	if recorder := serve(service, http.MethodPost, "/mocks/postman?variable=version", partnerCollection); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected %d status for an invalid variable but got %d", http.StatusBadRequest, recorder.Code)
+	}
+	if recorder := serve(service, http.MethodPost, "/mocks/postman", `{}`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected %d status for an invalid collection but got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
@@ -0,0 +1,834 @@
+package mockservice
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// protobuf field types from google/protobuf/descriptor.proto
+const (
+	typeDouble   = 1
+	typeFloat    = 2
+	typeInt64    = 3
+	typeUint64   = 4
+	typeInt32    = 5
+	typeFixed64  = 6
+	typeFixed32  = 7
+	typeBool     = 8
+	typeString   = 9
+	typeGroup    = 10
+	typeMessage  = 11
+	typeBytes    = 12
+	typeUint32   = 13
+	typeEnum     = 14
+	typeSfixed32 = 15
+	typeSfixed64 = 16
+	typeSint32   = 17
+	typeSint64   = 18
+
+	labelRepeated = 3
+)
+
+var errTruncatedProtobuf = errors.New("truncated protobuf message")
+
+// protoMessage describes a protobuf message type
+type protoMessage struct {
+	fullName string
+	proto3   bool
+	mapEntry bool
+	fields   []*protoField
+}
+
+// protoField describes a field of a protobuf message type
+type protoField struct {
+	name     string
+	jsonName string
+	number   uint64
+	label    uint64
+	typ      uint64
+	typeName string
+	message  *protoMessage
+	enum     *protoEnum
+}
+
+// protoEnum describes a protobuf enum type
+type protoEnum struct {
+	fullName string
+	names    map[int32]string
+	numbers  map[string]int32
+}
+
+// protoMethod describes a method of a protobuf service
+type protoMethod struct {
+	fullName        string
+	input           *protoMessage
+	output          *protoMessage
+	clientStreaming bool
+	serverStreaming bool
+}
+
+func (f *protoField) repeated() bool {
+	return f.label == labelRepeated
+}
+
+func (m *protoMessage) fieldByNumber(number uint64) *protoField {
+	for _, field := range m.fields {
+		if field.number == number {
+			return field
+		}
+	}
+	return nil
+}
+
+func (m *protoMessage) fieldByName(name string) *protoField {
+	for _, field := range m.fields {
+		if field.jsonName == name || field.name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// protoReader reads protobuf wire format
+type protoReader struct {
+	data []byte
+}
+
+func (r *protoReader) done() bool {
+	return len(r.data) == 0
+}
+
+func (r *protoReader) varint() (uint64, error) {
+	val, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		return 0, errTruncatedProtobuf
+	}
+	r.data = r.data[n:]
+	return val, nil
+}
+
+func (r *protoReader) fixed(size int) ([]byte, error) {
+	if len(r.data) < size {
+		return nil, errTruncatedProtobuf
+	}
+	val := r.data[:size]
+	r.data = r.data[size:]
+	return val, nil
+}
+
+func (r *protoReader) bytes() ([]byte, error) {
+	length, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(r.data)) < length {
+		return nil, errTruncatedProtobuf
+	}
+	return r.fixed(int(length))
+}
+
+// next reads the next field, returning its number, wire type and raw value; varints and fixed values are returned as their integer value
+func (r *protoReader) next() (number uint64, wireType int, raw []byte, val uint64, err error) {
+	tag, err := r.varint()
+	if err != nil {
+		return
+	}
+	number, wireType = tag>>3, int(tag&7)
+	switch wireType {
+	case wireVarint:
+		val, err = r.varint()
+	case wireFixed64:
+		raw, err = r.fixed(8)
+		if err == nil {
+			val = binary.LittleEndian.Uint64(raw)
+		}
+	case wireFixed32:
+		raw, err = r.fixed(4)
+		if err == nil {
+			val = uint64(binary.LittleEndian.Uint32(raw))
+		}
+	case wireBytes:
+		raw, err = r.bytes()
+	default:
+		err = fmt.Errorf("unsupported protobuf wire type %d", wireType)
+	}
+	return
+}
+
+// protoWriter writes protobuf wire format
+type protoWriter struct {
+	data []byte
+}
+
+func (w *protoWriter) varint(val uint64) {
+	w.data = binary.AppendUvarint(w.data, val)
+}
+
+func (w *protoWriter) tag(number uint64, wireType int) {
+	w.varint(number<<3 | uint64(wireType))
+}
+
+func (w *protoWriter) bytes(number uint64, val []byte) {
+	w.tag(number, wireBytes)
+	w.varint(uint64(len(val)))
+	w.data = append(w.data, val...)
+}
+
+// descriptorRegistry holds the message types and service methods of loaded FileDescriptorSets
+type descriptorRegistry struct {
+	messages map[string]*protoMessage
+	enums    map[string]*protoEnum
+	methods  map[string]*protoMethod
+	sync.RWMutex
+}
+
+func newDescriptorRegistry() *descriptorRegistry {
+	return &descriptorRegistry{
+		messages: map[string]*protoMessage{},
+		enums:    map[string]*protoEnum{},
+		methods:  map[string]*protoMethod{},
+	}
+}
+
+// pendingMethod is a method whose input and output types are resolved once all files are loaded
+type pendingMethod struct {
+	method             *protoMethod
+	inputType, outType string
+}
+
+// load adds the types and methods of a serialized google.protobuf.FileDescriptorSet
+func (d *descriptorRegistry) load(data []byte) error {
+	d.Lock()
+	defer d.Unlock()
+	methods := []pendingMethod{}
+	r := &protoReader{data: data}
+	for !r.done() {
+		number, wireType, raw, _, err := r.next()
+		if err != nil {
+			return err
+		}
+		if number == 1 && wireType == wireBytes {
+			fileMethods, err := d.loadFile(raw)
+			if err != nil {
+				return err
+			}
+			methods = append(methods, fileMethods...)
+		}
+	}
+
+	for _, message := range d.messages {
+		for _, field := range message.fields {
+			if err := d.resolve(field); err != nil {
+				return err
+			}
+		}
+	}
+	for _, pending := range methods {
+		input, output := d.messages[strings.TrimPrefix(pending.inputType, ".")], d.messages[strings.TrimPrefix(pending.outType, ".")]
+		if input == nil || output == nil {
+			return fmt.Errorf("unable to resolve the types of method %s", pending.method.fullName)
+		}
+		pending.method.input, pending.method.output = input, output
+		d.methods[pending.method.fullName] = pending.method
+	}
+	return nil
+}
+
+// method returns the service method with the fully-qualified name, such as "helloworld.Greeter/SayHello"
+func (d *descriptorRegistry) method(name string) *protoMethod {
+	d.RLock()
+	defer d.RUnlock()
+	return d.methods[name]
+}
+
+func (d *descriptorRegistry) resolve(field *protoField) error {
+	name := strings.TrimPrefix(field.typeName, ".")
+	switch field.typ {
+	case typeMessage, typeGroup:
+		if field.message = d.messages[name]; field.message == nil {
+			return fmt.Errorf("unable to resolve message type %s", field.typeName)
+		}
+	case typeEnum:
+		if field.enum = d.enums[name]; field.enum == nil {
+			return fmt.Errorf("unable to resolve enum type %s", field.typeName)
+		}
+	}
+	return nil
+}
+
+// loadFile adds the types of a google.protobuf.FileDescriptorProto and returns its unresolved methods
+func (d *descriptorRegistry) loadFile(data []byte) ([]pendingMethod, error) {
+	pkg, proto3 := "", false
+	messages, enums, services := [][]byte{}, [][]byte{}, [][]byte{}
+	r := &protoReader{data: data}
+	for !r.done() {
+		number, _, raw, _, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		switch number {
+		case 2:
+			pkg = string(raw)
+		case 4:
+			messages = append(messages, raw)
+		case 5:
+			enums = append(enums, raw)
+		case 6:
+			services = append(services, raw)
+		case 12:
+			proto3 = string(raw) == "proto3"
+		}
+	}
+
+	prefix := ""
+	if pkg != "" {
+		prefix = pkg + "."
+	}
+	for _, message := range messages {
+		if err := d.loadMessage(prefix, message, proto3); err != nil {
+			return nil, err
+		}
+	}
+	for _, enum := range enums {
+		if err := d.loadEnum(prefix, enum); err != nil {
+			return nil, err
+		}
+	}
+	methods := []pendingMethod{}
+	for _, service := range services {
+		serviceMethods, err := loadService(prefix, service)
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, serviceMethods...)
+	}
+	return methods, nil
+}
+
+// loadMessage adds a google.protobuf.DescriptorProto and its nested types
+func (d *descriptorRegistry) loadMessage(prefix string, data []byte, proto3 bool) error {
+	message := &protoMessage{proto3: proto3}
+	nested, enums := [][]byte{}, [][]byte{}
+	r := &protoReader{data: data}
+	for !r.done() {
+		number, _, raw, _, err := r.next()
+		if err != nil {
+			return err
+		}
+		switch number {
+		case 1:
+			message.fullName = prefix + string(raw)
+		case 2:
+			field, err := parseField(raw)
+			if err != nil {
+				return err
+			}
+			message.fields = append(message.fields, field)
+		case 3:
+			nested = append(nested, raw)
+		case 4:
+			enums = append(enums, raw)
+		case 7:
+			options := &protoReader{data: raw}
+			for !options.done() {
+				optionNumber, _, _, val, err := options.next()
+				if err != nil {
+					return err
+				}
+				if optionNumber == 7 {
+					message.mapEntry = val != 0
+				}
+			}
+		}
+	}
+
+	d.messages[message.fullName] = message
+	for _, nestedMessage := range nested {
+		if err := d.loadMessage(message.fullName+".", nestedMessage, proto3); err != nil {
+			return err
+		}
+	}
+	for _, enum := range enums {
+		if err := d.loadEnum(message.fullName+".", enum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseField parses a google.protobuf.FieldDescriptorProto
+func parseField(data []byte) (*protoField, error) {
+	field := &protoField{}
+	r := &protoReader{data: data}
+	for !r.done() {
+		number, _, raw, val, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		switch number {
+		case 1:
+			field.name = string(raw)
+		case 3:
+			field.number = val
+		case 4:
+			field.label = val
+		case 5:
+			field.typ = val
+		case 6:
+			field.typeName = string(raw)
+		case 10:
+			field.jsonName = string(raw)
+		}
+	}
+	if field.jsonName == "" {
+		field.jsonName = jsonName(field.name)
+	}
+	return field, nil
+}
+
+// jsonName converts a snake_case field name to the lowerCamelCase name protoc assigns
+func jsonName(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, c := range name {
+		if c == '_' {
+			upper = true
+			continue
+		}
+		if upper && c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		upper = false
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// loadEnum adds a google.protobuf.EnumDescriptorProto
+func (d *descriptorRegistry) loadEnum(prefix string, data []byte) error {
+	enum := &protoEnum{names: map[int32]string{}, numbers: map[string]int32{}}
+	r := &protoReader{data: data}
+	for !r.done() {
+		number, _, raw, _, err := r.next()
+		if err != nil {
+			return err
+		}
+		switch number {
+		case 1:
+			enum.fullName = prefix + string(raw)
+		case 2:
+			name, value := "", int32(0)
+			values := &protoReader{data: raw}
+			for !values.done() {
+				valueNumber, _, valueRaw, val, err := values.next()
+				if err != nil {
+					return err
+				}
+				switch valueNumber {
+				case 1:
+					name = string(valueRaw)
+				case 2:
+					value = int32(val)
+				}
+			}
+			enum.names[value], enum.numbers[name] = name, value
+		}
+	}
+	d.enums[enum.fullName] = enum
+	return nil
+}
+
+// loadService parses the methods of a google.protobuf.ServiceDescriptorProto
+func loadService(prefix string, data []byte) ([]pendingMethod, error) {
+	serviceName, methods := "", []pendingMethod{}
+	r := &protoReader{data: data}
+	for !r.done() {
+		number, _, raw, _, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		switch number {
+		case 1:
+			serviceName = prefix + string(raw)
+		case 2:
+			pending := pendingMethod{method: &protoMethod{}}
+			methodReader := &protoReader{data: raw}
+			for !methodReader.done() {
+				methodNumber, _, methodRaw, val, err := methodReader.next()
+				if err != nil {
+					return nil, err
+				}
+				switch methodNumber {
+				case 1:
+					pending.method.fullName = string(methodRaw)
+				case 2:
+					pending.inputType = string(methodRaw)
+				case 3:
+					pending.outType = string(methodRaw)
+				case 5:
+					pending.method.clientStreaming = val != 0
+				case 6:
+					pending.method.serverStreaming = val != 0
+				}
+			}
+			methods = append(methods, pending)
+		}
+	}
+	for i := range methods {
+		methods[i].method.fullName = serviceName + "/" + methods[i].method.fullName
+	}
+	return methods, nil
+}
+
+// protoToJSON renders a serialized protobuf message as JSON following the proto3 JSON mapping
+func protoToJSON(message *protoMessage, data []byte) ([]byte, error) {
+	val, err := decodeMessage(message, data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(val)
+}
+
+func decodeMessage(message *protoMessage, data []byte) (map[string]interface{}, error) {
+	obj := map[string]interface{}{}
+	r := &protoReader{data: data}
+	for !r.done() {
+		number, wireType, raw, val, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		field := message.fieldByNumber(number)
+		if field == nil {
+			continue
+		}
+
+		vals := []interface{}{}
+		if wireType == wireBytes && field.typ != typeString && field.typ != typeBytes && field.typ != typeMessage {
+			packed := &protoReader{data: raw}
+			for !packed.done() {
+				packedVal, err := readPacked(packed, field.typ)
+				if err != nil {
+					return nil, err
+				}
+				vals = append(vals, decodeScalar(field, nil, packedVal))
+			}
+		} else if field.typ == typeMessage {
+			nested, err := decodeMessage(field.message, raw)
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, nested)
+		} else {
+			vals = append(vals, decodeScalar(field, raw, val))
+		}
+
+		if field.message != nil && field.message.mapEntry {
+			entries, _ := obj[field.jsonName].(map[string]interface{})
+			if entries == nil {
+				entries = map[string]interface{}{}
+			}
+			for _, entry := range vals {
+				entryObj := entry.(map[string]interface{})
+				entries[fmt.Sprint(entryObj["key"])] = entryObj["value"]
+			}
+			obj[field.jsonName] = entries
+		} else if field.repeated() {
+			list, _ := obj[field.jsonName].([]interface{})
+			obj[field.jsonName] = append(list, vals...)
+		} else {
+			obj[field.jsonName] = vals[len(vals)-1]
+		}
+	}
+	return obj, nil
+}
+
+func readPacked(r *protoReader, typ uint64) (uint64, error) {
+	switch typ {
+	case typeDouble, typeFixed64, typeSfixed64:
+		raw, err := r.fixed(8)
+		if err != nil {
+			return 0, err
+		}
+		return binary.LittleEndian.Uint64(raw), nil
+	case typeFloat, typeFixed32, typeSfixed32:
+		raw, err := r.fixed(4)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.LittleEndian.Uint32(raw)), nil
+	}
+	return r.varint()
+}
+
+func decodeScalar(field *protoField, raw []byte, val uint64) interface{} {
+	switch field.typ {
+	case typeDouble:
+		return jsonFloat(math.Float64frombits(val))
+	case typeFloat:
+		return jsonFloat(float64(math.Float32frombits(uint32(val))))
+	case typeInt64, typeSfixed64:
+		return strconv.FormatInt(int64(val), 10)
+	case typeUint64, typeFixed64:
+		return strconv.FormatUint(val, 10)
+	case typeSint64:
+		return strconv.FormatInt(int64(val>>1)^-int64(val&1), 10)
+	case typeInt32, typeSfixed32:
+		return int32(val)
+	case typeSint32:
+		return int32(val>>1) ^ -int32(val&1)
+	case typeUint32, typeFixed32:
+		return uint32(val)
+	case typeBool:
+		return val != 0
+	case typeString:
+		return string(raw)
+	case typeBytes:
+		return base64.StdEncoding.EncodeToString(raw)
+	case typeEnum:
+		if name, ok := field.enum.names[int32(val)]; ok {
+			return name
+		}
+		return int32(val)
+	}
+	return nil
+}
+
+func jsonFloat(f float64) interface{} {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return f
+}
+
+// jsonToProto serializes a JSON object as a protobuf message following the proto3 JSON mapping
+func jsonToProto(message *protoMessage, data []byte) ([]byte, error) {
+	obj := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("%s must be a JSON object: %s", message.fullName, err)
+	}
+	w := &protoWriter{}
+	if err := encodeMessage(w, message, obj); err != nil {
+		return nil, err
+	}
+	return w.data, nil
+}
+
+func encodeMessage(w *protoWriter, message *protoMessage, obj map[string]json.RawMessage) error {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		raw := obj[key]
+		field := message.fieldByName(key)
+		if field == nil {
+			return fmt.Errorf("%s has no field %s", message.fullName, key)
+		}
+		if string(raw) == "null" {
+			continue
+		}
+
+		switch {
+		case field.message != nil && field.message.mapEntry:
+			entries := map[string]json.RawMessage{}
+			if err := json.Unmarshal(raw, &entries); err != nil {
+				return fmt.Errorf("%s.%s must be a JSON object: %s", message.fullName, key, err)
+			}
+			entryKeys := make([]string, 0, len(entries))
+			for entryKey := range entries {
+				entryKeys = append(entryKeys, entryKey)
+			}
+			sort.Strings(entryKeys)
+			for _, entryKey := range entryKeys {
+				keyJSON, _ := json.Marshal(entryKey)
+				entry := &protoWriter{}
+				if err := encodeMessage(entry, field.message, map[string]json.RawMessage{"key": keyJSON, "value": entries[entryKey]}); err != nil {
+					return err
+				}
+				w.bytes(field.number, entry.data)
+			}
+		case field.repeated():
+			list := []json.RawMessage{}
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return fmt.Errorf("%s.%s must be a JSON array: %s", message.fullName, key, err)
+			}
+			packed := message.proto3 && field.typ != typeString && field.typ != typeBytes && field.typ != typeMessage
+			packedWriter := &protoWriter{}
+			for _, item := range list {
+				if packed {
+					if err := encodeValue(packedWriter, field, item, false); err != nil {
+						return err
+					}
+				} else if err := encodeValue(w, field, item, true); err != nil {
+					return err
+				}
+			}
+			if packed && len(list) > 0 {
+				w.bytes(field.number, packedWriter.data)
+			}
+		default:
+			if err := encodeValue(w, field, raw, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// encodeValue writes a single JSON value of the field, preceded by the field's tag unless it is part of a packed list
+func encodeValue(w *protoWriter, field *protoField, raw json.RawMessage, tagged bool) error {
+	invalid := func(err error) error {
+		return fmt.Errorf("invalid value %s for field %s: %v", raw, field.name, err)
+	}
+
+	switch field.typ {
+	case typeMessage:
+		obj := map[string]json.RawMessage{}
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return invalid(err)
+		}
+		nested := &protoWriter{}
+		if err := encodeMessage(nested, field.message, obj); err != nil {
+			return err
+		}
+		w.bytes(field.number, nested.data)
+		return nil
+	case typeString:
+		s := ""
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return invalid(err)
+		}
+		w.bytes(field.number, []byte(s))
+		return nil
+	case typeBytes:
+		s := ""
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return invalid(err)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			if decoded, err = base64.URLEncoding.DecodeString(s); err != nil {
+				return invalid(err)
+			}
+		}
+		w.bytes(field.number, decoded)
+		return nil
+	}
+
+	wireType, val, err := scalarValue(field, raw)
+	if err != nil {
+		return invalid(err)
+	}
+	if tagged {
+		w.tag(field.number, wireType)
+	}
+	switch wireType {
+	case wireFixed64:
+		w.data = binary.LittleEndian.AppendUint64(w.data, val)
+	case wireFixed32:
+		w.data = binary.LittleEndian.AppendUint32(w.data, uint32(val))
+	default:
+		w.varint(val)
+	}
+	return nil
+}
+
+// scalarValue converts a JSON number, numeric string, boolean or enum name to its wire type and value
+func scalarValue(field *protoField, raw json.RawMessage) (int, uint64, error) {
+	text := strings.Trim(string(raw), `"`)
+	switch field.typ {
+	case typeBool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return 0, 0, err
+		}
+		if b {
+			return wireVarint, 1, nil
+		}
+		return wireVarint, 0, nil
+	case typeEnum:
+		if number, ok := field.enum.numbers[text]; ok {
+			return wireVarint, uint64(int64(number)), nil
+		}
+		number, err := strconv.ParseInt(text, 10, 32)
+		return wireVarint, uint64(number), err
+	case typeDouble, typeFloat:
+		f, err := parseJSONFloat(text)
+		if err != nil {
+			return 0, 0, err
+		}
+		if field.typ == typeFloat {
+			return wireFixed32, uint64(math.Float32bits(float32(f))), nil
+		}
+		return wireFixed64, math.Float64bits(f), nil
+	case typeUint64, typeFixed64, typeUint32, typeFixed32:
+		n, err := strconv.ParseUint(text, 10, 64)
+		if err != nil {
+			f, ferr := strconv.ParseFloat(text, 64)
+			if ferr != nil || f != math.Trunc(f) || f < 0 {
+				return 0, 0, err
+			}
+			n = uint64(f)
+		}
+		switch field.typ {
+		case typeFixed64:
+			return wireFixed64, n, nil
+		case typeFixed32:
+			return wireFixed32, n, nil
+		}
+		return wireVarint, n, nil
+	}
+
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		f, ferr := strconv.ParseFloat(text, 64)
+		if ferr != nil || f != math.Trunc(f) {
+			return 0, 0, err
+		}
+		n = int64(f)
+	}
+	switch field.typ {
+	case typeSint32, typeSint64:
+		return wireVarint, uint64(n<<1) ^ uint64(n>>63), nil
+	case typeSfixed64:
+		return wireFixed64, uint64(n), nil
+	case typeSfixed32:
+		return wireFixed32, uint64(uint32(int32(n))), nil
+	}
+	return wireVarint, uint64(n), nil
+}
+
+func parseJSONFloat(text string) (float64, error) {
+	switch text {
+	case "NaN":
+		return math.NaN(), nil
+	case "Infinity":
+		return math.Inf(1), nil
+	case "-Infinity":
+		return math.Inf(-1), nil
+	}
+	return strconv.ParseFloat(text, 64)
+}
@@ -10,15 +10,17 @@ import (
 
 // RegistrationService allows endpoints to be registered
 type RegistrationService struct {
-	mockedEndpoints *Endpoints
+	mockedEndpoints Store
+	// registrationEndpoint is the path of the registration endpoint, which registered endpoints must not conflict with
+	registrationEndpoint string
 }
 
 // NewRegistrationService creates a registration service to support the registering of mock endpoints through HTTP
-func NewRegistrationService(endpoints *Endpoints) *RegistrationService {
+func NewRegistrationService(endpoints Store) *RegistrationService {
 	return &RegistrationService{mockedEndpoints: endpoints}
 }
 
-// ServeHTTP creates mock endpoints via HTTP requests
+// ServeHTTP creates mock endpoints via HTTP requests, or updates existing ones for PUT requests
 func (m *RegistrationService) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	if req.Body == nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -41,13 +43,26 @@ func (m *RegistrationService) ServeHTTP(w http.ResponseWriter, req *http.Request
 		return
 	}
 
-	if err := m.mockedEndpoints.Create(&endpointRequest); err != nil {
+	if err := registrationConflict(m.registrationEndpoint, &endpointRequest); err != nil {
+		log.Printf("Validation error %s: %s", reqPayload, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	save, status := m.mockedEndpoints.Create, http.StatusCreated
+	if req.Method == http.MethodPut {
+		save, status = m.mockedEndpoints.Update, http.StatusOK
+	}
+	if err := save(&endpointRequest); err != nil {
 		switch err {
-		case ErrEmptyEndpoint, ErrEmptyHTTPMethod:
+		case ErrEndpointDoesNotExist:
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		default:
 			log.Printf("Validation error %s: %s", reqPayload, err)
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 	}
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(status)
 }
@@ -0,0 +1,204 @@
+package mockservice
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ErrInvalidRequestSchema is returned when attempting to add a mock endpoint whose request schemas are not JSON objects
+// or whose parameters have no name or an unknown location
+var ErrInvalidRequestSchema = errors.New("Invalid request schema provided")
+
+// RequestSchema validates the requests sent to an endpoint before they are matched, so that requests breaking the
+// contract of an OpenAPI operation are answered with 400 Bad Request and the list of violations
+type RequestSchema struct {
+	// Parameters are the path, query, header and cookie parameters of the operation
+	Parameters []SchemaParameter `json:"parameters,omitempty" xml:"parameters,omitempty"`
+	// ContentTypes are the media types accepted for the request body, such as "application/json" or "image/*"
+	ContentTypes []string `json:"contentTypes,omitempty" xml:"contentTypes,omitempty"`
+	// BodyRequired rejects requests without a body
+	BodyRequired bool `json:"bodyRequired,omitempty" xml:"bodyRequired,omitempty"`
+	// Body is the JSON schema of JSON request bodies
+	Body json.RawMessage `json:"body,omitempty" xml:"body,omitempty"`
+}
+
+// SchemaParameter describes a request parameter as declared by an OpenAPI operation
+type SchemaParameter struct {
+	Name string `json:"name" xml:"name"`
+	// In is the location of the parameter: "path", "query", "header" or "cookie"
+	In       string `json:"in" xml:"in"`
+	Required bool   `json:"required,omitempty" xml:"required,omitempty"`
+	// Explode reads array query parameters from repeated query parameters rather than a comma separated list
+	Explode bool `json:"explode,omitempty" xml:"explode,omitempty"`
+	// Schema is the JSON schema of the parameter value
+	Schema json.RawMessage `json:"schema,omitempty" xml:"schema,omitempty"`
+}
+
+// schemaViolations is the body of the response to a request breaking its request schema
+type schemaViolations struct {
+	Message    string            `json:"message"`
+	Violations []SchemaViolation `json:"violations"`
+}
+
+func (r *RequestSchema) validate() error {
+	for _, parameter := range r.Parameters {
+		switch parameter.In {
+		case "path", "query", "header", "cookie":
+		default:
+			return ErrInvalidRequestSchema
+		}
+		if parameter.Name == "" || !validSchema(parameter.Schema) {
+			return ErrInvalidRequestSchema
+		}
+	}
+	if !validSchema(r.Body) {
+		return ErrInvalidRequestSchema
+	}
+	return nil
+}
+
+func validSchema(schema json.RawMessage) bool {
+	if len(schema) == 0 {
+		return true
+	}
+	obj := map[string]interface{}{}
+	return decodeJSON(schema, &obj) == nil
+}
+
+// violations validates the request sent to the endpoint with the given path template against the schema
+func (r *RequestSchema) violations(req *http.Request, body []byte, template string) []SchemaViolation {
+	validator := &schemaValidator{request: true}
+	pathParameters := PathParameters(template, req.URL.Path)
+	query := req.URL.Query()
+	for _, parameter := range r.Parameters {
+		validator.in = parameter.In
+		var values []string
+		switch parameter.In {
+		case "path":
+			if value, ok := pathParameters[parameter.Name]; ok {
+				values = []string{value}
+			}
+		case "query":
+			values = query[parameter.Name]
+		case "header":
+			values = req.Header.Values(parameter.Name)
+		case "cookie":
+			if cookie, err := req.Cookie(parameter.Name); err == nil {
+				values = []string{cookie.Value}
+			}
+		}
+		if len(values) == 0 {
+			if parameter.Required || parameter.In == "path" {
+				validator.violation(parameter.Name, "is required")
+			}
+			continue
+		}
+
+		schema := map[string]interface{}{}
+		if len(parameter.Schema) > 0 {
+			decodeJSON(parameter.Schema, &schema)
+		}
+		validator.validate(schema, parameterValue(schema, values, parameter.Explode), parameter.Name)
+	}
+
+	validator.in = "body"
+	r.validateBody(validator, req, body)
+	return validator.violations
+}
+
+func (r *RequestSchema) validateBody(validator *schemaValidator, req *http.Request, body []byte) {
+	if len(body) == 0 {
+		if r.BodyRequired {
+			validator.violation("", "is required")
+		}
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if len(r.ContentTypes) > 0 && !acceptsMediaType(r.ContentTypes, mediaType) {
+		validator.in = "header"
+		validator.violation("Content-Type", "must be one of %s", strings.Join(r.ContentTypes, ", "))
+		return
+	}
+	if len(r.Body) == 0 || (mediaType != "" && !isJSONMediaType(mediaType)) {
+		return
+	}
+
+	var value interface{}
+	if err := decodeJSON(body, &value); err != nil {
+		validator.violation("", "must be valid JSON: %s", err)
+		return
+	}
+	schema := map[string]interface{}{}
+	decodeJSON(r.Body, &schema)
+	validator.validate(schema, value, "")
+}
+
+// acceptsMediaType reports whether the media type is one of the accepted media types, which may use wildcards
+func acceptsMediaType(accepted []string, mediaType string) bool {
+	for _, candidate := range accepted {
+		candidate = strings.ToLower(candidate)
+		if candidate == "*/*" || candidate == mediaType ||
+			(strings.HasSuffix(candidate, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(candidate, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// parameterValue converts the textual values of a parameter to the JSON value its schema describes, leaving values
+// that cannot be converted as strings for the schema to reject
+func parameterValue(schema map[string]interface{}, values []string, explode bool) interface{} {
+	if !schemaAllowsType(schema, "array") {
+		return parameterScalar(schema, values[0])
+	}
+	if !explode || len(values) == 1 {
+		values = strings.Split(values[0], ",")
+	}
+	items, _ := schema["items"].(map[string]interface{})
+	array := make([]interface{}, len(values))
+	for i, value := range values {
+		array[i] = parameterScalar(items, value)
+	}
+	return array
+}
+
+func parameterScalar(schema map[string]interface{}, value string) interface{} {
+	switch {
+	case schemaAllowsType(schema, "integer") || schemaAllowsType(schema, "number"):
+		var number interface{}
+		if decodeJSON([]byte(value), &number) == nil {
+			if _, ok := number.(json.Number); ok {
+				return number
+			}
+		}
+	case schemaAllowsType(schema, "boolean"):
+		if value == "true" || value == "false" {
+			return value == "true"
+		}
+	}
+	return value
+}
+
+// schemaRoute finds the endpoint of the store with a request schema that the request is routed to by its method and
+// path, preferring literal paths over path templates and then the most recently created endpoint
+func schemaRoute(store Store, req *http.Request) *MockEndpoint {
+	endpoints := store.Routes(req.Method)
+	var best *MockEndpoint
+	for i := len(endpoints) - 1; i >= 0; i-- {
+		candidate := endpoints[i]
+		if candidate.RequestSchema == nil || !matchPath(candidate.Endpoint, req.URL.Path) {
+			continue
+		}
+		if !isPathTemplate(candidate.Endpoint) {
+			return candidate
+		}
+		if best == nil {
+			best = candidate
+		}
+	}
+	return best
+}
@@ -0,0 +1,256 @@
+package mockservice_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wchan2/mock_service"
+)
+
+const validatedSpec = `openapi: 3.0.3
+paths:
+  /pets:
+    parameters:
+      - name: X-Request-ID
+        in: header
+        required: true
+        schema:
+          type: string
+          format: uuid
+    get:
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            minimum: 1
+            maximum: 100
+        - name: tag
+          in: query
+          schema:
+            type: array
+            maxItems: 2
+            items:
+              type: string
+              enum: [cat, dog]
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              example: []
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+      responses:
+        '201':
+          description: created
+  /pets/{id}:
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: integer
+            format: int32
+      responses:
+        '200':
+          description: ok
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [id, name]
+      additionalProperties: false
+      properties:
+        id:
+          type: integer
+          readOnly: true
+        name:
+          type: string
+          minLength: 1
+        born:
+          type: string
+          format: date
+          nullable: true
+        weight:
+          type: number
+          exclusiveMinimum: 0
+        parent:
+          $ref: '#/components/schemas/Pet'
+        kind:
+          oneOf:
+            - type: string
+              enum: [cat]
+            - type: string
+              pattern: ^d
+`
+
+func TestRequestSchema(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	if recorder := serve(service, http.MethodPost, "/mocks/openapi?validate=true", validatedSpec); recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected %d status but got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+	}
+
+	const requestID = "3f2504e0-4f89-11d3-9a0c-0305e82c3301"
+	cases := []struct {
+		name       string
+		method     string
+		url        string
+		headers    map[string]string
+		body       string
+		status     int
+		violations []mockservice.SchemaViolation
+	}{
+		{
+			"Valid_query",
+			http.MethodGet, "/pets?limit=10&tag=cat&tag=dog",
+			map[string]string{"X-Request-ID": requestID}, "",
+			http.StatusOK, nil,
+		},
+		{
+			"Invalid_query",
+			http.MethodGet, "/pets?limit=ten&tag=cat&tag=cow&tag=dog",
+			map[string]string{"X-Request-ID": requestID}, "",
+			http.StatusBadRequest,
+			[]mockservice.SchemaViolation{
+				{In: "query", Name: "limit", Message: "must be of type integer"},
+				{In: "query", Name: "tag", Message: "must have at most 2 items"},
+				{In: "query", Name: "tag/1", Message: `must be one of ["cat","dog"]`},
+			},
+		},
+		{
+			"Out_of_range_query",
+			http.MethodGet, "/pets?limit=0",
+			map[string]string{"X-Request-ID": requestID}, "",
+			http.StatusBadRequest,
+			[]mockservice.SchemaViolation{{In: "query", Name: "limit", Message: "must be at least 1"}},
+		},
+		{
+			"Missing_header",
+			http.MethodGet, "/pets", nil, "",
+			http.StatusBadRequest,
+			[]mockservice.SchemaViolation{{In: "header", Name: "X-Request-ID", Message: "is required"}},
+		},
+		{
+			"Invalid_header",
+			http.MethodGet, "/pets",
+			map[string]string{"X-Request-ID": "42"}, "",
+			http.StatusBadRequest,
+			[]mockservice.SchemaViolation{{In: "header", Name: "X-Request-ID", Message: "must be a valid uuid"}},
+		},
+		{
+			"Valid_path_parameter",
+			http.MethodGet, "/pets/7", nil, "",
+			http.StatusOK, nil,
+		},
+		{
+			"Invalid_path_parameter",
+			http.MethodGet, "/pets/3000000000", nil, "",
+			http.StatusBadRequest,
+			[]mockservice.SchemaViolation{{In: "path", Name: "id", Message: "must be a valid int32"}},
+		},
+		{
+			"Valid_body",
+			http.MethodPost, "/pets",
+			map[string]string{"X-Request-ID": requestID, "Content-Type": "application/json"},
+			`{"name": "Rex", "born": null, "weight": 3.5, "kind": "dog", "parent": {"name": "Max"}}`,
+			http.StatusCreated, nil,
+		},
+		{
+			"Invalid_body",
+			http.MethodPost, "/pets",
+			map[string]string{"X-Request-ID": requestID, "Content-Type": "application/json"},
+			`{"born": "yesterday", "weight": 0, "color": "brown", "kind": "cow", "parent": {"name": ""}}`,
+			http.StatusBadRequest,
+			[]mockservice.SchemaViolation{
+				{In: "body", Name: "/name", Message: "is required"},
+				{In: "body", Name: "/born", Message: "must be a valid date"},
+				{In: "body", Name: "/color", Message: "is not allowed"},
+				{In: "body", Name: "/kind", Message: "must match exactly one of the oneOf schemas but matches 0"},
+				{In: "body", Name: "/parent/name", Message: "must be at least 1 characters long"},
+				{In: "body", Name: "/weight", Message: "must be greater than 0"},
+			},
+		},
+		{
+			"Missing_body",
+			http.MethodPost, "/pets",
+			map[string]string{"X-Request-ID": requestID}, "",
+			http.StatusBadRequest,
+			[]mockservice.SchemaViolation{{In: "body", Message: "is required"}},
+		},
+		{
+			"Malformed_body",
+			http.MethodPost, "/pets",
+			map[string]string{"X-Request-ID": requestID, "Content-Type": "application/json"}, `{"name":`,
+			http.StatusBadRequest,
+			[]mockservice.SchemaViolation{{In: "body", Message: "must be valid JSON: unexpected EOF"}},
+		},
+		{
+			"Unsupported_content_type",
+			http.MethodPost, "/pets",
+			map[string]string{"X-Request-ID": requestID, "Content-Type": "text/plain"}, "Rex",
+			http.StatusBadRequest,
+			[]mockservice.SchemaViolation{{In: "header", Name: "Content-Type", Message: "must be one of application/json"}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.url, strings.NewReader(c.body))
+			for key, val := range c.headers {
+				req.Header.Set(key, val)
+			}
+			recorder := httptest.NewRecorder()
+			service.ServeHTTP(recorder, req)
+
+			if recorder.Code != c.status {
+				t.Fatalf("Expected status %d but got %d: %s", c.status, recorder.Code, recorder.Body.String())
+			}
+			if c.violations == nil {
+				return
+			}
+			response := struct {
+				Message    string                        `json:"message"`
+				Violations []mockservice.SchemaViolation `json:"violations"`
+			}{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Expected a JSON list of violations but got %s", recorder.Body.String())
+			}
+			if !reflect.DeepEqual(response.Violations, c.violations) {
+				t.Errorf("Expected violations %+v but got %+v", c.violations, response.Violations)
+			}
+		})
+	}
+}
+
+func TestRequestSchemaValidation(t *testing.T) {
+	cases := []struct {
+		name   string
+		schema *mockservice.RequestSchema
+	}{
+		{"Unknown_location", &mockservice.RequestSchema{Parameters: []mockservice.SchemaParameter{{Name: "id", In: "body"}}}},
+		{"Empty_name", &mockservice.RequestSchema{Parameters: []mockservice.SchemaParameter{{In: "query"}}}},
+		{"Invalid_body_schema", &mockservice.RequestSchema{Body: json.RawMessage(`[]`)}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := mockservice.NewEndpoints().Create(&mockservice.MockEndpoint{Method: http.MethodGet, Endpoint: "/pets", RequestSchema: c.schema})
+			if err != mockservice.ErrInvalidRequestSchema {
+				t.Errorf("Expected %s but got %v", mockservice.ErrInvalidRequestSchema, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package mockservice
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// writeResponse writes the mock response, streaming the body in chunks and sending trailers when configured. A mock
+// endpoint without a status code responds with 200 OK.
+func writeResponse(w http.ResponseWriter, req *http.Request, endpoint *MockEndpoint) {
+	push(w, endpoint.PushPromises)
+	for headerKey, headerVal := range endpoint.ResponseHeaders {
+		w.Header().Add(headerKey, headerVal)
+	}
+	trailerKeys := make([]string, 0, len(endpoint.ResponseTrailers))
+	for trailerKey := range endpoint.ResponseTrailers {
+		trailerKeys = append(trailerKeys, trailerKey)
+	}
+	sort.Strings(trailerKeys)
+	if len(trailerKeys) > 0 {
+		w.Header().Set("Trailer", strings.Join(trailerKeys, ", "))
+	}
+	statusCode := endpoint.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	w.WriteHeader(statusCode)
+
+	if endpoint.ChunkSize > 0 {
+		streamBody(w, req, endpoint)
+	} else {
+		fmt.Fprint(w, endpoint.ResponseBody)
+	}
+
+	for _, trailerKey := range trailerKeys {
+		w.Header().Set(trailerKey, endpoint.ResponseTrailers[trailerKey])
+	}
+}
+
+// streamBody writes the response body in chunks of the endpoint's chunk size, flushing each chunk and waiting for the
+// chunk delay in between, until the body is written or the client goes away
+func streamBody(w http.ResponseWriter, req *http.Request, endpoint *MockEndpoint) {
+	flusher, _ := w.(http.Flusher)
+	body := endpoint.ResponseBody
+	for len(body) > 0 {
+		size := endpoint.ChunkSize
+		if size > len(body) {
+			size = len(body)
+		}
+		if _, err := fmt.Fprint(w, body[:size]); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		body = body[size:]
+		if len(body) > 0 && endpoint.ChunkDelay > 0 {
+			select {
+			case <-time.After(time.Duration(endpoint.ChunkDelay)):
+			case <-req.Context().Done():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package mockservice_test
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/wchan2/mock_service"
+)
+
+func TestStreamingResponseWithTrailers(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	service.Endpoints().Create(mockservice.Get("/download").
+		WillReturn(http.StatusOK).
+		WithBody("abcdefghij").
+		InChunks(4, 30*time.Millisecond).
+		WithTrailer("X-Checksum", "sha256:1234").
+		MustBuild())
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL + "/download")
+	if err != nil {
+		t.Fatalf("Expected the request to succeed but got %s", err)
+	}
+	defer resp.Body.Close()
+
+	first := make([]byte, 4)
+	if _, err := io.ReadFull(resp.Body, first); err != nil || string(first) != "abcd" {
+		t.Fatalf(`Expected the first chunk "abcd" but got "%s" (%v)`, first, err)
+	}
+	firstChunkAt := time.Now()
+
+	rest, err := ioutil.ReadAll(resp.Body)
+	if err != nil || string(rest) != "efghij" {
+		t.Errorf(`Expected the remaining chunks "efghij" but got "%s" (%v)`, rest, err)
+	}
+
+	if elapsed := time.Since(firstChunkAt); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected the remaining chunks to be delayed by at least 50ms but they arrived after %s", elapsed)
+	}
+
+	if resp.Trailer.Get("X-Checksum") != "sha256:1234" {
+		t.Errorf("Expected the X-Checksum trailer but got %v", resp.Trailer)
+	}
+
+	if len(resp.TransferEncoding) != 1 || resp.TransferEncoding[0] != "chunked" {
+		t.Errorf("Expected a chunked response but got %v", resp.TransferEncoding)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	endpoint := mockservice.MockEndpoint{}
+	if err := json.Unmarshal([]byte(`{"chunkDelay": "250ms"}`), &endpoint); err != nil {
+		t.Fatalf("Expected unmarshaling the duration to succeed but got %s", err)
+	}
+
+	if time.Duration(endpoint.ChunkDelay) != 250*time.Millisecond {
+		t.Errorf("Expected a 250ms chunk delay but got %s", time.Duration(endpoint.ChunkDelay))
+	}
+
+	if err := json.Unmarshal([]byte(`{"chunkDelay": "soon"}`), &endpoint); err == nil {
+		t.Errorf("Expected an error when unmarshaling an invalid duration")
+	}
+}
@@ -0,0 +1,71 @@
+package mockservice
+
+import (
+	"errors"
+	"sort"
+)
+
+// ScenarioStarted is the state every scenario starts in
+const ScenarioStarted = "Started"
+
+// ErrMissingScenario is returned when adding a mock endpoint with a required or new scenario state but no scenario
+var ErrMissingScenario = errors.New("Scenario states require a scenario name")
+
+// ScenarioState is the current state of a scenario
+type ScenarioState struct {
+	Name  string `json:"name" xml:"name"`
+	State string `json:"state" xml:"state"`
+}
+
+// inState reports whether the scenario of the endpoint is in its required state. It must be called with the lock held.
+func (e *Endpoints) inState(endpoint *MockEndpoint) bool {
+	return endpoint.RequiredState == "" || e.scenarioState(endpoint.Scenario) == endpoint.RequiredState
+}
+
+// scenarioState returns the current state of the scenario. It must be called with the lock held.
+func (e *Endpoints) scenarioState(name string) string {
+	if state, ok := e.scenarios[name]; ok {
+		return state
+	}
+	return ScenarioStarted
+}
+
+// Scenarios lists the current states of the scenarios of the registered endpoints and of the scenarios set, sorted by name
+func (e *Endpoints) Scenarios() []ScenarioState {
+	e.Lock()
+	defer e.Unlock()
+	names := map[string]bool{}
+	for name := range e.scenarios {
+		names[name] = true
+	}
+	for _, endpoints := range e.endpoints {
+		for _, endpoint := range endpoints {
+			if endpoint.Scenario != "" {
+				names[endpoint.Scenario] = true
+			}
+		}
+	}
+
+	states := []ScenarioState{}
+	for name := range names {
+		states = append(states, ScenarioState{Name: name, State: e.scenarioState(name)})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+// SetScenarioState moves the scenario to the state
+func (e *Endpoints) SetScenarioState(name, state string) {
+	e.Lock()
+	e.scenarios[name] = state
+	e.Unlock()
+	e.notify([]StoreEvent{{Op: StoreScenario}})
+}
+
+// ResetScenarios moves all the scenarios back to ScenarioStarted
+func (e *Endpoints) ResetScenarios() {
+	e.Lock()
+	e.scenarios = map[string]string{}
+	e.Unlock()
+	e.notify([]StoreEvent{{Op: StoreScenario}})
+}
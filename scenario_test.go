@@ -0,0 +1,59 @@
+package mockservice_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/wchan2/mock_service"
+)
+
+func TestScenarios(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	err = service.Endpoints().Load([]*mockservice.MockEndpoint{
+		{Method: http.MethodGet, Endpoint: "/cart", StatusCode: http.StatusOK, ResponseBody: "empty", Scenario: "cart", RequiredState: mockservice.ScenarioStarted},
+		{Method: http.MethodPost, Endpoint: "/cart", StatusCode: http.StatusCreated, Scenario: "cart", NewState: "full"},
+		{Method: http.MethodGet, Endpoint: "/cart", StatusCode: http.StatusOK, ResponseBody: "widget", Scenario: "cart", RequiredState: "full"},
+	})
+	if err != nil {
+		t.Fatalf("Expected loading the endpoints to succeed but got %s", err)
+	}
+
+	if recorder := serve(service, http.MethodGet, "/cart", ""); recorder.Body.String() != "empty" {
+		t.Errorf("Expected the cart to start empty but got %s", recorder.Body.String())
+	}
+	serve(service, http.MethodPost, "/cart", "")
+	if recorder := serve(service, http.MethodGet, "/cart", ""); recorder.Body.String() != "widget" {
+		t.Errorf("Expected the cart to be full after adding to it but got %s", recorder.Body.String())
+	}
+
+	expected := []mockservice.ScenarioState{{Name: "cart", State: "full"}}
+	if states := service.Endpoints().Scenarios(); !reflect.DeepEqual(states, expected) {
+		t.Errorf("Expected the scenario states %v but got %v", expected, states)
+	}
+
+	if recorder := serve(service, http.MethodDelete, "/mocks/scenarios", ""); recorder.Code != http.StatusNoContent {
+		t.Errorf("Expected %d status but got %d", http.StatusNoContent, recorder.Code)
+	}
+	if recorder := serve(service, http.MethodGet, "/cart", ""); recorder.Body.String() != "empty" {
+		t.Errorf("Expected the reset cart to be empty but got %s", recorder.Body.String())
+	}
+
+	if recorder := serve(service, http.MethodPut, "/mocks/scenarios", `{"name": "cart", "state": "full"}`); recorder.Code != http.StatusNoContent {
+		t.Errorf("Expected %d status but got %d", http.StatusNoContent, recorder.Code)
+	}
+	if recorder := serve(service, http.MethodGet, "/mocks/scenarios", ""); recorder.Body.String() != `[{"name":"cart","state":"full"}]` {
+		t.Errorf("Expected the scenario to be full but got %s", recorder.Body.String())
+	}
+	if recorder := serve(service, http.MethodPut, "/mocks/scenarios", `{"name": "cart"}`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected %d status for a missing state but got %d", http.StatusBadRequest, recorder.Code)
+	}
+
+	err = service.Endpoints().Create(&mockservice.MockEndpoint{Method: http.MethodGet, Endpoint: "/cart", RequiredState: "full"})
+	if err != mockservice.ErrMissingScenario {
+		t.Errorf("Expected %s but got %v", mockservice.ErrMissingScenario, err)
+	}
+}
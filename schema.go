@@ -0,0 +1,387 @@
+package mockservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// uuidPattern matches the canonical textual form of a UUID
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// SchemaViolation describes how a request breaks the schema of its OpenAPI operation
+type SchemaViolation struct {
+	// In is where the violation was found: "path", "query", "header", "cookie" or "body"
+	In string `json:"in" xml:"in"`
+	// Name is the name of the parameter, or the JSON pointer of the value in the body
+	Name string `json:"name,omitempty" xml:"name,omitempty"`
+	// Message describes the violation
+	Message string `json:"message" xml:"message"`
+}
+
+// schemaValidator validates values against the subset of JSON Schema used by OpenAPI 3 and collects the violations
+type schemaValidator struct {
+	in         string
+	request    bool
+	violations []SchemaViolation
+}
+
+// decodeJSON decodes JSON keeping numbers as json.Number
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return fmt.Errorf("unexpected data after the JSON value")
+	}
+	return nil
+}
+
+func (v *schemaValidator) violation(name, format string, args ...interface{}) {
+	v.violations = append(v.violations, SchemaViolation{In: v.in, Name: name, Message: fmt.Sprintf(format, args...)})
+}
+
+// valid reports whether the value satisfies the schema without collecting its violations
+func (v *schemaValidator) valid(schema map[string]interface{}, value interface{}) bool {
+	nested := &schemaValidator{in: v.in, request: v.request}
+	nested.validate(schema, value, "")
+	return len(nested.violations) == 0
+}
+
+// validate checks the value found at the JSON pointer against the schema
+func (v *schemaValidator) validate(schema map[string]interface{}, value interface{}, pointer string) {
+	if schema == nil {
+		return
+	}
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable || schemaAllowsType(schema, "null") {
+			return
+		}
+	}
+	if types := schemaTypes(schema); len(types) > 0 && !matchesType(types, value) {
+		v.violation(pointer, "must be of type %s", strings.Join(types, " or "))
+		return
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && !containsJSON(enum, value) {
+		v.violation(pointer, "must be one of %s", marshalEnum(enum))
+	}
+
+	switch value := value.(type) {
+	case string:
+		v.validateString(schema, value, pointer)
+	case json.Number:
+		v.validateNumber(schema, value, pointer)
+	case []interface{}:
+		v.validateArray(schema, value, pointer)
+	case map[string]interface{}:
+		v.validateObject(schema, value, pointer)
+	}
+
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range allOf {
+			subSchema, _ := sub.(map[string]interface{})
+			v.validate(subSchema, value, pointer)
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok && countValid(v, anyOf, value) == 0 {
+		v.violation(pointer, "must match at least one of the anyOf schemas")
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		if count := countValid(v, oneOf, value); count != 1 {
+			v.violation(pointer, "must match exactly one of the oneOf schemas but matches %d", count)
+		}
+	}
+	if not, ok := schema["not"].(map[string]interface{}); ok && v.valid(not, value) {
+		v.violation(pointer, "must not match the schema of not")
+	}
+}
+
+func (v *schemaValidator) validateString(schema map[string]interface{}, value, pointer string) {
+	length := utf8.RuneCountInString(value)
+	if min, ok := schemaNumber(schema, "minLength"); ok && float64(length) < min {
+		v.violation(pointer, "must be at least %v characters long", min)
+	}
+	if max, ok := schemaNumber(schema, "maxLength"); ok && float64(length) > max {
+		v.violation(pointer, "must be at most %v characters long", max)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(value) {
+			v.violation(pointer, "must match the pattern %s", pattern)
+		}
+	}
+
+	format, _ := schema["format"].(string)
+	valid := true
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		valid = err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", value)
+		valid = err == nil
+	case "uuid":
+		valid = uuidPattern.MatchString(value)
+	case "email":
+		at := strings.LastIndex(value, "@")
+		valid = at > 0 && at < len(value)-1
+	}
+	if !valid {
+		v.violation(pointer, "must be a valid %s", format)
+	}
+}
+
+func (v *schemaValidator) validateNumber(schema map[string]interface{}, value json.Number, pointer string) {
+	number, err := value.Float64()
+	if err != nil {
+		v.violation(pointer, "must be a number")
+		return
+	}
+	if min, ok := schemaNumber(schema, "minimum"); ok {
+		if exclusive, _ := schema["exclusiveMinimum"].(bool); exclusive && number <= min {
+			v.violation(pointer, "must be greater than %v", min)
+		} else if number < min {
+			v.violation(pointer, "must be at least %v", min)
+		}
+	}
+	if min, ok := schemaNumber(schema, "exclusiveMinimum"); ok && number <= min {
+		v.violation(pointer, "must be greater than %v", min)
+	}
+	if max, ok := schemaNumber(schema, "maximum"); ok {
+		if exclusive, _ := schema["exclusiveMaximum"].(bool); exclusive && number >= max {
+			v.violation(pointer, "must be less than %v", max)
+		} else if number > max {
+			v.violation(pointer, "must be at most %v", max)
+		}
+	}
+	if max, ok := schemaNumber(schema, "exclusiveMaximum"); ok && number >= max {
+		v.violation(pointer, "must be less than %v", max)
+	}
+	if multiple, ok := schemaNumber(schema, "multipleOf"); ok && multiple > 0 {
+		if quotient := number / multiple; math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			v.violation(pointer, "must be a multiple of %v", multiple)
+		}
+	}
+
+	switch format, _ := schema["format"].(string); format {
+	case "int32":
+		if number < math.MinInt32 || number > math.MaxInt32 {
+			v.violation(pointer, "must be a valid int32")
+		}
+	case "int64":
+		if _, err := strconv.ParseInt(value.String(), 10, 64); err != nil {
+			v.violation(pointer, "must be a valid int64")
+		}
+	}
+}
+
+func (v *schemaValidator) validateArray(schema map[string]interface{}, value []interface{}, pointer string) {
+	if min, ok := schemaNumber(schema, "minItems"); ok && float64(len(value)) < min {
+		v.violation(pointer, "must have at least %v items", min)
+	}
+	if max, ok := schemaNumber(schema, "maxItems"); ok && float64(len(value)) > max {
+		v.violation(pointer, "must have at most %v items", max)
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		for i := range value {
+			if containsJSON(value[:i], value[i]) {
+				v.violation(pointer, "must have unique items")
+				break
+			}
+		}
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		for i, item := range value {
+			v.validate(items, item, pointer+"/"+strconv.Itoa(i))
+		}
+	}
+}
+
+func (v *schemaValidator) validateObject(schema map[string]interface{}, value map[string]interface{}, pointer string) {
+	properties, _ := schema["properties"].(map[string]interface{})
+	required, _ := schema["required"].([]interface{})
+	for _, name := range required {
+		name, _ := name.(string)
+		if _, ok := value[name]; ok {
+			continue
+		}
+		property, _ := properties[name].(map[string]interface{})
+		if readOnly, _ := property["readOnly"].(bool); readOnly && v.request {
+			continue
+		}
+		v.violation(pointer+"/"+escapePointer(name), "is required")
+	}
+	if min, ok := schemaNumber(schema, "minProperties"); ok && float64(len(value)) < min {
+		v.violation(pointer, "must have at least %v properties", min)
+	}
+	if max, ok := schemaNumber(schema, "maxProperties"); ok && float64(len(value)) > max {
+		v.violation(pointer, "must have at most %v properties", max)
+	}
+
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		propertyPointer := pointer + "/" + escapePointer(name)
+		if property, ok := properties[name].(map[string]interface{}); ok {
+			v.validate(property, value[name], propertyPointer)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.violation(propertyPointer, "is not allowed")
+			}
+		case map[string]interface{}:
+			v.validate(additional, value[name], propertyPointer)
+		}
+	}
+}
+
+// schemaTypes returns the types allowed by the schema, which OpenAPI 3.1 allows to be a list
+func schemaTypes(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := []string{}
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func schemaAllowsType(schema map[string]interface{}, t string) bool {
+	for _, allowed := range schemaTypes(schema) {
+		if allowed == t {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesType(types []string, value interface{}) bool {
+	for _, t := range types {
+		switch value := value.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case json.Number:
+			if t == "number" {
+				return true
+			}
+			if f, err := value.Float64(); t == "integer" && err == nil && f == math.Trunc(f) {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func schemaNumber(schema map[string]interface{}, keyword string) (float64, bool) {
+	number, ok := schema[keyword].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := number.Float64()
+	return f, err == nil
+}
+
+func countValid(v *schemaValidator, schemas []interface{}, value interface{}) int {
+	count := 0
+	for _, sub := range schemas {
+		if subSchema, ok := sub.(map[string]interface{}); ok && v.valid(subSchema, value) {
+			count++
+		}
+	}
+	return count
+}
+
+// containsJSON reports whether the list holds a value equal to the given one, comparing numbers by value
+func containsJSON(list []interface{}, value interface{}) bool {
+	for _, item := range list {
+		if sameJSONValue(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameJSONValue(a, b interface{}) bool {
+	if x, ok := a.(json.Number); ok {
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, errX := x.Float64()
+		fy, errY := y.Float64()
+		return errX == nil && errY == nil && fx == fy
+	}
+	switch x := a.(type) {
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !sameJSONValue(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key := range x {
+			if _, ok := y[key]; !ok || !sameJSONValue(x[key], y[key]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func marshalEnum(enum []interface{}) string {
+	data, _ := json.Marshal(enum)
+	return string(data)
+}
+
+// escapePointer escapes a property name as a JSON pointer token
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
@@ -0,0 +1,183 @@
+package mockservice
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRepeatingEventsWithoutDelay is returned when attempting to add a mock endpoint repeating server-sent events without any delay
+var ErrRepeatingEventsWithoutDelay = errors.New("Repeating server-sent events require a delay")
+
+// ServerSentEvents responds to a request with a text/event-stream of events
+type ServerSentEvents struct {
+	Events []ServerSentEvent `json:"events" xml:"events"`
+	// Repeat emits the events again from the start once they were all emitted
+	Repeat bool `json:"repeat,omitempty" xml:"repeat,omitempty"`
+	// KeepOpen keeps the stream open once the events were emitted, for events pushed through the events administration route
+	KeepOpen bool `json:"keepOpen,omitempty" xml:"keepOpen,omitempty"`
+}
+
+// ServerSentEvent is a single event of a text/event-stream
+type ServerSentEvent struct {
+	ID    string `json:"id,omitempty" xml:"id,omitempty"`
+	Event string `json:"event,omitempty" xml:"event,omitempty"`
+	Data  string `json:"data" xml:"data"`
+	// Retry is the reconnection time sent to the client
+	Retry Duration `json:"retry,omitempty" xml:"retry,omitempty"`
+	// Delay is the time waited before the event is emitted
+	Delay Duration `json:"delay,omitempty" xml:"delay,omitempty"`
+}
+
+func (e *ServerSentEvent) format() string {
+	var b strings.Builder
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", e.ID)
+	}
+	if e.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", e.Event)
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", time.Duration(e.Retry).Milliseconds())
+	}
+	for _, line := range strings.Split(e.Data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func (s *ServerSentEvents) validate() error {
+	if !s.Repeat {
+		return nil
+	}
+	for _, event := range s.Events {
+		if event.Delay > 0 {
+			return nil
+		}
+	}
+	return ErrRepeatingEventsWithoutDelay
+}
+
+// eventHub tracks the clients connected to server-sent event mock endpoints so events can be pushed to them
+type eventHub struct {
+	clients map[string]map[chan ServerSentEvent]struct{}
+	sync.Mutex
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{clients: map[string]map[chan ServerSentEvent]struct{}{}}
+}
+
+func eventHubKey(method, endpoint string) string {
+	return method + " " + endpoint
+}
+
+func (h *eventHub) subscribe(key string) chan ServerSentEvent {
+	h.Lock()
+	defer h.Unlock()
+	pushed := make(chan ServerSentEvent, 16)
+	if h.clients[key] == nil {
+		h.clients[key] = map[chan ServerSentEvent]struct{}{}
+	}
+	h.clients[key][pushed] = struct{}{}
+	return pushed
+}
+
+func (h *eventHub) unsubscribe(key string, pushed chan ServerSentEvent) {
+	h.Lock()
+	delete(h.clients[key], pushed)
+	h.Unlock()
+}
+
+// push sends the event to the clients connected to the mock endpoint and returns how many received it
+func (h *eventHub) push(method, endpoint string, event ServerSentEvent) int {
+	h.Lock()
+	defer h.Unlock()
+	sent := 0
+	for pushed := range h.clients[eventHubKey(method, endpoint)] {
+		select {
+		case pushed <- event:
+			sent++
+		default:
+		}
+	}
+	return sent
+}
+
+// stream emits the endpoint's server-sent events, and any events pushed for it, until the events are exhausted or the client goes away
+func (h *eventHub) stream(w http.ResponseWriter, req *http.Request, endpoint *MockEndpoint) {
+	key := eventHubKey(endpoint.Method, endpoint.Endpoint)
+	pushed := h.subscribe(key)
+	defer h.unsubscribe(key, pushed)
+
+	for headerKey, headerVal := range endpoint.ResponseHeaders {
+		w.Header().Add(headerKey, headerVal)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	status := endpoint.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	flusher, _ := w.(http.Flusher)
+	emit := func(event ServerSentEvent) bool {
+		if _, err := fmt.Fprint(w, event.format()); err != nil {
+			return false
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return true
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	// wait emits pushed events until the delay elapsed, returning false if the stream ended
+	wait := func(delay time.Duration) bool {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		for {
+			select {
+			case <-timer.C:
+				return true
+			case event := <-pushed:
+				if !emit(event) {
+					return false
+				}
+			case <-req.Context().Done():
+				return false
+			}
+		}
+	}
+
+	events := endpoint.ServerSentEvents
+	for {
+		for _, event := range events.Events {
+			if !wait(time.Duration(event.Delay)) || !emit(event) {
+				return
+			}
+		}
+		if !events.Repeat {
+			break
+		}
+	}
+
+	if events.KeepOpen {
+		for {
+			select {
+			case event := <-pushed:
+				if !emit(event) {
+					return
+				}
+			case <-req.Context().Done():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package mockservice_test
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wchan2/mock_service"
+)
+
+// readEvent reads the lines of the next server-sent event
+func readEvent(t *testing.T, reader *bufio.Reader) string {
+	lines := []string{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Expected to read an event but got %s", err)
+		}
+		if line == "\n" {
+			return strings.Join(lines, "")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestServerSentEvents(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	service.Endpoints().Load([]*mockservice.MockEndpoint{
+		{
+			Method:   http.MethodGet,
+			Endpoint: "/notifications",
+			ServerSentEvents: &mockservice.ServerSentEvents{
+				Events: []mockservice.ServerSentEvent{
+					{ID: "1", Event: "greeting", Data: "hello\nworld", Retry: mockservice.Duration(time.Second)},
+					{ID: "2", Data: "later", Delay: mockservice.Duration(20 * time.Millisecond)},
+				},
+				KeepOpen: true,
+			},
+		},
+		{
+			Method:           http.MethodGet,
+			Endpoint:         "/once",
+			ServerSentEvents: &mockservice.ServerSentEvents{Events: []mockservice.ServerSentEvent{{Data: "only"}}},
+		},
+	})
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	t.Run("Scripted_and_pushed_events", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/notifications", nil)
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatalf("Expected the request to succeed but got %s", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.Header.Get("Content-Type") != "text/event-stream" {
+			t.Errorf("Expected a text/event-stream content type but got %s", resp.Header.Get("Content-Type"))
+		}
+
+		reader := bufio.NewReader(resp.Body)
+		if event := readEvent(t, reader); event != "id: 1\nevent: greeting\nretry: 1000\ndata: hello\ndata: world\n" {
+			t.Errorf("Expected the first event but got %q", event)
+		}
+		if event := readEvent(t, reader); event != "id: 2\ndata: later\n" {
+			t.Errorf("Expected the second event but got %q", event)
+		}
+
+		pushed := serve(service, http.MethodPost, "/mocks/events?endpoint=/notifications", `{"event": "alert", "data": "pushed"}`)
+		if pushed.Body.String() != `{"clients":1}` {
+			t.Errorf(`Expected the event to be pushed to 1 client but got %s`, pushed.Body.String())
+		}
+		if event := readEvent(t, reader); event != "event: alert\ndata: pushed\n" {
+			t.Errorf("Expected the pushed event but got %q", event)
+		}
+	})
+
+	t.Run("Stream_closes_after_events", func(t *testing.T) {
+		resp, err := server.Client().Get(server.URL + "/once")
+		if err != nil {
+			t.Fatalf("Expected the request to succeed but got %s", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "data: only\n\n" {
+			t.Errorf("Expected a single event but got %q", body)
+		}
+	})
+
+	t.Run("Repeat_without_delay", func(t *testing.T) {
+		err := service.Endpoints().Create(&mockservice.MockEndpoint{
+			Method:           http.MethodGet,
+			Endpoint:         "/forever",
+			ServerSentEvents: &mockservice.ServerSentEvents{Events: []mockservice.ServerSentEvent{{Data: "spin"}}, Repeat: true},
+		})
+		if err != mockservice.ErrRepeatingEventsWithoutDelay {
+			t.Errorf("Expected %s error but got %v", mockservice.ErrRepeatingEventsWithoutDelay, err)
+		}
+	})
+}
@@ -0,0 +1,244 @@
+package mockservice
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrSessionDoesNotExist is returned for a session that no mock endpoint was registered into, or that was deleted
+	ErrSessionDoesNotExist = errors.New("Session does not exist")
+
+	// ErrTooManySessions is returned when registering mock endpoints into a new session while the maximum number of
+	// sessions are in use
+	ErrTooManySessions = errors.New("Too many sessions in use")
+)
+
+const (
+	// SessionHeader selects the session of a request to the registration, administration or mock endpoints
+	SessionHeader = "X-Mock-Session"
+
+	// SessionPathPrefix selects the session of a request whose path starts with it followed by the session ID, such as
+	// /_sessions/{id}/mocks or /_sessions/{id}/users/1, which is served with the rest of the path
+	SessionPathPrefix = "/_sessions/"
+
+	// DefaultMaxSessions is the number of sessions that requests can create, see LimitSessions
+	DefaultMaxSessions = 1000
+
+	// DefaultSessionIdleTimeout is the time after which a session that received no request is deleted, see LimitSessions
+	DefaultSessionIdleTimeout = time.Hour
+)
+
+// sessionRegistry holds the isolated mock services of the sessions and when they last received a request
+type sessionRegistry struct {
+	services    map[string]*MockService
+	lastUsed    map[string]time.Time
+	max         int
+	idleTimeout time.Duration
+	// journalLimit is the number of requests and WebSocket messages kept by the journal of every session
+	journalLimit int
+	sync.Mutex
+}
+
+func newSessionRegistry() *sessionRegistry {
+	return &sessionRegistry{
+		services:     map[string]*MockService{},
+		lastUsed:     map[string]time.Time{},
+		max:          DefaultMaxSessions,
+		idleTimeout:  DefaultSessionIdleTimeout,
+		journalLimit: DefaultJournalLimit,
+	}
+}
+
+// LimitSessions sets the maximum number of sessions that requests registering mock endpoints can create, and the time
+// after which a session that received no request is deleted. Zero disables either limit.
+func (m *MockService) LimitSessions(max int, idleTimeout time.Duration) {
+	if m.sessions == nil {
+		return
+	}
+	m.sessions.Lock()
+	m.sessions.max = max
+	m.sessions.idleTimeout = idleTimeout
+	m.sessions.Unlock()
+}
+
+// LimitJournal sets the number of requests and WebSocket messages kept by the journal of the mock service and of its
+// sessions, including the sessions created later, see Journal.Limit
+func (m *MockService) LimitJournal(max int) {
+	m.journal.Limit(max)
+	if m.sessions == nil {
+		return
+	}
+	m.sessions.Lock()
+	m.sessions.journalLimit = max
+	for _, session := range m.sessions.services {
+		session.journal.Limit(max)
+	}
+	m.sessions.Unlock()
+}
+
+// Session returns the isolated mock service of the session, creating it when first used. Its mock endpoints only match
+// requests of the session, which are recorded in its own journal, and resetting it leaves other sessions untouched.
+func (m *MockService) Session(id string) *MockService {
+	if m.sessions == nil {
+		return m
+	}
+	session, _ := m.session(id, true, false)
+	return session
+}
+
+// session returns the mock service of the session, creating it when create is set, up to the maximum number of sessions
+// when limited is set. It deletes the sessions that have been idle for too long first.
+func (m *MockService) session(id string, create, limited bool) (*MockService, error) {
+	m.sessions.Lock()
+	defer m.sessions.Unlock()
+	now := time.Now()
+	m.sessions.evict(now)
+	if session, ok := m.sessions.services[id]; ok {
+		m.sessions.lastUsed[id] = now
+		return session, nil
+	}
+	if !create {
+		return nil, ErrSessionDoesNotExist
+	}
+	if limited && m.sessions.max > 0 && len(m.sessions.services) >= m.sessions.max {
+		return nil, ErrTooManySessions
+	}
+	session := newMockService(m.adminService.registrationEndpoint, NewEndpoints())
+	session.endpointService.descriptors = m.endpointService.descriptors
+	session.adminService.certificateAuthority = m.adminService.certificateAuthority
+	session.journal.Limit(m.sessions.journalLimit)
+	m.sessions.services[id] = session
+	m.sessions.lastUsed[id] = now
+	return session, nil
+}
+
+// evict deletes the sessions that received no request within the idle timeout. It must be called with the lock held.
+func (r *sessionRegistry) evict(now time.Time) {
+	if r.idleTimeout <= 0 {
+		return
+	}
+	for id, lastUsed := range r.lastUsed {
+		if now.Sub(lastUsed) >= r.idleTimeout {
+			r.services[id].Close()
+			delete(r.services, id)
+			delete(r.lastUsed, id)
+		}
+	}
+}
+
+// Sessions lists the IDs of the sessions in use, sorted
+func (m *MockService) Sessions() []string {
+	ids := []string{}
+	if m.sessions == nil {
+		return ids
+	}
+	m.sessions.Lock()
+	m.sessions.evict(time.Now())
+	for id := range m.sessions.services {
+		ids = append(ids, id)
+	}
+	m.sessions.Unlock()
+	sort.Strings(ids)
+	return ids
+}
+
+// DeleteSession removes the session along with its mock endpoints and recorded requests
+func (m *MockService) DeleteSession(id string) error {
+	if m.sessions == nil {
+		return ErrSessionDoesNotExist
+	}
+	m.sessions.Lock()
+	defer m.sessions.Unlock()
+	session, ok := m.sessions.services[id]
+	if !ok {
+		return ErrSessionDoesNotExist
+	}
+	session.Close()
+	delete(m.sessions.services, id)
+	delete(m.sessions.lastUsed, id)
+	return nil
+}
+
+// serveSession serves the request within the session. Only requests registering mock endpoints create the session, so
+// mock traffic and administration requests for an unknown session get 404 Not Found.
+func (m *MockService) serveSession(w http.ResponseWriter, req *http.Request, id string) {
+	session, err := m.session(id, m.adminService.registers(req), true)
+	if err == ErrTooManySessions {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	session.ServeHTTP(w, req)
+}
+
+// sessionServices returns the mock services of the sessions in use, sorted by session ID
+func (m *MockService) sessionServices() []*MockService {
+	services := []*MockService{}
+	if m.sessions == nil {
+		return services
+	}
+	m.sessions.Lock()
+	defer m.sessions.Unlock()
+	ids := []string{}
+	for id := range m.sessions.services {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		services = append(services, m.sessions.services[id])
+	}
+	return services
+}
+
+// sessionOf returns the session ID of the request, from its path prefix or header, along with the request to serve
+// within the session
+func sessionOf(req *http.Request) (string, *http.Request) {
+	if strings.HasPrefix(req.URL.Path, SessionPathPrefix) {
+		rest := strings.TrimPrefix(req.URL.Path, SessionPathPrefix)
+		id, path := rest, "/"
+		if i := strings.Index(rest, "/"); i >= 0 {
+			id, path = rest[:i], rest[i:]
+		}
+		if id == "" {
+			return "", req
+		}
+		stripped := req.Clone(req.Context())
+		stripped.URL.Path = path
+		stripped.URL.RawPath = ""
+		if raw := strings.TrimPrefix(req.URL.RawPath, SessionPathPrefix); raw != req.URL.RawPath && strings.Contains(raw, "/") {
+			stripped.URL.RawPath = raw[strings.Index(raw, "/"):]
+		}
+		return id, stripped
+	}
+	return strings.TrimSpace(req.Header.Get(SessionHeader)), req
+}
+
+// serveSessions serves the sessions administration route, listing the sessions in use or deleting the session given by
+// the id query parameter, or all of them without one
+func (m *MockService) serveSessions(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, m.Sessions())
+	case http.MethodDelete:
+		ids := req.URL.Query()["id"]
+		if len(ids) == 0 {
+			ids = m.Sessions()
+		}
+		for _, id := range ids {
+			if err := m.DeleteSession(id); err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+	}
+}
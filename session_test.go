@@ -0,0 +1,167 @@
+package mockservice_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wchan2/mock_service"
+)
+
+func serveSession(service http.Handler, session, method, url, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set(mockservice.SessionHeader, session)
+	recorder := httptest.NewRecorder()
+	service.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestSessions(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+
+	t.Run("Parallel_sessions", func(t *testing.T) {
+		for _, session := range []string{"a", "b", "c"} {
+			session := session
+			t.Run(session, func(t *testing.T) {
+				t.Parallel()
+				endpoint := fmt.Sprintf(`{"method": "GET", "endpoint": "/user", "httpStatusCode": 200, "responseBody": "%s"}`, session)
+				if recorder := serveSession(service, session, http.MethodPost, "/mocks", endpoint); recorder.Code != http.StatusCreated {
+					t.Fatalf("Expected %d status but got %d", http.StatusCreated, recorder.Code)
+				}
+				for i := 0; i < 10; i++ {
+					if recorder := serveSession(service, session, http.MethodGet, "/user", ""); recorder.Body.String() != session {
+						t.Errorf("Expected the response of session %s but got %s", session, recorder.Body.String())
+					}
+				}
+				if count := service.Session(session).Journal().Count(http.MethodGet, "/user"); count != 10 {
+					t.Errorf("Expected 10 requests in the journal of session %s but got %d", session, count)
+				}
+			})
+		}
+	})
+
+	if recorder := serve(service, http.MethodGet, "/user", ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected the mock endpoints of sessions not to match requests without a session but got %d", recorder.Code)
+	}
+	if requests := service.Journal().Requests(); len(requests) != 1 {
+		t.Errorf("Expected only the request without a session in the journal but got %d requests", len(requests))
+	}
+
+	t.Run("Path_prefix", func(t *testing.T) {
+		if recorder := serve(service, http.MethodGet, "/_sessions/b/user", ""); recorder.Body.String() != "b" {
+			t.Errorf("Expected the response of session b but got %d %s", recorder.Code, recorder.Body.String())
+		}
+		recorder := serve(service, http.MethodGet, "/_sessions/b/mocks/requests?offset=10", "")
+		requests := []*mockservice.RecordedRequest{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &requests); err != nil || len(requests) != 1 || requests[0].Endpoint != "/user" {
+			t.Errorf("Expected the request without its session prefix in the journal of session b but got %s", recorder.Body.String())
+		}
+	})
+
+	t.Run("Reset_session", func(t *testing.T) {
+		if recorder := serveSession(service, "a", http.MethodPost, "/mocks/reset", ""); recorder.Code != http.StatusNoContent {
+			t.Fatalf("Expected %d status but got %d", http.StatusNoContent, recorder.Code)
+		}
+		if recorder := serveSession(service, "a", http.MethodGet, "/user", ""); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected the mock endpoints of session a to be reset but got %d", recorder.Code)
+		}
+		if recorder := serveSession(service, "b", http.MethodGet, "/user", ""); recorder.Body.String() != "b" {
+			t.Errorf("Expected session b to be left untouched but got %s", recorder.Body.String())
+		}
+	})
+
+	t.Run("List_and_delete", func(t *testing.T) {
+		sessions := []string{}
+		recorder := serve(service, http.MethodGet, "/mocks/sessions", "")
+		if err := json.Unmarshal(recorder.Body.Bytes(), &sessions); err != nil || !reflect.DeepEqual(sessions, []string{"a", "b", "c"}) {
+			t.Errorf("Expected sessions a, b and c but got %s", recorder.Body.String())
+		}
+
+		if recorder := serve(service, http.MethodDelete, "/mocks/sessions?id=c", ""); recorder.Code != http.StatusNoContent {
+			t.Errorf("Expected %d status but got %d", http.StatusNoContent, recorder.Code)
+		}
+		if recorder := serve(service, http.MethodDelete, "/mocks/sessions?id=c", ""); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected deleting a missing session to respond with %d but got %d", http.StatusNotFound, recorder.Code)
+		}
+		if recorder := serveSession(service, "c", http.MethodGet, "/user", ""); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected the mock endpoints of the deleted session to be gone but got %d", recorder.Code)
+		}
+
+		serve(service, http.MethodDelete, "/mocks/sessions", "")
+		if sessions := service.Sessions(); len(sessions) != 0 {
+			t.Errorf("Expected all the sessions to be deleted but got %v", sessions)
+		}
+	})
+
+	t.Run("Unknown_session", func(t *testing.T) {
+		if recorder := serveSession(service, "random", http.MethodGet, "/user", ""); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected mock traffic of an unknown session to respond with %d but got %d", http.StatusNotFound, recorder.Code)
+		}
+		if recorder := serve(service, http.MethodGet, "/_sessions/random/mocks/requests", ""); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected administration requests of an unknown session to respond with %d but got %d", http.StatusNotFound, recorder.Code)
+		}
+		if sessions := service.Sessions(); len(sessions) != 0 {
+			t.Errorf("Expected requests not registering mock endpoints not to create sessions but got %v", sessions)
+		}
+	})
+}
+
+func TestSessions_Limits(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	defer service.Close()
+	service.LimitSessions(2, 50*time.Millisecond)
+	endpoint := `{"method": "GET", "endpoint": "/user", "httpStatusCode": 200}`
+
+	serveSession(service, "a", http.MethodPost, "/mocks", endpoint)
+	serveSession(service, "b", http.MethodPost, "/mocks", endpoint)
+	if recorder := serveSession(service, "c", http.MethodPost, "/mocks", endpoint); recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected registering into a session beyond the maximum to respond with %d but got %d", http.StatusServiceUnavailable, recorder.Code)
+	}
+	if recorder := serveSession(service, "a", http.MethodGet, "/user", ""); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the sessions in use to be served but got %d", recorder.Code)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if sessions := service.Sessions(); len(sessions) != 0 {
+		t.Errorf("Expected the idle sessions to be deleted but got %v", sessions)
+	}
+	if recorder := serveSession(service, "c", http.MethodPost, "/mocks", endpoint); recorder.Code != http.StatusCreated {
+		t.Errorf("Expected registering into a session once idle ones are deleted to succeed but got %d", recorder.Code)
+	}
+}
+
+func TestSessions_JournalLimit(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	defer service.Close()
+	endpoint := `{"method": "GET", "endpoint": "/user", "httpStatusCode": 200}`
+
+	serveSession(service, "before", http.MethodPost, "/mocks", endpoint)
+	service.LimitJournal(1)
+	serveSession(service, "after", http.MethodPost, "/mocks", endpoint)
+	for _, session := range []string{"before", "after"} {
+		serveSession(service, session, http.MethodGet, "/user", "")
+		serveSession(service, session, http.MethodGet, "/user", "")
+
+		journal := service.Session(session).Journal()
+		if requests := journal.Requests(); len(requests) != 1 {
+			t.Errorf("Expected the journal of session %s to keep 1 request but got %d", session, len(requests))
+		}
+		if count := journal.Count(http.MethodGet, "/user"); count != 2 {
+			t.Errorf("Expected the journal of session %s to count 2 requests but got %d", session, count)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package mockservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Snapshot holds the registered mock endpoints and scenario states, to be restored later or in another mock service
+type Snapshot struct {
+	// Endpoints are the registered mock endpoints by HTTP method, each in the order they were created
+	Endpoints []*MockEndpoint `json:"endpoints" xml:"endpoints"`
+	// MatchCounts are the numbers of requests matched by each of the endpoints, restored along with them so endpoints
+	// limited to a maximum number of matches are not matched more times
+	MatchCounts []int `json:"matchCounts,omitempty" xml:"matchCounts,omitempty"`
+	// ExpiresAt are the times the TTL of each of the endpoints expires, zero for endpoints without a TTL, restored along
+	// with them so the TTL of a restored endpoint does not start over
+	ExpiresAt []time.Time     `json:"expiresAt,omitempty" xml:"expiresAt,omitempty"`
+	Scenarios []ScenarioState `json:"scenarios,omitempty" xml:"scenarios,omitempty"`
+}
+
+// state returns the match count and TTL expiry of the endpoint at the index, which older snapshots may not hold
+func (s *Snapshot) state(i int) (int, time.Time) {
+	matchCount, expiresAt := 0, time.Time{}
+	if i < len(s.MatchCounts) {
+		matchCount = s.MatchCounts[i]
+	}
+	if i < len(s.ExpiresAt) {
+		expiresAt = s.ExpiresAt[i]
+	}
+	return matchCount, expiresAt
+}
+
+// snapshotFile reads and writes the snapshots of a store in a file, one at a time
+type snapshotFile struct {
+	name string
+	sync.Mutex
+}
+
+// Snapshot returns the endpoints that have not expired, keeping their creation order so a restored snapshot matches
+// requests the same way, and the scenario states
+func (e *Endpoints) Snapshot() *Snapshot {
+	return snapshotOf(e)
+}
+
+// Restore replaces the registered endpoints and scenario states with those of the snapshot, along with their match
+// counts and TTL expiry, leaving them untouched when an endpoint of the snapshot is invalid. The TTL of restored
+// endpoints without an expiry starts over.
+func (e *Endpoints) Restore(snapshot *Snapshot) error {
+	for _, endpoint := range snapshot.Endpoints {
+		if err := endpoint.Validate(); err != nil {
+			return err
+		}
+	}
+	e.Lock()
+	now := time.Now()
+	e.endpoints = make(map[string][]*MockEndpoint)
+	e.scenarios = map[string]string{}
+	e.unmatched = nil
+	events := []StoreEvent{{Op: StoreReset}}
+	for i, endpoint := range snapshot.Endpoints {
+		endpoint.matchCount, endpoint.expiresAt = snapshot.state(i)
+		if endpoint.TTL <= 0 {
+			endpoint.expiresAt = time.Time{}
+		} else if endpoint.expiresAt.IsZero() {
+			endpoint.expiresAt = now.Add(time.Duration(endpoint.TTL))
+		}
+		if j := e.index(endpoint); j >= 0 {
+			e.endpoints[endpoint.Method][j] = endpoint
+		} else {
+			e.endpoints[endpoint.Method] = append(e.endpoints[endpoint.Method], endpoint)
+		}
+		events = append(events, StoreEvent{Op: StoreCreated, Endpoint: endpoint})
+	}
+	for _, scenario := range snapshot.Scenarios {
+		e.scenarios[scenario.Name] = scenario.State
+	}
+	if len(snapshot.Scenarios) > 0 {
+		events = append(events, StoreEvent{Op: StoreScenario})
+	}
+	events = append(events, e.sweep(now)...)
+	e.Unlock()
+	e.notify(events)
+	return nil
+}
+
+// snapshotOf returns the snapshot of the store, with the endpoints of each HTTP method in the order they were created
+func snapshotOf(store Store) *Snapshot {
+	snapshot := &Snapshot{Endpoints: []*MockEndpoint{}, MatchCounts: []int{}, ExpiresAt: []time.Time{}, Scenarios: store.Scenarios()}
+	method := ""
+	for _, endpoint := range store.List() {
+		if endpoint.Method != method {
+			method = endpoint.Method
+			snapshot.Endpoints = append(snapshot.Endpoints, store.Routes(method)...)
+		}
+	}
+	for _, endpoint := range snapshot.Endpoints {
+		snapshot.MatchCounts = append(snapshot.MatchCounts, store.MatchCount(endpoint))
+		snapshot.ExpiresAt = append(snapshot.ExpiresAt, store.ExpiresAt(endpoint))
+	}
+	return snapshot
+}
+
+// read restores the snapshot saved in the file, when it exists, in place of the endpoints and scenario states of the
+// store
+func (f *snapshotFile) read(store Store) error {
+	data, err := ioutil.ReadFile(f.name)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("Unable to read snapshot: %s", err)
+	}
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return fmt.Errorf("Unable to parse snapshot %s: %s", f.name, err)
+	}
+	if err := store.Restore(snapshot); err != nil {
+		return fmt.Errorf("Unable to restore snapshot %s: %s", f.name, err)
+	}
+	return nil
+}
+
+// write saves the snapshot of the store to the file, replacing it atomically
+func (f *snapshotFile) write(store Store) error {
+	f.Lock()
+	defer f.Unlock()
+	data, err := json.MarshalIndent(snapshotOf(store), "", "  ")
+	if err != nil {
+		return fmt.Errorf("Unable to marshal snapshot: %s", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.name), filepath.Base(f.name)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("Unable to save snapshot: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Unable to save snapshot: %s", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Unable to save snapshot: %s", err)
+	}
+	if err := os.Rename(tmp.Name(), f.name); err != nil {
+		return fmt.Errorf("Unable to save snapshot: %s", err)
+	}
+	return nil
+}
+
+// Persist restores the mock endpoints, match counts, TTL expiry and scenario states saved in the file when it exists,
+// in place of the registered ones so that configured endpoints deleted before a restart stay deleted. It then saves
+// them to the file whenever the store changes, including endpoints removed by their TTL or maximum number of matches
+// and scenarios moved by matched requests, and when the mock service is closed. Mock endpoints of sessions are not
+// persisted.
+func (m *MockService) Persist(file string) error {
+	persistence := &snapshotFile{name: file}
+	if err := persistence.read(m.store); err != nil {
+		return err
+	}
+	if m.stopPersisting != nil {
+		m.stopPersisting()
+	}
+	m.persistence = persistence
+	m.stopPersisting = m.store.Watch(func(event StoreEvent) {
+		if !event.persisted() {
+			return
+		}
+		if err := m.save(); err != nil {
+			log.Printf("%s", err)
+		}
+	})
+	return m.save()
+}
+
+// save writes the snapshot of the mock endpoints to the persistence file when there is one
+func (m *MockService) save() error {
+	if m.persistence == nil {
+		return nil
+	}
+	return m.persistence.write(m.store)
+}
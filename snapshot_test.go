@@ -0,0 +1,193 @@
+package mockservice_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wchan2/mock_service"
+)
+
+func TestPersist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mockservice")
+	if err != nil {
+		t.Fatalf("Expected creating a temp dir to succeed but got %s", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "mocks.json")
+
+	conf := &mockservice.Conf{
+		RegistrationEndpoint: "/mocks",
+		Endpoints:            []*mockservice.MockEndpoint{mockservice.Get("/configured").WillReturn(http.StatusOK).MustBuild()},
+		PersistenceFile:      file,
+	}
+	service, err := mockservice.NewWithConf(conf)
+	if err != nil {
+		t.Fatalf("Expected creating the mock service to succeed but got %s", err)
+	}
+	serve(service, http.MethodPost, "/mocks", `{"method": "GET", "endpoint": "/users/{id}", "httpStatusCode": 200, "responseBody": "template"}`)
+	serve(service, http.MethodPost, "/mocks", `{"method": "GET", "endpoint": "/users/{id}", "httpStatusCode": 200, "responseBody": "newer", "requestHeaders": {"Accept": "text/plain"}}`)
+	serve(service, http.MethodPut, "/mocks/scenarios", `{"name": "cart", "state": "filled"}`)
+	if err := service.Close(); err != nil {
+		t.Fatalf("Expected closing the mock service to succeed but got %s", err)
+	}
+
+	restarted, err := mockservice.NewWithConf(&mockservice.Conf{RegistrationEndpoint: "/mocks", PersistenceFile: file})
+	if err != nil {
+		t.Fatalf("Expected restoring the mock service to succeed but got %s", err)
+	}
+	defer restarted.Close()
+	if endpoints := restarted.Endpoints().List(); len(endpoints) != 3 {
+		t.Errorf("Expected the configured and registered endpoints to be restored but got %d", len(endpoints))
+	}
+	if recorder := serve(restarted, http.MethodGet, "/users/1", ""); recorder.Body.String() != "template" {
+		t.Errorf("Expected the endpoint matching the request to be restored but got %s", recorder.Body.String())
+	}
+	if scenarios := restarted.Endpoints().Scenarios(); len(scenarios) != 1 || scenarios[0].State != "filled" {
+		t.Errorf("Expected the scenario state to be restored but got %+v", scenarios)
+	}
+
+	serve(restarted, http.MethodDelete, "/mocks?method=GET&endpoint=/configured", "")
+	snapshot := &mockservice.Snapshot{}
+	data, _ := ioutil.ReadFile(file)
+	if err := json.Unmarshal(data, snapshot); err != nil || len(snapshot.Endpoints) != 2 {
+		t.Errorf("Expected the deletion to be saved but got %s", data)
+	}
+
+	if err := ioutil.WriteFile(file, []byte(`{"endpoints": [{"method": "GET"}]}`), 0644); err != nil {
+		t.Fatalf("Expected writing the file to succeed but got %s", err)
+	}
+	if _, err := mockservice.NewWithConf(&mockservice.Conf{RegistrationEndpoint: "/mocks", PersistenceFile: file}); err == nil {
+		t.Errorf("Expected an error when restoring an invalid snapshot")
+	}
+}
+
+func TestPersist_Restart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mockservice")
+	if err != nil {
+		t.Fatalf("Expected creating a temp dir to succeed but got %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := func() *mockservice.Conf {
+		return &mockservice.Conf{
+			RegistrationEndpoint: "/mocks",
+			Endpoints: []*mockservice.MockEndpoint{
+				mockservice.Get("/configured").WillReturn(http.StatusOK).MustBuild(),
+				mockservice.Get("/deleted").WillReturn(http.StatusOK).MustBuild(),
+			},
+			PersistenceFile: filepath.Join(dir, "mocks.json"),
+		}
+	}
+	service, err := mockservice.NewWithConf(conf())
+	if err != nil {
+		t.Fatalf("Expected creating the mock service to succeed but got %s", err)
+	}
+	serve(service, http.MethodDelete, "/mocks?method=GET&endpoint=/deleted", "")
+	serve(service, http.MethodPost, "/mocks", `{"method": "GET", "endpoint": "/temporary", "httpStatusCode": 200, "ttl": "1h"}`)
+	expiresAt := service.Store().ExpiresAt(mockservice.Get("/temporary").MustBuild())
+	if err := service.Close(); err != nil {
+		t.Fatalf("Expected closing the mock service to succeed but got %s", err)
+	}
+
+	restarted, err := mockservice.NewWithConf(conf())
+	if err != nil {
+		t.Fatalf("Expected restoring the mock service to succeed but got %s", err)
+	}
+	defer restarted.Close()
+	if recorder := serve(restarted, http.MethodGet, "/deleted", ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected the configured endpoint deleted before the restart to stay deleted but got %d", recorder.Code)
+	}
+	if recorder := serve(restarted, http.MethodGet, "/configured", ""); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the configured endpoint to be restored but got %d", recorder.Code)
+	}
+	if restored := restarted.Store().ExpiresAt(mockservice.Get("/temporary").MustBuild()); !restored.Equal(expiresAt) {
+		t.Errorf("Expected the TTL to keep expiring at %s after the restart but got %s", expiresAt, restored)
+	}
+}
+
+func TestAdminService_Snapshot(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	serve(service, http.MethodPost, "/mocks", successfulRegistrationRequest)
+
+	recorder := serve(service, http.MethodGet, "/mocks/snapshot", "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected %d status but got %d", http.StatusOK, recorder.Code)
+	}
+	snapshot := recorder.Body.String()
+
+	if recorder := serve(service, http.MethodPut, "/mocks/snapshot", `{"endpoints": [{"method": "GET", "endpoint": " "}]}`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected %d status for an invalid snapshot but got %d", http.StatusBadRequest, recorder.Code)
+	}
+	if endpoints := service.Endpoints().List(); len(endpoints) != 1 {
+		t.Errorf("Expected an invalid snapshot to leave the endpoints untouched but got %d", len(endpoints))
+	}
+
+	serve(service, http.MethodPost, "/mocks/reset", "")
+	if recorder := serve(service, http.MethodPut, "/mocks/snapshot", snapshot); recorder.Code != http.StatusNoContent {
+		t.Errorf("Expected %d status but got %d", http.StatusNoContent, recorder.Code)
+	}
+	if recorder := serve(service, http.MethodGet, "/mock/test", ""); recorder.Code != 203 || recorder.Body.String() != "hello world" {
+		t.Errorf("Expected the restored endpoint to respond but got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestPersist_ChangesByTraffic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mockservice")
+	if err != nil {
+		t.Fatalf("Expected creating a temp dir to succeed but got %s", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "mocks.json")
+
+	service, err := mockservice.NewWithConf(&mockservice.Conf{RegistrationEndpoint: "/mocks", PersistenceFile: file})
+	if err != nil {
+		t.Fatalf("Expected creating the mock service to succeed but got %s", err)
+	}
+	service.Endpoints().Load([]*mockservice.MockEndpoint{
+		mockservice.Get("/once").Times(1).WillReturn(http.StatusOK).MustBuild(),
+		mockservice.Get("/twice").Times(2).WillReturn(http.StatusOK).MustBuild(),
+		mockservice.Get("/short").WithTTL(20 * time.Millisecond).WillReturn(http.StatusOK).MustBuild(),
+		{Method: http.MethodPost, Endpoint: "/cart", StatusCode: http.StatusCreated, Scenario: "cart", NewState: "filled"},
+	})
+	serve(service, http.MethodGet, "/once", "")
+	serve(service, http.MethodGet, "/twice", "")
+	serve(service, http.MethodPost, "/cart", "")
+	saved := func() int {
+		snapshot := &mockservice.Snapshot{}
+		data, _ := ioutil.ReadFile(file)
+		json.Unmarshal(data, snapshot)
+		return len(snapshot.Endpoints)
+	}
+	deadline := time.Now().Add(time.Second)
+	for saved() != 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// the service is not closed, as when the process crashes
+	restarted, err := mockservice.NewWithConf(&mockservice.Conf{RegistrationEndpoint: "/mocks", PersistenceFile: file})
+	if err != nil {
+		t.Fatalf("Expected restoring the mock service to succeed but got %s", err)
+	}
+	defer restarted.Close()
+	defer service.Close()
+	if endpoints := restarted.Endpoints().List(); len(endpoints) != 2 {
+		t.Errorf("Expected the exhausted and expired endpoints to stay removed but got %d endpoints", len(endpoints))
+	}
+	if scenarios := restarted.Endpoints().Scenarios(); len(scenarios) != 1 || scenarios[0].State != "filled" {
+		t.Errorf("Expected the scenario moved by a request to be restored but got %+v", scenarios)
+	}
+	if recorder := serve(restarted, http.MethodGet, "/twice", ""); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the endpoint to match its remaining request but got %d", recorder.Code)
+	}
+	if recorder := serve(restarted, http.MethodGet, "/twice", ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected the restored match count to remove the endpoint after its maximum but got %d", recorder.Code)
+	}
+}
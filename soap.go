@@ -0,0 +1,197 @@
+package mockservice
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ErrInvalidSOAPVersion is returned when attempting to add a mock endpoint with a SOAP version other than 1.1 or 1.2
+var ErrInvalidSOAPVersion = errors.New("Invalid SOAP version provided")
+
+// SOAP envelope namespaces and content types by version
+const (
+	soap11Namespace   = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12Namespace   = "http://www.w3.org/2003/05/soap-envelope"
+	soap11ContentType = "text/xml; charset=utf-8"
+	soap12ContentType = "application/soap+xml; charset=utf-8"
+)
+
+// SOAPMatcher matches SOAP requests, which are typically all sent to the same endpoint, by the operation they call.
+// All the non-empty properties must match.
+type SOAPMatcher struct {
+	// Action matches the SOAPAction header of SOAP 1.1 requests or the action parameter of the SOAP 1.2 content type
+	Action string `json:"action,omitempty" xml:"action,omitempty"`
+	// BodyElement matches the local name of the first child of the SOAP body, or its namespace as well when given as "{namespace}name"
+	BodyElement string `json:"bodyElement,omitempty" xml:"bodyElement,omitempty"`
+	// Version matches the SOAP version of the envelope, "1.1" or "1.2"
+	Version string `json:"version,omitempty" xml:"version,omitempty"`
+}
+
+// SOAPFault describes a SOAP fault returned instead of the response body, in the SOAP version of the request
+type SOAPFault struct {
+	// Code is the fault code: "Client" or "Sender" for faults caused by the request, "Server" or "Receiver" (the default)
+	// for faults of the service, or another code such as "MustUnderstand"
+	Code string `json:"code,omitempty" xml:"code,omitempty"`
+	// Reason is the human readable description of the fault
+	Reason string `json:"reason" xml:"reason"`
+	// Detail is XML included in the fault's detail element
+	Detail string `json:"detail,omitempty" xml:"detail,omitempty"`
+}
+
+// soapRequest is what identifies the operation called by a SOAP request
+type soapRequest struct {
+	version     string
+	action      string
+	bodyElement xml.Name
+}
+
+func (s *SOAPMatcher) validate() error {
+	if s.Version != "" && s.Version != "1.1" && s.Version != "1.2" {
+		return ErrInvalidSOAPVersion
+	}
+	return nil
+}
+
+func (s *SOAPMatcher) matches(req *http.Request, body []byte) bool {
+	request, err := parseSOAPRequest(req, body)
+	if err != nil {
+		return false
+	}
+
+	if s.Version != "" && s.Version != request.version {
+		return false
+	}
+	if s.Action != "" && s.Action != request.action {
+		return false
+	}
+	if s.BodyElement != "" && s.BodyElement != request.bodyElement.Local &&
+		s.BodyElement != "{"+request.bodyElement.Space+"}"+request.bodyElement.Local {
+		return false
+	}
+	return true
+}
+
+// parseSOAPRequest reads the SOAP version and the first child of the body from the envelope, and the action from the headers
+func parseSOAPRequest(req *http.Request, body []byte) (*soapRequest, error) {
+	request := &soapRequest{}
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	depth := 0
+	for request.bodyElement.Local == "" {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("no SOAP body element found: %s", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			if _, end := token.(xml.EndElement); end {
+				depth--
+			}
+			continue
+		}
+		switch depth {
+		case 0:
+			if start.Name.Local != "Envelope" {
+				return nil, fmt.Errorf("expected a SOAP envelope but got %s", start.Name.Local)
+			}
+			switch start.Name.Space {
+			case soap11Namespace:
+				request.version = "1.1"
+			case soap12Namespace:
+				request.version = "1.2"
+			default:
+				return nil, fmt.Errorf("unknown SOAP envelope namespace %s", start.Name.Space)
+			}
+		case 1:
+			if start.Name.Local != "Body" {
+				decoder.Skip()
+				continue
+			}
+		case 2:
+			request.bodyElement = start.Name
+		}
+		depth++
+	}
+
+	if action := req.Header.Get("SOAPAction"); action != "" {
+		request.action = strings.Trim(action, `"`)
+	} else if _, params, err := mime.ParseMediaType(req.Header.Get("Content-Type")); err == nil {
+		request.action = params["action"]
+	}
+	return request, nil
+}
+
+// soapVersion returns the SOAP version of the request, falling back to the version the endpoint matches and then 1.1
+func soapVersion(req *http.Request, body []byte, endpoint *MockEndpoint) string {
+	if request, err := parseSOAPRequest(req, body); err == nil {
+		return request.version
+	}
+	if endpoint.SOAP != nil && endpoint.SOAP.Version != "" {
+		return endpoint.SOAP.Version
+	}
+	return "1.1"
+}
+
+func soapContentType(version string) string {
+	if version == "1.2" {
+		return soap12ContentType
+	}
+	return soap11ContentType
+}
+
+// writeSOAPFault writes the endpoint's SOAP fault in the SOAP version of the request
+func writeSOAPFault(w http.ResponseWriter, req *http.Request, body []byte, endpoint *MockEndpoint) {
+	fault, version := endpoint.SOAPFault, soapVersion(req, body, endpoint)
+	code, statusCode := soapFaultCode(fault.Code, version)
+
+	var envelope bytes.Buffer
+	envelope.WriteString(xml.Header)
+	if version == "1.2" {
+		fmt.Fprintf(&envelope, `<env:Envelope xmlns:env="%s"><env:Body><env:Fault>`, soap12Namespace)
+		fmt.Fprintf(&envelope, `<env:Code><env:Value>env:%s</env:Value></env:Code>`, code)
+		envelope.WriteString(`<env:Reason><env:Text xml:lang="en">`)
+		xml.EscapeText(&envelope, []byte(fault.Reason))
+		envelope.WriteString(`</env:Text></env:Reason>`)
+		if fault.Detail != "" {
+			fmt.Fprintf(&envelope, `<env:Detail>%s</env:Detail>`, fault.Detail)
+		}
+		envelope.WriteString(`</env:Fault></env:Body></env:Envelope>`)
+	} else {
+		fmt.Fprintf(&envelope, `<soap:Envelope xmlns:soap="%s"><soap:Body><soap:Fault>`, soap11Namespace)
+		fmt.Fprintf(&envelope, `<faultcode>soap:%s</faultcode><faultstring>`, code)
+		xml.EscapeText(&envelope, []byte(fault.Reason))
+		envelope.WriteString(`</faultstring>`)
+		if fault.Detail != "" {
+			fmt.Fprintf(&envelope, `<detail>%s</detail>`, fault.Detail)
+		}
+		envelope.WriteString(`</soap:Fault></soap:Body></soap:Envelope>`)
+	}
+
+	for headerKey, headerVal := range endpoint.ResponseHeaders {
+		w.Header().Add(headerKey, headerVal)
+	}
+	w.Header().Set("Content-Type", soapContentType(version))
+	w.WriteHeader(statusCode)
+	w.Write(envelope.Bytes())
+}
+
+// soapFaultCode translates the fault code to the SOAP version and returns the HTTP status code the fault is sent with
+func soapFaultCode(code, version string) (string, int) {
+	switch code {
+	case "Client", "Sender":
+		if version == "1.2" {
+			return "Sender", http.StatusBadRequest
+		}
+		return "Client", http.StatusInternalServerError
+	case "", "Server", "Receiver":
+		if version == "1.2" {
+			return "Receiver", http.StatusInternalServerError
+		}
+		return "Server", http.StatusInternalServerError
+	}
+	return code, http.StatusInternalServerError
+}
@@ -0,0 +1,129 @@
+package mockservice_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wchan2/mock_service"
+)
+
+const (
+	soap11Request = `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+	<soap:Header><auth>secret</auth></soap:Header>
+	<soap:Body><m:GetPrice xmlns:m="urn:stock"><m:Item>Apples</m:Item></m:GetPrice></soap:Body>
+</soap:Envelope>`
+	soap12Request = `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope">
+	<env:Body><m:GetPrice xmlns:m="urn:stock"><m:Item>Pears</m:Item></m:GetPrice></env:Body>
+</env:Envelope>`
+)
+
+func TestSOAP(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	err = service.Endpoints().Load([]*mockservice.MockEndpoint{
+		mockservice.Post("/soap").WithSOAPBodyElement("{urn:stock}GetPrice").
+			WillReturn(http.StatusOK).WithSOAPFault("Client", "Unknown item <Pears>").MustBuild(),
+		mockservice.Post("/soap").WithSOAPAction("urn:stock/GetPrice").
+			WillReturn(http.StatusOK).WithBody("<price>1.90</price>").MustBuild(),
+		mockservice.Post("/soap").WithSOAPBodyElement("GetQuote").
+			WillReturn(http.StatusOK).WithBody("<quote/>").MustBuild(),
+	})
+	if err != nil {
+		t.Fatalf("Expected loading the endpoints to succeed but got %s", err)
+	}
+
+	cases := []struct {
+		name        string
+		body        string
+		headers     map[string]string
+		status      int
+		contentType string
+		expected    string
+	}{
+		{
+			"SOAP_1.1_Action",
+			soap11Request,
+			map[string]string{"Content-Type": "text/xml", "SOAPAction": `"urn:stock/GetPrice"`},
+			http.StatusOK,
+			"text/xml; charset=utf-8",
+			"<price>1.90</price>",
+		},
+		{
+			"SOAP_1.2_Action_Parameter",
+			soap12Request,
+			map[string]string{"Content-Type": `application/soap+xml; charset=utf-8; action="urn:stock/GetPrice"`},
+			http.StatusOK,
+			"application/soap+xml; charset=utf-8",
+			"<price>1.90</price>",
+		},
+		{
+			"SOAP_1.1_Fault",
+			soap11Request,
+			map[string]string{"Content-Type": "text/xml"},
+			http.StatusInternalServerError,
+			"text/xml; charset=utf-8",
+			`<soap:Fault><faultcode>soap:Client</faultcode><faultstring>Unknown item &lt;Pears&gt;</faultstring></soap:Fault>`,
+		},
+		{
+			"SOAP_1.2_Fault",
+			soap12Request,
+			map[string]string{"Content-Type": "application/soap+xml"},
+			http.StatusBadRequest,
+			"application/soap+xml; charset=utf-8",
+			`<env:Code><env:Value>env:Sender</env:Value></env:Code><env:Reason><env:Text xml:lang="en">Unknown item &lt;Pears&gt;</env:Text></env:Reason>`,
+		},
+		{
+			"Unmatched_Body_Element",
+			strings.Replace(soap11Request, "GetPrice", "GetStock", -1),
+			map[string]string{"Content-Type": "text/xml"},
+			http.StatusNotFound,
+			"text/plain; charset=utf-8",
+			"Endpoint does not exist",
+		},
+		{
+			"Not_SOAP",
+			"<GetQuote/>",
+			map[string]string{"Content-Type": "text/xml"},
+			http.StatusNotFound,
+			"text/plain; charset=utf-8",
+			"Endpoint does not exist",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(c.body))
+			for key, val := range c.headers {
+				req.Header.Set(key, val)
+			}
+			recorder := httptest.NewRecorder()
+			service.ServeHTTP(recorder, req)
+
+			if recorder.Code != c.status {
+				t.Errorf("Expected status %d but got %d", c.status, recorder.Code)
+			}
+			if contentType := recorder.Header().Get("Content-Type"); contentType != c.contentType {
+				t.Errorf(`Expected Content-Type "%s" but got "%s"`, c.contentType, contentType)
+			}
+			if !strings.Contains(recorder.Body.String(), c.expected) {
+				t.Errorf("Expected the body to contain %s but got %s", c.expected, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestSOAPMatcherValidation(t *testing.T) {
+	err := mockservice.NewEndpoints().Create(&mockservice.MockEndpoint{
+		Method:   http.MethodPost,
+		Endpoint: "/soap",
+		SOAP:     &mockservice.SOAPMatcher{Version: "2.0"},
+	})
+	if err != mockservice.ErrInvalidSOAPVersion {
+		t.Errorf("Expected %s but got %v", mockservice.ErrInvalidSOAPVersion, err)
+	}
+}
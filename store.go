@@ -0,0 +1,182 @@
+package mockservice
+
+import (
+	"net/http"
+	"time"
+)
+
+// Store holds the mock endpoints of a mock service along with their match counts, TTL expiry and the states of their
+// scenarios. Endpoints is the in-memory store and FileStore saves them to a file; other implementations are checked by
+// the storetest package.
+type Store interface {
+	// Create adds the endpoint, replacing one with the same route, see MockEndpoint.SameRoute, and starts its TTL
+	Create(endpoint *MockEndpoint) error
+	// Update replaces the endpoint with the same route, or returns ErrEndpointDoesNotExist
+	Update(endpoint *MockEndpoint) error
+	// Lookup returns the most recently created endpoint for the HTTP method and exact URL path or path template
+	Lookup(method, path string) (*MockEndpoint, error)
+	// Match returns the endpoint that best matches the request, or ErrEndpointDoesNotExist, as Endpoints.Match does
+	Match(req *http.Request, body []byte) (*MockEndpoint, error)
+	// List returns the endpoints sorted by HTTP method and URL path
+	List() []*MockEndpoint
+	// Routes returns the endpoints for the HTTP method that have not expired, in the order they were created
+	Routes(method string) []*MockEndpoint
+	// MatchCount returns how many requests matched the endpoint with the same route as the given one
+	MatchCount(endpoint *MockEndpoint) int
+	// ExpiresAt returns when the TTL of the endpoint with the same route as the given one expires, or the zero time
+	ExpiresAt(endpoint *MockEndpoint) time.Time
+	// Restore replaces the endpoints and scenario states with those of the snapshot, along with the match counts and
+	// TTL expiry it holds, or leaves them untouched when an endpoint of the snapshot is invalid
+	Restore(snapshot *Snapshot) error
+	// Delete removes the endpoints for the HTTP method and URL path, or returns ErrEndpointDoesNotExist
+	Delete(method, path string) error
+	// Reset removes all the endpoints and moves all the scenarios back to ScenarioStarted
+	Reset()
+	// Scenarios lists the states of the scenarios of the endpoints and of the scenarios set, sorted by name
+	Scenarios() []ScenarioState
+	// SetScenarioState moves the scenario to the state
+	SetScenarioState(name, state string)
+	// ResetScenarios moves all the scenarios back to ScenarioStarted
+	ResetScenarios()
+	// Watch calls the watcher after every change of the endpoints, match and change of scenario state until stop is
+	// called
+	Watch(watcher func(StoreEvent)) (stop func())
+}
+
+// StoreOp is the kind of change of a StoreEvent
+type StoreOp string
+
+const (
+	// StoreCreated is the op of an endpoint created, possibly replacing one with the same route
+	StoreCreated StoreOp = "created"
+	// StoreUpdated is the op of an endpoint updated
+	StoreUpdated StoreOp = "updated"
+	// StoreDeleted is the op of an endpoint deleted, expired or matched its maximum number of times
+	StoreDeleted StoreOp = "deleted"
+	// StoreReset is the op of all the endpoints being removed, which has no endpoint
+	StoreReset StoreOp = "reset"
+	// StoreMatched is the op of an endpoint matching a request
+	StoreMatched StoreOp = "matched"
+	// StoreScenario is the op of a scenario changing state, with the endpoint that moved it when it was matched
+	StoreScenario StoreOp = "scenario"
+)
+
+// StoreEvent is a change of the endpoints of a Store
+type StoreEvent struct {
+	Op       StoreOp
+	Endpoint *MockEndpoint
+}
+
+// persisted reports whether the event changes the saved snapshot of a store. Matches only do for endpoints limited to a
+// maximum number of matches, so that a restored endpoint is not matched more times.
+func (e StoreEvent) persisted() bool {
+	return e.Op != StoreMatched || e.Endpoint.MaxMatches > 0
+}
+
+// watcher is a function watching the changes of Endpoints
+type watcher struct {
+	id int
+	fn func(StoreEvent)
+}
+
+// Watch calls the watcher after every change of the endpoints, from the goroutine making the change, until stop is
+// called. The watcher must not block.
+func (e *Endpoints) Watch(fn func(StoreEvent)) (stop func()) {
+	e.Lock()
+	e.watcherID++
+	id := e.watcherID
+	e.watchers = append(e.watchers, watcher{id: id, fn: fn})
+	e.Unlock()
+	return func() {
+		e.Lock()
+		defer e.Unlock()
+		for i, w := range e.watchers {
+			if w.id == id {
+				e.watchers = append(e.watchers[:i:i], e.watchers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// notify calls the watchers with the events. It must be called without the lock held.
+func (e *Endpoints) notify(events []StoreEvent) {
+	if len(events) == 0 {
+		return
+	}
+	e.Lock()
+	watchers := append([]watcher{}, e.watchers...)
+	e.Unlock()
+	for _, event := range events {
+		for _, w := range watchers {
+			w.fn(event)
+		}
+	}
+}
+
+// Routes returns the endpoints for the HTTP method that have not expired, in the order they were created
+func (e *Endpoints) Routes(method string) []*MockEndpoint {
+	e.Lock()
+	defer e.Unlock()
+	now := time.Now()
+	routes := []*MockEndpoint{}
+	for _, endpoint := range e.endpoints[method] {
+		if !endpoint.expired(now) {
+			routes = append(routes, endpoint)
+		}
+	}
+	return routes
+}
+
+// MatchCount returns how many requests matched the endpoint with the same route as the given one
+func (e *Endpoints) MatchCount(endpoint *MockEndpoint) int {
+	e.Lock()
+	defer e.Unlock()
+	if i := e.index(endpoint); i >= 0 {
+		return e.endpoints[endpoint.Method][i].matchCount
+	}
+	return 0
+}
+
+// ExpiresAt returns when the TTL of the endpoint with the same route as the given one expires, or the zero time when it
+// has no TTL or does not exist
+func (e *Endpoints) ExpiresAt(endpoint *MockEndpoint) time.Time {
+	e.Lock()
+	defer e.Unlock()
+	if i := e.index(endpoint); i >= 0 {
+		return e.endpoints[endpoint.Method][i].expiresAt
+	}
+	return time.Time{}
+}
+
+// memory returns the endpoints themselves, so the Endpoints of stores embedding them can be accessed
+func (e *Endpoints) memory() *Endpoints {
+	return e
+}
+
+// memoryOf returns the Endpoints of the store, or nil when it is not built on Endpoints
+func memoryOf(store Store) *Endpoints {
+	if m, ok := store.(interface{ memory() *Endpoints }); ok {
+		return m.memory()
+	}
+	return nil
+}
+
+// loadEndpoints creates the endpoints in the store after validating all of them, so that an invalid endpoint leaves the
+// store untouched. A store built on Endpoints creates them at once, under a single lock.
+func loadEndpoints(store Store, endpoints []*MockEndpoint) error {
+	if memory := memoryOf(store); memory != nil {
+		return memory.createAll(endpoints)
+	}
+	for _, endpoint := range endpoints {
+		if err := endpoint.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, endpoint := range endpoints {
+		if err := store.Create(endpoint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
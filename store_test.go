@@ -0,0 +1,424 @@
+package mockservice_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wchan2/mock_service"
+	"github.com/wchan2/mock_service/storetest"
+)
+
+func TestEndpoints_Store(t *testing.T) {
+	storetest.Run(t, func() mockservice.Store {
+		return mockservice.NewEndpoints()
+	})
+}
+
+func TestStore_OutsideThePackage(t *testing.T) {
+	storetest.Run(t, func() mockservice.Store {
+		return newSliceStore()
+	})
+}
+
+// sliceStore is a Store built only on the exported API, as a store of another package would be
+type sliceStore struct {
+	entries   []*sliceEntry
+	scenarios map[string]string
+	watchers  map[int]func(mockservice.StoreEvent)
+	watcherID int
+	sync.Mutex
+}
+
+// sliceEntry is an endpoint of a sliceStore with its match count and TTL expiry
+type sliceEntry struct {
+	endpoint   *mockservice.MockEndpoint
+	matchCount int
+	expiresAt  time.Time
+}
+
+func newSliceStore() *sliceStore {
+	return &sliceStore{scenarios: map[string]string{}, watchers: map[int]func(mockservice.StoreEvent){}}
+}
+
+func (s *sliceStore) live() []*sliceEntry {
+	now := time.Now()
+	live := []*sliceEntry{}
+	for _, entry := range s.entries {
+		if entry.expiresAt.IsZero() || now.Before(entry.expiresAt) {
+			live = append(live, entry)
+		}
+	}
+	return live
+}
+
+func (s *sliceStore) index(endpoint *mockservice.MockEndpoint) int {
+	for i, entry := range s.entries {
+		if entry.endpoint.SameRoute(endpoint) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *sliceStore) entryOf(endpoint *mockservice.MockEndpoint) *sliceEntry {
+	entry := &sliceEntry{endpoint: endpoint}
+	if endpoint.TTL > 0 {
+		entry.expiresAt = time.Now().Add(time.Duration(endpoint.TTL))
+	}
+	return entry
+}
+
+func (s *sliceStore) state(name string) string {
+	if state, ok := s.scenarios[name]; ok {
+		return state
+	}
+	return mockservice.ScenarioStarted
+}
+
+func (s *sliceStore) notify(events ...mockservice.StoreEvent) {
+	s.Lock()
+	watchers := []func(mockservice.StoreEvent){}
+	for _, watcher := range s.watchers {
+		watchers = append(watchers, watcher)
+	}
+	s.Unlock()
+	for _, event := range events {
+		for _, watcher := range watchers {
+			watcher(event)
+		}
+	}
+}
+
+func (s *sliceStore) Create(endpoint *mockservice.MockEndpoint) error {
+	if err := endpoint.Validate(); err != nil {
+		return err
+	}
+	s.Lock()
+	if i := s.index(endpoint); i >= 0 {
+		s.entries[i] = s.entryOf(endpoint)
+	} else {
+		s.entries = append(s.entries, s.entryOf(endpoint))
+	}
+	s.Unlock()
+	s.notify(mockservice.StoreEvent{Op: mockservice.StoreCreated, Endpoint: endpoint})
+	return nil
+}
+
+func (s *sliceStore) Update(endpoint *mockservice.MockEndpoint) error {
+	if err := endpoint.Validate(); err != nil {
+		return err
+	}
+	s.Lock()
+	i := s.index(endpoint)
+	if i < 0 {
+		s.Unlock()
+		return mockservice.ErrEndpointDoesNotExist
+	}
+	s.entries[i] = s.entryOf(endpoint)
+	s.Unlock()
+	s.notify(mockservice.StoreEvent{Op: mockservice.StoreUpdated, Endpoint: endpoint})
+	return nil
+}
+
+func (s *sliceStore) Lookup(method, path string) (*mockservice.MockEndpoint, error) {
+	s.Lock()
+	defer s.Unlock()
+	live := s.live()
+	for i := len(live) - 1; i >= 0; i-- {
+		if live[i].endpoint.Method == method && live[i].endpoint.Endpoint == path {
+			return live[i].endpoint, nil
+		}
+	}
+	return nil, mockservice.ErrEndpointDoesNotExist
+}
+
+func (s *sliceStore) Match(req *http.Request, body []byte) (*mockservice.MockEndpoint, error) {
+	s.Lock()
+	var best *sliceEntry
+	live := s.live()
+	for i := len(live) - 1; i >= 0; i-- {
+		candidate := live[i].endpoint
+		if candidate.Method != req.Method || !candidate.Matches(req, body) ||
+			candidate.RequiredState != "" && s.state(candidate.Scenario) != candidate.RequiredState {
+			continue
+		}
+		if best == nil || candidate.Priority > best.endpoint.Priority ||
+			candidate.Priority == best.endpoint.Priority && strings.Contains(best.endpoint.Endpoint, "{") && !strings.Contains(candidate.Endpoint, "{") {
+			best = live[i]
+		}
+	}
+	if best == nil {
+		s.Unlock()
+		return nil, mockservice.ErrEndpointDoesNotExist
+	}
+	best.matchCount++
+	events := []mockservice.StoreEvent{{Op: mockservice.StoreMatched, Endpoint: best.endpoint}}
+	if best.endpoint.NewState != "" {
+		s.scenarios[best.endpoint.Scenario] = best.endpoint.NewState
+		events = append(events, mockservice.StoreEvent{Op: mockservice.StoreScenario, Endpoint: best.endpoint})
+	}
+	if best.endpoint.MaxMatches > 0 && best.matchCount >= best.endpoint.MaxMatches {
+		i := s.index(best.endpoint)
+		s.entries = append(s.entries[:i:i], s.entries[i+1:]...)
+		events = append(events, mockservice.StoreEvent{Op: mockservice.StoreDeleted, Endpoint: best.endpoint})
+	}
+	s.Unlock()
+	s.notify(events...)
+	return best.endpoint, nil
+}
+
+func (s *sliceStore) List() []*mockservice.MockEndpoint {
+	s.Lock()
+	list := []*mockservice.MockEndpoint{}
+	for _, entry := range s.live() {
+		list = append(list, entry.endpoint)
+	}
+	s.Unlock()
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Method != list[j].Method {
+			return list[i].Method < list[j].Method
+		}
+		return list[i].Endpoint < list[j].Endpoint
+	})
+	return list
+}
+
+func (s *sliceStore) Routes(method string) []*mockservice.MockEndpoint {
+	s.Lock()
+	defer s.Unlock()
+	routes := []*mockservice.MockEndpoint{}
+	for _, entry := range s.live() {
+		if entry.endpoint.Method == method {
+			routes = append(routes, entry.endpoint)
+		}
+	}
+	return routes
+}
+
+func (s *sliceStore) MatchCount(endpoint *mockservice.MockEndpoint) int {
+	s.Lock()
+	defer s.Unlock()
+	if i := s.index(endpoint); i >= 0 {
+		return s.entries[i].matchCount
+	}
+	return 0
+}
+
+func (s *sliceStore) ExpiresAt(endpoint *mockservice.MockEndpoint) time.Time {
+	s.Lock()
+	defer s.Unlock()
+	if i := s.index(endpoint); i >= 0 {
+		return s.entries[i].expiresAt
+	}
+	return time.Time{}
+}
+
+func (s *sliceStore) Restore(snapshot *mockservice.Snapshot) error {
+	for _, endpoint := range snapshot.Endpoints {
+		if err := endpoint.Validate(); err != nil {
+			return err
+		}
+	}
+	s.Lock()
+	s.entries = nil
+	s.scenarios = map[string]string{}
+	events := []mockservice.StoreEvent{{Op: mockservice.StoreReset}}
+	for i, endpoint := range snapshot.Endpoints {
+		entry := s.entryOf(endpoint)
+		if i < len(snapshot.MatchCounts) {
+			entry.matchCount = snapshot.MatchCounts[i]
+		}
+		if i < len(snapshot.ExpiresAt) && endpoint.TTL > 0 && !snapshot.ExpiresAt[i].IsZero() {
+			entry.expiresAt = snapshot.ExpiresAt[i]
+		}
+		s.entries = append(s.entries, entry)
+		events = append(events, mockservice.StoreEvent{Op: mockservice.StoreCreated, Endpoint: endpoint})
+	}
+	for _, scenario := range snapshot.Scenarios {
+		s.scenarios[scenario.Name] = scenario.State
+	}
+	s.Unlock()
+	s.notify(events...)
+	return nil
+}
+
+func (s *sliceStore) Delete(method, path string) error {
+	s.Lock()
+	remaining := []*sliceEntry{}
+	events := []mockservice.StoreEvent{}
+	for _, entry := range s.entries {
+		if entry.endpoint.Method == method && entry.endpoint.Endpoint == path {
+			events = append(events, mockservice.StoreEvent{Op: mockservice.StoreDeleted, Endpoint: entry.endpoint})
+		} else {
+			remaining = append(remaining, entry)
+		}
+	}
+	s.entries = remaining
+	s.Unlock()
+	if len(events) == 0 {
+		return mockservice.ErrEndpointDoesNotExist
+	}
+	s.notify(events...)
+	return nil
+}
+
+func (s *sliceStore) Reset() {
+	s.Lock()
+	s.entries = nil
+	s.scenarios = map[string]string{}
+	s.Unlock()
+	s.notify(mockservice.StoreEvent{Op: mockservice.StoreReset})
+}
+
+func (s *sliceStore) Scenarios() []mockservice.ScenarioState {
+	s.Lock()
+	defer s.Unlock()
+	names := map[string]bool{}
+	for name := range s.scenarios {
+		names[name] = true
+	}
+	for _, entry := range s.entries {
+		if entry.endpoint.Scenario != "" {
+			names[entry.endpoint.Scenario] = true
+		}
+	}
+	states := []mockservice.ScenarioState{}
+	for name := range names {
+		states = append(states, mockservice.ScenarioState{Name: name, State: s.state(name)})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+func (s *sliceStore) SetScenarioState(name, state string) {
+	s.Lock()
+	s.scenarios[name] = state
+	s.Unlock()
+	s.notify(mockservice.StoreEvent{Op: mockservice.StoreScenario})
+}
+
+func (s *sliceStore) ResetScenarios() {
+	s.Lock()
+	s.scenarios = map[string]string{}
+	s.Unlock()
+	s.notify(mockservice.StoreEvent{Op: mockservice.StoreScenario})
+}
+
+func (s *sliceStore) Watch(watcher func(mockservice.StoreEvent)) (stop func()) {
+	s.Lock()
+	s.watcherID++
+	id := s.watcherID
+	s.watchers[id] = watcher
+	s.Unlock()
+	return func() {
+		s.Lock()
+		delete(s.watchers, id)
+		s.Unlock()
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mockservice")
+	if err != nil {
+		t.Fatalf("Expected creating a temp dir to succeed but got %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	stores := 0
+	storetest.Run(t, func() mockservice.Store {
+		stores++
+		store, err := mockservice.NewFileStore(filepath.Join(dir, strconv.Itoa(stores)+".json"))
+		if err != nil {
+			t.Fatalf("Expected creating the file store to succeed but got %s", err)
+		}
+		return store
+	})
+
+	file := filepath.Join(dir, "mocks.json")
+	store, err := mockservice.NewFileStore(file)
+	if err != nil {
+		t.Fatalf("Expected creating the file store to succeed but got %s", err)
+	}
+	store.Create(mockservice.Get("/saved").WillReturn(http.StatusOK).MustBuild())
+	store.SetScenarioState("cart", "filled")
+	store.Close()
+
+	reopened, err := mockservice.NewFileStore(file)
+	if err != nil {
+		t.Fatalf("Expected reopening the file store to succeed but got %s", err)
+	}
+	defer reopened.Close()
+	if _, err := reopened.Lookup(http.MethodGet, "/saved"); err != nil {
+		t.Errorf("Expected the endpoint saved on change to be restored but got %s", err)
+	}
+	if scenarios := reopened.Scenarios(); len(scenarios) != 1 || scenarios[0].State != "filled" {
+		t.Errorf("Expected the scenario state to be restored but got %+v", scenarios)
+	}
+
+	if err := ioutil.WriteFile(file, []byte("not json"), 0644); err != nil {
+		t.Fatalf("Expected writing the file to succeed but got %s", err)
+	}
+	if _, err := mockservice.NewFileStore(file); err == nil {
+		t.Errorf("Expected an invalid file to return an error but got nil")
+	}
+}
+
+func TestNewWithStore(t *testing.T) {
+	if _, err := mockservice.NewWithStore(" ", mockservice.NewEndpoints()); err != mockservice.ErrEmptyRegistrationEndpoint {
+		t.Errorf("Expected %s error but got %v", mockservice.ErrEmptyRegistrationEndpoint, err)
+	}
+
+	store := newSliceStore()
+	service, err := mockservice.NewWithStore("/mocks", store)
+	if err != nil {
+		t.Fatalf("Expected creating the mock service to succeed but got %s", err)
+	}
+	defer service.Close()
+	if service.Store() != store || service.Endpoints() != nil {
+		t.Errorf("Expected the mock service to serve the store but got %+v", service.Store())
+	}
+
+	if recorder := serve(service, http.MethodPost, "/mocks", `{"method": "GET", "endpoint": "/users", "httpStatusCode": 200, "responseBody": "users"}`); recorder.Code != http.StatusCreated {
+		t.Errorf("Expected the endpoint to be registered but got %d", recorder.Code)
+	}
+	if recorder := serve(service, http.MethodGet, "/users", ""); recorder.Body.String() != "users" {
+		t.Errorf("Expected the endpoint of the store to respond but got %s", recorder.Body.String())
+	}
+	if recorder := serve(service, http.MethodGet, "/mocks/snapshot", ""); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the snapshot of the listed endpoints but got %d", recorder.Code)
+	}
+	serve(service, http.MethodPut, "/mocks/scenarios", `{"name": "cart", "state": "filled"}`)
+	if recorder := serve(service, http.MethodGet, "/mocks/scenarios", ""); recorder.Body.String() != `[{"name":"cart","state":"filled"}]` {
+		t.Errorf("Expected the scenario states of the store but got %s", recorder.Body.String())
+	}
+
+	store.Create(mockservice.Post("/orders/{name}").WillReturn(http.StatusCreated).MustBuild())
+	store.Create(&mockservice.MockEndpoint{Method: http.MethodPost, Endpoint: "/orders/{id}", StatusCode: http.StatusCreated, RequestSchema: &mockservice.RequestSchema{BodyRequired: true}})
+	if recorder := serve(service, http.MethodPost, "/orders/1", ""); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected the request to be validated by the schema of the newest endpoint but got %d", recorder.Code)
+	}
+}
+
+func TestNewWithConf_Store(t *testing.T) {
+	store := newSliceStore()
+	service, err := mockservice.NewWithConf(&mockservice.Conf{
+		RegistrationEndpoint: "/mocks",
+		Endpoints:            []*mockservice.MockEndpoint{mockservice.Get("/configured").WillReturn(http.StatusOK).MustBuild()},
+		Store:                store,
+	})
+	if err != nil {
+		t.Fatalf("Expected creating the mock service to succeed but got %s", err)
+	}
+	defer service.Close()
+	if _, err := store.Lookup(http.MethodGet, "/configured"); service.Store() != store || err != nil {
+		t.Errorf("Expected the configured endpoints to be loaded into the store but got %v", err)
+	}
+}
@@ -0,0 +1,418 @@
+// Package storetest checks that an implementation of mockservice.Store behaves as the mock service expects.
+//
+// Run it from a test of the implementation, with a function creating an empty store for each check:
+//
+//	func TestStore(t *testing.T) {
+//		storetest.Run(t, func() mockservice.Store {
+//			return NewRedisStore(client, prefix)
+//		})
+//	}
+//
+// Stores implementing Close() are closed after each check. Endpoints are compared by their route and response, so
+// stores may return copies of the endpoints they were given.
+package storetest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wchan2/mock_service"
+)
+
+// Run checks the store created by newStore against the behavior of mockservice.Endpoints
+func Run(t *testing.T, newStore func() mockservice.Store) {
+	checks := []struct {
+		name  string
+		check func(t *testing.T, store mockservice.Store)
+	}{
+		{"Create_and_lookup", testCreateAndLookup},
+		{"Create_replaces_same_route", testCreateReplacesSameRoute},
+		{"Update", testUpdate},
+		{"Validation", testValidation},
+		{"Match", testMatch},
+		{"Match_priority", testMatchPriority},
+		{"Max_matches", testMaxMatches},
+		{"TTL", testTTL},
+		{"List", testList},
+		{"Routes", testRoutes},
+		{"Match_count", testMatchCount},
+		{"Expires_at", testExpiresAt},
+		{"Restore", testRestore},
+		{"Scenarios", testScenarios},
+		{"Delete", testDelete},
+		{"Reset", testReset},
+		{"Watch", testWatch},
+	}
+	for _, c := range checks {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			store := newStore()
+			if closer, ok := store.(interface{ Close() }); ok {
+				defer closer.Close()
+			}
+			c.check(t, store)
+		})
+	}
+}
+
+func testCreateAndLookup(t *testing.T, store mockservice.Store) {
+	create(t, store, mockservice.Post("/users").WillReturn(http.StatusCreated).MustBuild())
+	create(t, store, mockservice.Get("/users/{id}").WillReturn(http.StatusOK).MustBuild())
+
+	endpoint, err := store.Lookup(http.MethodPost, "/users")
+	if err != nil || endpoint.StatusCode != http.StatusCreated {
+		t.Errorf("Expected the created endpoint responding %d but got %+v, %v", http.StatusCreated, endpoint, err)
+	}
+	if endpoint, err := store.Lookup(http.MethodGet, "/users/{id}"); err != nil || endpoint.StatusCode != http.StatusOK {
+		t.Errorf("Expected the path template to be looked up by its template but got %+v, %v", endpoint, err)
+	}
+	if _, err := store.Lookup(http.MethodGet, "/users"); err != mockservice.ErrEndpointDoesNotExist {
+		t.Errorf("Expected %s error for another method but got %v", mockservice.ErrEndpointDoesNotExist, err)
+	}
+	if _, err := store.Lookup(http.MethodGet, "/users/42"); err != mockservice.ErrEndpointDoesNotExist {
+		t.Errorf("Expected %s error for a path matching the template but got %v", mockservice.ErrEndpointDoesNotExist, err)
+	}
+}
+
+func testCreateReplacesSameRoute(t *testing.T, store mockservice.Store) {
+	create(t, store, mockservice.Get("/status").WillReturn(http.StatusOK).MustBuild())
+	create(t, store, mockservice.Get("/status").WithHeader("Accept", "text/plain").WillReturn(http.StatusAccepted).MustBuild())
+	create(t, store, mockservice.Get("/status").WillReturn(http.StatusNoContent).MustBuild())
+
+	if list := store.List(); len(list) != 2 {
+		t.Errorf("Expected the endpoint with the same route to be replaced, leaving 2 endpoints, but got %d", len(list))
+	}
+	expectMatch(t, store, httptest.NewRequest(http.MethodGet, "/status", nil), http.StatusNoContent)
+}
+
+func testUpdate(t *testing.T, store mockservice.Store) {
+	if err := store.Update(mockservice.Get("/status").MustBuild()); err != mockservice.ErrEndpointDoesNotExist {
+		t.Errorf("Expected %s error updating a missing endpoint but got %v", mockservice.ErrEndpointDoesNotExist, err)
+	}
+
+	create(t, store, mockservice.Get("/status").WillReturn(http.StatusOK).MustBuild())
+	if err := store.Update(mockservice.Get("/status").WillReturn(http.StatusTeapot).MustBuild()); err != nil {
+		t.Errorf("Expected updating the endpoint to return a nil error but got %s", err)
+	}
+	expectMatch(t, store, httptest.NewRequest(http.MethodGet, "/status", nil), http.StatusTeapot)
+}
+
+func testValidation(t *testing.T, store mockservice.Store) {
+	cases := []struct {
+		name     string
+		endpoint *mockservice.MockEndpoint
+		err      error
+	}{
+		{"Empty_method", &mockservice.MockEndpoint{Endpoint: "/a"}, mockservice.ErrEmptyHTTPMethod},
+		{"Empty_endpoint", &mockservice.MockEndpoint{Method: http.MethodGet}, mockservice.ErrEmptyEndpoint},
+		{"Invalid_path_template", &mockservice.MockEndpoint{Method: http.MethodGet, Endpoint: "/a/{id"}, mockservice.ErrInvalidPathTemplate},
+		{"Negative_limit", &mockservice.MockEndpoint{Method: http.MethodGet, Endpoint: "/a", MaxMatches: -1}, mockservice.ErrInvalidLimit},
+	}
+	for _, c := range cases {
+		if err := store.Create(c.endpoint); err != c.err {
+			t.Errorf("Expected %s creating the endpoint (%s) but got %v", c.err, c.name, err)
+		}
+		if err := store.Update(c.endpoint); err != c.err {
+			t.Errorf("Expected %s updating the endpoint (%s) but got %v", c.err, c.name, err)
+		}
+	}
+	if list := store.List(); len(list) != 0 {
+		t.Errorf("Expected invalid endpoints not to be stored but got %d", len(list))
+	}
+}
+
+func testMatch(t *testing.T, store mockservice.Store) {
+	create(t, store, mockservice.Get("/users/{id}").WillReturn(http.StatusAccepted).MustBuild())
+	create(t, store, mockservice.Get("/users/me").WillReturn(http.StatusOK).MustBuild())
+	create(t, store, mockservice.Get("/users/{name}").WillReturn(http.StatusNonAuthoritativeInfo).MustBuild())
+	create(t, store, mockservice.Post("/users").WithBody(`{"name":"ada"}`).WillReturn(http.StatusCreated).MustBuild())
+
+	expectMatch(t, store, httptest.NewRequest(http.MethodGet, "/users/me", nil), http.StatusOK)
+	expectMatch(t, store, httptest.NewRequest(http.MethodGet, "/users/42", nil), http.StatusNonAuthoritativeInfo)
+	expectMatch(t, store, httptest.NewRequest(http.MethodPost, "/users", nil), 0)
+	req := httptest.NewRequest(http.MethodPost, "/users", nil)
+	if endpoint, err := store.Match(req, []byte(`{"name":"ada"}`)); err != nil || endpoint.StatusCode != http.StatusCreated {
+		t.Errorf("Expected the request body to match the endpoint but got %+v, %v", endpoint, err)
+	}
+	expectMatch(t, store, httptest.NewRequest(http.MethodDelete, "/users/me", nil), 0)
+}
+
+func testMatchPriority(t *testing.T, store mockservice.Store) {
+	create(t, store, mockservice.Get("/orders/{id}").WithPriority(1).WillReturn(http.StatusAccepted).MustBuild())
+	create(t, store, mockservice.Get("/orders/1").WillReturn(http.StatusOK).MustBuild())
+
+	expectMatch(t, store, httptest.NewRequest(http.MethodGet, "/orders/1", nil), http.StatusAccepted)
+}
+
+func testMaxMatches(t *testing.T, store mockservice.Store) {
+	create(t, store, mockservice.Get("/dependency").WillReturn(http.StatusServiceUnavailable).MustBuild())
+	create(t, store, mockservice.Get("/dependency").WithPriority(1).Times(2).WillReturn(http.StatusOK).MustBuild())
+
+	for _, expected := range []int{http.StatusOK, http.StatusOK, http.StatusServiceUnavailable} {
+		expectMatch(t, store, httptest.NewRequest(http.MethodGet, "/dependency", nil), expected)
+	}
+	if list := store.List(); len(list) != 1 {
+		t.Errorf("Expected the endpoint matched its maximum number of times to be removed but got %d endpoints", len(list))
+	}
+}
+
+func testTTL(t *testing.T, store mockservice.Store) {
+	create(t, store, mockservice.Get("/short").WithTTL(20*time.Millisecond).WillReturn(http.StatusOK).MustBuild())
+	create(t, store, mockservice.Get("/long").WithTTL(time.Hour).WillReturn(http.StatusOK).MustBuild())
+
+	expectMatch(t, store, httptest.NewRequest(http.MethodGet, "/short", nil), http.StatusOK)
+	time.Sleep(40 * time.Millisecond)
+	expectMatch(t, store, httptest.NewRequest(http.MethodGet, "/short", nil), 0)
+	if _, err := store.Lookup(http.MethodGet, "/short"); err != mockservice.ErrEndpointDoesNotExist {
+		t.Errorf("Expected the expired endpoint not to be looked up but got %v", err)
+	}
+	if list := store.List(); len(list) != 1 || list[0].Endpoint != "/long" {
+		t.Errorf("Expected only the endpoint that has not expired to be listed but got %+v", list)
+	}
+}
+
+func testList(t *testing.T, store mockservice.Store) {
+	create(t, store, mockservice.Post("/b").MustBuild())
+	create(t, store, mockservice.Get("/b").MustBuild())
+	create(t, store, mockservice.Get("/a").MustBuild())
+
+	list := store.List()
+	expected := []string{"GET /a", "GET /b", "POST /b"}
+	if len(list) != len(expected) {
+		t.Fatalf("Expected %d endpoints but got %d", len(expected), len(list))
+	}
+	for i, endpoint := range list {
+		if route := endpoint.Method + " " + endpoint.Endpoint; route != expected[i] {
+			t.Errorf("Expected endpoint %d to be %s but got %s", i, expected[i], route)
+		}
+	}
+}
+
+func testRoutes(t *testing.T, store mockservice.Store) {
+	create(t, store, mockservice.Get("/b").MustBuild())
+	create(t, store, mockservice.Get("/a").MustBuild())
+	create(t, store, mockservice.Post("/c").MustBuild())
+	create(t, store, mockservice.Get("/expired").WithTTL(time.Nanosecond).MustBuild())
+	time.Sleep(time.Millisecond)
+
+	routes := store.Routes(http.MethodGet)
+	if len(routes) != 2 || routes[0].Endpoint != "/b" || routes[1].Endpoint != "/a" {
+		t.Errorf("Expected the GET endpoints that have not expired in the order they were created but got %+v", routes)
+	}
+	if routes := store.Routes(http.MethodDelete); len(routes) != 0 {
+		t.Errorf("Expected no endpoints for another method but got %+v", routes)
+	}
+}
+
+func testMatchCount(t *testing.T, store mockservice.Store) {
+	endpoint := mockservice.Get("/a").WillReturn(http.StatusOK).MustBuild()
+	create(t, store, endpoint)
+	create(t, store, mockservice.Get("/b").MustBuild())
+
+	expectMatch(t, store, httptest.NewRequest(http.MethodGet, "/a", nil), http.StatusOK)
+	expectMatch(t, store, httptest.NewRequest(http.MethodGet, "/a", nil), http.StatusOK)
+	if count := store.MatchCount(endpoint); count != 2 {
+		t.Errorf("Expected the endpoint to be matched 2 times but got %d", count)
+	}
+	if count := store.MatchCount(mockservice.Get("/b").MustBuild()); count != 0 {
+		t.Errorf("Expected an endpoint with the same route as an unmatched one to be matched 0 times but got %d", count)
+	}
+	if count := store.MatchCount(mockservice.Get("/missing").MustBuild()); count != 0 {
+		t.Errorf("Expected a missing endpoint to be matched 0 times but got %d", count)
+	}
+}
+
+func testExpiresAt(t *testing.T, store mockservice.Store) {
+	before := time.Now()
+	create(t, store, mockservice.Get("/a").WithTTL(time.Hour).MustBuild())
+	after := time.Now()
+	create(t, store, mockservice.Get("/b").MustBuild())
+
+	if expiresAt := store.ExpiresAt(mockservice.Get("/a").MustBuild()); expiresAt.Before(before.Add(time.Hour)) || expiresAt.After(after.Add(time.Hour)) {
+		t.Errorf("Expected the endpoint to expire an hour after it was created but got %s", expiresAt)
+	}
+	if expiresAt := store.ExpiresAt(mockservice.Get("/b").MustBuild()); !expiresAt.IsZero() {
+		t.Errorf("Expected an endpoint without a TTL not to expire but got %s", expiresAt)
+	}
+	if expiresAt := store.ExpiresAt(mockservice.Get("/missing").MustBuild()); !expiresAt.IsZero() {
+		t.Errorf("Expected a missing endpoint not to expire but got %s", expiresAt)
+	}
+}
+
+func testRestore(t *testing.T, store mockservice.Store) {
+	create(t, store, mockservice.Get("/old").MustBuild())
+	store.SetScenarioState("checkout", "paid")
+
+	deadline := time.Now().Add(time.Minute)
+	err := store.Restore(&mockservice.Snapshot{
+		Endpoints: []*mockservice.MockEndpoint{
+			mockservice.Get("/limited").Times(3).WillReturn(http.StatusOK).MustBuild(),
+			mockservice.Get("/expiring").WithTTL(time.Hour).MustBuild(),
+			mockservice.Get("/expired").WithTTL(time.Hour).MustBuild(),
+		},
+		MatchCounts: []int{2, 0, 0},
+		ExpiresAt:   []time.Time{{}, deadline, time.Now().Add(-time.Second)},
+		Scenarios:   []mockservice.ScenarioState{{Name: "cart", State: "filled"}},
+	})
+	if err != nil {
+		t.Fatalf("Expected restoring the snapshot to succeed but got %s", err)
+	}
+	if list := store.List(); len(list) != 2 || list[0].Endpoint != "/expiring" || list[1].Endpoint != "/limited" {
+		t.Errorf("Expected the endpoints of the snapshot that have not expired to replace the others but got %+v", list)
+	}
+	if count := store.MatchCount(mockservice.Get("/limited").MustBuild()); count != 2 {
+		t.Errorf("Expected the match count of the snapshot to be restored but got %d", count)
+	}
+	if expiresAt := store.ExpiresAt(mockservice.Get("/expiring").MustBuild()); expiresAt.Sub(deadline) > time.Second || deadline.Sub(expiresAt) > time.Second {
+		t.Errorf("Expected the TTL expiry of the snapshot to be restored as %s but got %s", deadline, expiresAt)
+	}
+	expectScenarios(t, store, "cart=filled")
+	expectMatch(t, store, httptest.NewRequest(http.MethodGet, "/limited", nil), http.StatusOK)
+	expectMatch(t, store, httptest.NewRequest(http.MethodGet, "/limited", nil), 0)
+
+	invalid := &mockservice.Snapshot{Endpoints: []*mockservice.MockEndpoint{{Method: http.MethodGet}}}
+	if err := store.Restore(invalid); err != mockservice.ErrEmptyEndpoint {
+		t.Errorf("Expected %s error restoring an invalid snapshot but got %v", mockservice.ErrEmptyEndpoint, err)
+	}
+	if list := store.List(); len(list) != 1 || list[0].Endpoint != "/expiring" {
+		t.Errorf("Expected an invalid snapshot to leave the endpoints untouched but got %+v", list)
+	}
+}
+
+func testScenarios(t *testing.T, store mockservice.Store) {
+	create(t, store, &mockservice.MockEndpoint{Method: http.MethodGet, Endpoint: "/cart", StatusCode: http.StatusNoContent, Scenario: "cart", RequiredState: mockservice.ScenarioStarted})
+	create(t, store, &mockservice.MockEndpoint{Method: http.MethodPost, Endpoint: "/cart", StatusCode: http.StatusCreated, Scenario: "cart", NewState: "filled"})
+	create(t, store, &mockservice.MockEndpoint{Method: http.MethodGet, Endpoint: "/cart", StatusCode: http.StatusOK, Scenario: "cart", RequiredState: "filled"})
+
+	expectMatch(t, store, httptest.NewRequest(http.MethodGet, "/cart", nil), http.StatusNoContent)
+	expectMatch(t, store, httptest.NewRequest(http.MethodPost, "/cart", nil), http.StatusCreated)
+	expectMatch(t, store, httptest.NewRequest(http.MethodGet, "/cart", nil), http.StatusOK)
+	expectScenarios(t, store, "cart=filled")
+
+	store.SetScenarioState("checkout", "paid")
+	expectScenarios(t, store, "cart=filled", "checkout=paid")
+
+	store.ResetScenarios()
+	expectScenarios(t, store, "cart="+mockservice.ScenarioStarted)
+	expectMatch(t, store, httptest.NewRequest(http.MethodGet, "/cart", nil), http.StatusNoContent)
+}
+
+func testDelete(t *testing.T, store mockservice.Store) {
+	create(t, store, mockservice.Get("/a").MustBuild())
+	create(t, store, mockservice.Get("/a").WithQueryParameter("page", "2").MustBuild())
+	create(t, store, mockservice.Get("/b").MustBuild())
+
+	if err := store.Delete(http.MethodGet, "/a"); err != nil {
+		t.Errorf("Expected deleting the endpoints to return a nil error but got %s", err)
+	}
+	if list := store.List(); len(list) != 1 || list[0].Endpoint != "/b" {
+		t.Errorf("Expected all the endpoints for the path to be deleted but got %+v", list)
+	}
+	if err := store.Delete(http.MethodGet, "/a"); err != mockservice.ErrEndpointDoesNotExist {
+		t.Errorf("Expected %s error deleting a missing endpoint but got %v", mockservice.ErrEndpointDoesNotExist, err)
+	}
+}
+
+func testReset(t *testing.T, store mockservice.Store) {
+	create(t, store, mockservice.Get("/a").MustBuild())
+	create(t, store, mockservice.Post("/b").MustBuild())
+
+	store.Reset()
+	if list := store.List(); len(list) != 0 {
+		t.Errorf("Expected no endpoints after a reset but got %d", len(list))
+	}
+	expectMatch(t, store, httptest.NewRequest(http.MethodGet, "/a", nil), 0)
+}
+
+func testWatch(t *testing.T, store mockservice.Store) {
+	var lock sync.Mutex
+	events := []mockservice.StoreEvent{}
+	stop := store.Watch(func(event mockservice.StoreEvent) {
+		lock.Lock()
+		defer lock.Unlock()
+		events = append(events, event)
+	})
+
+	create(t, store, mockservice.Get("/a").Times(1).MustBuild())
+	store.Update(mockservice.Get("/a").Times(1).WillReturn(http.StatusAccepted).MustBuild())
+	expectMatch(t, store, httptest.NewRequest(http.MethodGet, "/a", nil), http.StatusAccepted)
+	create(t, store, mockservice.Get("/b").MustBuild())
+	store.Delete(http.MethodGet, "/b")
+	store.SetScenarioState("cart", "filled")
+	store.Reset()
+	expected := []mockservice.StoreOp{
+		mockservice.StoreCreated,
+		mockservice.StoreUpdated,
+		mockservice.StoreMatched,
+		mockservice.StoreDeleted,
+		mockservice.StoreCreated,
+		mockservice.StoreDeleted,
+		mockservice.StoreScenario,
+		mockservice.StoreReset,
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for received(&lock, &events) < len(expected) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+	create(t, store, mockservice.Get("/c").MustBuild())
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events until the watcher is stopped but got %+v", len(expected), events)
+	}
+	for i, event := range events {
+		if event.Op != expected[i] {
+			t.Errorf("Expected event %d to be %s but got %s", i, expected[i], event.Op)
+		}
+		if event.Op != mockservice.StoreReset && event.Op != mockservice.StoreScenario && event.Endpoint == nil {
+			t.Errorf("Expected event %d to have its endpoint but got none", i)
+		}
+	}
+}
+
+func create(t *testing.T, store mockservice.Store, endpoint *mockservice.MockEndpoint) {
+	t.Helper()
+	if err := store.Create(endpoint); err != nil {
+		t.Fatalf("Unable to create endpoint %s %s: %s", endpoint.Method, endpoint.Endpoint, err)
+	}
+}
+
+// expectMatch checks that the request matches an endpoint responding the status code, or none when it is 0
+func expectMatch(t *testing.T, store mockservice.Store, req *http.Request, statusCode int) {
+	t.Helper()
+	endpoint, err := store.Match(req, nil)
+	if statusCode == 0 {
+		if err != mockservice.ErrEndpointDoesNotExist {
+			t.Errorf("Expected %s %s not to match but got %+v, %v", req.Method, req.URL.Path, endpoint, err)
+		}
+		return
+	}
+	if err != nil || endpoint.StatusCode != statusCode {
+		t.Errorf("Expected %s %s to match the endpoint responding %d but got %+v, %v", req.Method, req.URL.Path, statusCode, endpoint, err)
+	}
+}
+
+// expectScenarios checks the states of the scenarios, given as name=state sorted by name
+func expectScenarios(t *testing.T, store mockservice.Store, expected ...string) {
+	t.Helper()
+	states := []string{}
+	for _, scenario := range store.Scenarios() {
+		states = append(states, scenario.Name+"="+scenario.State)
+	}
+	if strings.Join(states, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected scenarios %v but got %v", expected, states)
+	}
+}
+
+func received(lock *sync.Mutex, events *[]mockservice.StoreEvent) int {
+	lock.Lock()
+	defer lock.Unlock()
+	return len(*events)
+}
@@ -0,0 +1,70 @@
+package mockservice
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRegistrationEndpoint is the registration endpoint of the mock service started by NewTestServer
+const TestRegistrationEndpoint = "/mocks"
+
+// TestServer is an httptest.Server serving a MockService for the duration of a test
+type TestServer struct {
+	*httptest.Server
+	Service *MockService
+	t       testing.TB
+}
+
+// NewTestServer starts a mock service for the test. When the test finishes, the server is closed and the test fails
+// if a mock endpoint marked as expected was never matched or if a request did not match any mock endpoint.
+func NewTestServer(t testing.TB) *TestServer {
+	t.Helper()
+	service, err := New(TestRegistrationEndpoint)
+	if err != nil {
+		t.Fatalf("Unable to create mock service: %s", err)
+	}
+
+	s := &TestServer{Server: httptest.NewServer(service), Service: service, t: t}
+	t.Cleanup(func() {
+		s.Close()
+		s.Service.Close()
+		s.verify()
+	})
+	return s
+}
+
+// Register creates the mock endpoints, failing the test if any of them is invalid
+func (s *TestServer) Register(endpoints ...*MockEndpoint) {
+	s.t.Helper()
+	for _, endpoint := range endpoints {
+		if err := s.Service.Store().Create(endpoint); err != nil {
+			s.t.Fatalf("Unable to register mock endpoint %s %s: %s", endpoint.Method, endpoint.Endpoint, err)
+		}
+	}
+}
+
+// RegistrationURL is the URL of the registration endpoint, to be used with the client package
+func (s *TestServer) RegistrationURL() string {
+	return s.URL + TestRegistrationEndpoint
+}
+
+// verify checks the expectations of the mock service and of the sessions created by the test
+func (s *TestServer) verify() {
+	s.t.Helper()
+	for _, service := range append([]*MockService{s.Service}, s.Service.sessionServices()...) {
+		for _, endpoint := range service.Endpoints().Unsatisfied() {
+			s.t.Errorf("Expected mock endpoint %s %s to be requested but it was not", endpoint.Method, endpoint.Endpoint)
+		}
+		unmatched := service.Journal().Unmatched()
+		for _, req := range unmatched {
+			target := req.Endpoint
+			if req.Query != "" {
+				target += "?" + req.Query
+			}
+			s.t.Errorf("Received request %s %s that did not match any mock endpoint", req.Method, target)
+		}
+		if dropped := service.Journal().UnmatchedCount() - len(unmatched); dropped > 0 {
+			s.t.Errorf("Received %d more requests that did not match any mock endpoint, dropped from the journal", dropped)
+		}
+	}
+}
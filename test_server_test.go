@@ -0,0 +1,123 @@
+package mockservice_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/wchan2/mock_service"
+)
+
+// recordingT records the failures and cleanups of a test instead of applying them
+type recordingT struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingT) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+func (r *recordingT) finish() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+}
+
+func TestNewTestServer(t *testing.T) {
+	t.Run("Expectations_met", func(t *testing.T) {
+		rt := &recordingT{TB: t}
+		server := mockservice.NewTestServer(rt)
+		server.Register(mockservice.Get("/users/{id}").Expected().WillReturn(http.StatusOK).MustBuild())
+
+		resp, err := server.Client().Get(server.URL + "/users/1")
+		if err != nil {
+			t.Fatalf("Expected requesting the mock to succeed but got %s", err)
+		}
+		resp.Body.Close()
+
+		rt.finish()
+		if len(rt.errors) != 0 {
+			t.Errorf("Expected no failures but got %v", rt.errors)
+		}
+	})
+
+	t.Run("Expected_endpoint_not_requested_and_unmatched_request", func(t *testing.T) {
+		rt := &recordingT{TB: t}
+		server := mockservice.NewTestServer(rt)
+		server.Register(mockservice.Post("/orders").Expected().WillReturn(http.StatusCreated).MustBuild())
+
+		resp, err := server.Client().Get(server.URL + "/unknown?foo=bar")
+		if err != nil {
+			t.Fatalf("Expected requesting the mock service to succeed but got %s", err)
+		}
+		resp.Body.Close()
+
+		rt.finish()
+		expected := []string{
+			"Expected mock endpoint POST /orders to be requested but it was not",
+			"Received request GET /unknown?foo=bar that did not match any mock endpoint",
+		}
+		if fmt.Sprint(rt.errors) != fmt.Sprint(expected) {
+			t.Errorf("Expected failures %v but got %v", expected, rt.errors)
+		}
+
+		if _, err := server.Client().Get(server.URL + "/orders"); err == nil {
+			t.Errorf("Expected the server to be closed after the test finished")
+		}
+	})
+
+	t.Run("Expected_endpoint_of_session_not_requested", func(t *testing.T) {
+		rt := &recordingT{TB: t}
+		server := mockservice.NewTestServer(rt)
+		server.Service.Session("checkout").Endpoints().Create(mockservice.Get("/cart").Expected().WillReturn(http.StatusOK).MustBuild())
+
+		rt.finish()
+		expected := []string{"Expected mock endpoint GET /cart to be requested but it was not"}
+		if fmt.Sprint(rt.errors) != fmt.Sprint(expected) {
+			t.Errorf("Expected failures %v but got %v", expected, rt.errors)
+		}
+	})
+
+	t.Run("Expected_endpoint_expired_without_request", func(t *testing.T) {
+		rt := &recordingT{TB: t}
+		server := mockservice.NewTestServer(rt)
+		server.Register(mockservice.Get("/coupon").Expected().WithTTL(10 * time.Millisecond).WillReturn(http.StatusOK).MustBuild())
+		time.Sleep(50 * time.Millisecond)
+
+		rt.finish()
+		expected := []string{"Expected mock endpoint GET /coupon to be requested but it was not"}
+		if fmt.Sprint(rt.errors) != fmt.Sprint(expected) {
+			t.Errorf("Expected failures %v but got %v", expected, rt.errors)
+		}
+	})
+	t.Run("Unmatched_requests_dropped_from_the_journal", func(t *testing.T) {
+		rt := &recordingT{TB: t}
+		server := mockservice.NewTestServer(rt)
+		server.Service.Journal().Limit(1)
+		for _, path := range []string{"/first", "/second", "/third"} {
+			resp, err := server.Client().Get(server.URL + path)
+			if err != nil {
+				t.Fatalf("Expected requesting the mock service to succeed but got %s", err)
+			}
+			resp.Body.Close()
+		}
+
+		rt.finish()
+		expected := []string{
+			"Received request GET /third that did not match any mock endpoint",
+			"Received 2 more requests that did not match any mock endpoint, dropped from the journal",
+		}
+		if fmt.Sprint(rt.errors) != fmt.Sprint(expected) {
+			t.Errorf("Expected failures %v but got %v", expected, rt.errors)
+		}
+	})
+}
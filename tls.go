@@ -0,0 +1,153 @@
+package mockservice
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultTLSHosts are the host names a leaf certificate is issued for when none are provided
+var DefaultTLSHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// ErrNoCertificateAuthority is returned when the certificate authority is requested from a mock service that has none
+var ErrNoCertificateAuthority = errors.New("No certificate authority configured")
+
+// CertificateAuthority is a self-signed certificate authority that issues leaf certificates to serve mocks over HTTPS.
+// Clients under test trust the mock service by trusting the certificate authority's certificate.
+type CertificateAuthority struct {
+	Certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+}
+
+// NewCertificateAuthority generates a self-signed certificate authority valid for a year
+func NewCertificateAuthority() (*CertificateAuthority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template, err := certificateTemplate("mockservice CA")
+	if err != nil {
+		return nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &CertificateAuthority{Certificate: cert, key: key}, nil
+}
+
+// CertificatePEM returns the PEM encoded certificate of the certificate authority
+func (ca *CertificateAuthority) CertificatePEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate.Raw})
+}
+
+// CertPool returns a certificate pool trusting the certificate authority, to be used by clients under test
+func (ca *CertificateAuthority) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Certificate)
+	return pool
+}
+
+// IssueCertificate issues a leaf certificate for the host names and IP addresses, or DefaultTLSHosts when none are provided
+func (ca *CertificateAuthority) IssueCertificate(hosts ...string) (tls.Certificate, error) {
+	if len(hosts) == 0 {
+		hosts = DefaultTLSHosts
+	}
+	return ca.issue(hosts[0], x509.ExtKeyUsageServerAuth, hosts)
+}
+
+// IssueClientCertificate issues a client certificate with the subject common name and subject alternative names,
+// which are host names, IP addresses, email addresses or URIs
+func (ca *CertificateAuthority) IssueClientCertificate(commonName string, sans ...string) (tls.Certificate, error) {
+	return ca.issue(commonName, x509.ExtKeyUsageClientAuth, sans)
+}
+
+func (ca *CertificateAuthority) issue(commonName string, usage x509.ExtKeyUsage, sans []string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template, err := certificateTemplate(commonName)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+	for _, san := range sans {
+		if ip := net.ParseIP(san); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if strings.Contains(san, "@") {
+			template.EmailAddresses = append(template.EmailAddresses, san)
+		} else if uri, err := url.Parse(san); err == nil && uri.Scheme != "" {
+			template.URIs = append(template.URIs, uri)
+		} else {
+			template.DNSNames = append(template.DNSNames, san)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Certificate, &key.PublicKey, ca.key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{
+		Certificate: [][]byte{der, ca.Certificate.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
+
+// TLSConfig returns a server TLS configuration with a leaf certificate issued for the hosts
+func (ca *CertificateAuthority) TLSConfig(hosts ...string) (*tls.Config, error) {
+	cert, err := ca.IssueCertificate(hosts...)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// RequestClientCertificates makes the server TLS configuration ask clients for a certificate so mock endpoints can
+// match on it. Any certificate is accepted when clientCAs is nil, otherwise presented certificates must be issued by
+// one of the clientCAs. Clients that present no certificate are always accepted.
+func RequestClientCertificates(config *tls.Config, clientCAs *x509.CertPool) {
+	config.ClientAuth = tls.RequestClientCert
+	if clientCAs != nil {
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		config.ClientCAs = clientCAs
+	}
+}
+
+func certificateTemplate(commonName string) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"mockservice"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(1, 0, 0),
+	}, nil
+}
@@ -0,0 +1,80 @@
+package mockservice_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/wchan2/mock_service"
+)
+
+func TestCertificateAuthority(t *testing.T) {
+	ca, err := mockservice.NewCertificateAuthority()
+	if err != nil {
+		t.Fatalf("Expected generating a certificate authority to succeed but got %s", err)
+	}
+
+	t.Run("Issue_certificate", func(t *testing.T) {
+		cert, err := ca.IssueCertificate("mock.local", "10.0.0.1")
+		if err != nil {
+			t.Fatalf("Expected issuing a certificate to succeed but got %s", err)
+		}
+
+		if len(cert.Leaf.DNSNames) != 1 || cert.Leaf.DNSNames[0] != "mock.local" || len(cert.Leaf.IPAddresses) != 1 {
+			t.Errorf("Expected the certificate for mock.local and 10.0.0.1 but got %v %v", cert.Leaf.DNSNames, cert.Leaf.IPAddresses)
+		}
+
+		if _, err := cert.Leaf.Verify(x509.VerifyOptions{DNSName: "mock.local", Roots: ca.CertPool()}); err != nil {
+			t.Errorf("Expected the certificate to be verified by the certificate authority but got %s", err)
+		}
+	})
+
+	t.Run("Serve_HTTPS_and_export_CA", func(t *testing.T) {
+		service, err := mockservice.New("/mocks")
+		if err != nil {
+			t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+		}
+		service.UseCertificateAuthority(ca)
+		service.Endpoints().Create(mockservice.Get("/secure").WillReturn(http.StatusOK).WithBody("secret").MustBuild())
+
+		server := httptest.NewUnstartedServer(service)
+		if server.TLS, err = ca.TLSConfig(); err != nil {
+			t.Fatalf("Expected creating the TLS config to succeed but got %s", err)
+		}
+		server.StartTLS()
+		defer server.Close()
+
+		insecure := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+		resp, err := insecure.Get(server.URL + "/mocks/ca.pem")
+		if err != nil {
+			t.Fatalf("Expected downloading the certificate authority to succeed but got %s", err)
+		}
+		pem, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			t.Fatalf("Expected a PEM encoded certificate but got %s", pem)
+		}
+		trusting := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+		resp, err = trusting.Get(server.URL + "/secure")
+		if err != nil {
+			t.Fatalf("Expected the client trusting the certificate authority to succeed but got %s", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "secret" {
+			t.Errorf(`Expected "secret" response body but got "%s"`, body)
+		}
+	})
+
+	t.Run("No_certificate_authority", func(t *testing.T) {
+		service, _ := mockservice.New("/mocks")
+		if recorder := serve(service, http.MethodGet, "/mocks/ca.pem", ""); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected %d status but got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+}
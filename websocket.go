@@ -0,0 +1,394 @@
+package mockservice
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+
+	maxWebSocketMessage = 16 << 20
+
+	closeProtocolError  = 1002
+	closeMessageTooBig  = 1009
+	maxControlFrameSize = 125
+)
+
+var (
+	// ErrWebSocketMessageTooLarge is returned when a client sends a WebSocket message larger than 16MB
+	ErrWebSocketMessageTooLarge = errors.New("WebSocket message too large")
+
+	// ErrUnmaskedWebSocketFrame is returned when a client sends a WebSocket frame without masking it
+	ErrUnmaskedWebSocketFrame = errors.New("WebSocket frame from client is not masked")
+
+	// ErrInvalidWebSocketFrame is returned when a client sends a fragmented or oversized control frame, a continuation
+	// frame outside a fragmented message, a new message inside one, or a frame with an unknown opcode
+	ErrInvalidWebSocketFrame = errors.New("Invalid WebSocket frame sequence")
+)
+
+// WebSocketScript is the conversation a WebSocket mock endpoint holds with each connected client
+type WebSocketScript struct {
+	// OnConnect are the messages sent once the connection is established
+	OnConnect []WebSocketMessage `json:"onConnect,omitempty" xml:"onConnect,omitempty"`
+	// Rules reply to client messages; the first rule matching a message sends its replies
+	Rules []WebSocketRule `json:"rules,omitempty" xml:"rules,omitempty"`
+}
+
+// WebSocketRule replies to client messages matched by all of its body matchers
+type WebSocketRule struct {
+	Match []BodyMatcher      `json:"match" xml:"match"`
+	Reply []WebSocketMessage `json:"reply" xml:"reply"`
+}
+
+// WebSocketMessage is a text message sent to a WebSocket client
+type WebSocketMessage struct {
+	Text string `json:"text" xml:"text"`
+	// Delay is the time waited before the message is sent
+	Delay Duration `json:"delay,omitempty" xml:"delay,omitempty"`
+}
+
+// RecordedFrame is a WebSocket message received from a client of a mock endpoint
+type RecordedFrame struct {
+	Time     time.Time `json:"time" xml:"time"`
+	Endpoint string    `json:"endpoint" xml:"endpoint"`
+	Payload  string    `json:"payload" xml:"payload"`
+	Binary   bool      `json:"binary,omitempty" xml:"binary,omitempty"`
+}
+
+func (s *WebSocketScript) validate() error {
+	for _, rule := range s.Rules {
+		for i := range rule.Match {
+			if err := rule.Match[i].validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isWebSocketUpgrade reports whether the request asks to upgrade the connection to a WebSocket
+func isWebSocketUpgrade(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get("Upgrade"), "websocket") && headerContainsToken(req.Header, "Connection", "upgrade")
+}
+
+func headerContainsToken(header http.Header, key, token string) bool {
+	for _, val := range header.Values(key) {
+		for _, part := range strings.Split(val, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func webSocketAccept(key string) string {
+	hash := sha1.Sum([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// webSocketConn is a server side WebSocket connection
+type webSocketConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	sync.Mutex
+}
+
+// upgrade completes the WebSocket handshake and takes over the connection
+func upgrade(w http.ResponseWriter, req *http.Request) (*webSocketConn, error) {
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if !isWebSocketUpgrade(req) || key == "" || req.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Expected a WebSocket version 13 upgrade request", http.StatusUpgradeRequired)
+		return nil, errors.New("not a WebSocket upgrade request")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket upgrades require HTTP/1.1", http.StatusHTTPVersionNotSupported)
+		return nil, errors.New("connection cannot be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	rw.WriteString("Upgrade: websocket\r\n")
+	rw.WriteString("Connection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + webSocketAccept(key) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &webSocketConn{conn: conn, reader: rw.Reader}, nil
+}
+
+// writeFrame sends a single unfragmented frame
+func (c *webSocketConn) writeFrame(opcode byte, payload []byte) error {
+	c.Lock()
+	defer c.Unlock()
+	header := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		header = append(header, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(len(payload)))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(len(payload)))
+	}
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readFrame reads a single frame sent by the client, unmasking its payload
+func (c *webSocketConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	header := make([]byte, 2)
+	if _, err = io.ReadFull(c.reader, header); err != nil {
+		return
+	}
+	fin, opcode = header[0]&0x80 != 0, header[0]&0x0F
+	if header[1]&0x80 == 0 {
+		err = ErrUnmaskedWebSocketFrame
+		return
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		if _, err = io.ReadFull(c.reader, extended); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		if _, err = io.ReadFull(c.reader, extended); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(extended)
+	}
+	if length > maxWebSocketMessage {
+		err = ErrWebSocketMessageTooLarge
+		return
+	}
+
+	mask := make([]byte, 4)
+	if _, err = io.ReadFull(c.reader, mask); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.reader, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// readMessage reads the next text or binary message, answering pings and closes along the way. The connection is closed
+// with a protocol error status when the client breaks the framing rules.
+func (c *webSocketConn) readMessage() (binaryMessage bool, message []byte, err error) {
+	binaryMessage, message, err = c.readFrames()
+	switch err {
+	case ErrUnmaskedWebSocketFrame, ErrInvalidWebSocketFrame:
+		c.close(closeProtocolError)
+	case ErrWebSocketMessageTooLarge:
+		c.close(closeMessageTooBig)
+	}
+	return binaryMessage, message, err
+}
+
+// readFrames reads the frames of the next text or binary message
+func (c *webSocketConn) readFrames() (binaryMessage bool, message []byte, err error) {
+	var messageOpcode byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return false, nil, err
+		}
+		if opcode >= opClose && (!fin || len(payload) > maxControlFrameSize) {
+			return false, nil, ErrInvalidWebSocketFrame
+		}
+		switch opcode {
+		case opPing:
+			c.writeFrame(opPong, payload)
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.writeFrame(opClose, payload)
+			return false, nil, io.EOF
+		case opText, opBinary:
+			if messageOpcode != 0 {
+				return false, nil, ErrInvalidWebSocketFrame
+			}
+			messageOpcode, message = opcode, payload
+		case opContinuation:
+			if messageOpcode == 0 {
+				return false, nil, ErrInvalidWebSocketFrame
+			}
+			message = append(message, payload...)
+		default:
+			return false, nil, ErrInvalidWebSocketFrame
+		}
+		if len(message) > maxWebSocketMessage {
+			return false, nil, ErrWebSocketMessageTooLarge
+		}
+		if fin {
+			return messageOpcode == opBinary, message, nil
+		}
+	}
+}
+
+func (c *webSocketConn) sendText(text string) error {
+	return c.writeFrame(opText, []byte(text))
+}
+
+// close sends a close frame with the status code
+func (c *webSocketConn) close(code uint16) {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, code)
+	c.writeFrame(opClose, payload)
+}
+
+// socketHub tracks the clients connected to WebSocket mock endpoints so messages can be pushed to them
+type socketHub struct {
+	clients map[string]map[*webSocketConn]struct{}
+	sync.Mutex
+}
+
+func newSocketHub() *socketHub {
+	return &socketHub{clients: map[string]map[*webSocketConn]struct{}{}}
+}
+
+func (h *socketHub) add(key string, conn *webSocketConn) {
+	h.Lock()
+	if h.clients[key] == nil {
+		h.clients[key] = map[*webSocketConn]struct{}{}
+	}
+	h.clients[key][conn] = struct{}{}
+	h.Unlock()
+}
+
+func (h *socketHub) remove(key string, conn *webSocketConn) {
+	h.Lock()
+	delete(h.clients[key], conn)
+	h.Unlock()
+}
+
+// push sends the text message to the clients connected to the mock endpoint and returns how many received it
+func (h *socketHub) push(method, endpoint, text string) int {
+	h.Lock()
+	conns := []*webSocketConn{}
+	for conn := range h.clients[eventHubKey(method, endpoint)] {
+		conns = append(conns, conn)
+	}
+	h.Unlock()
+
+	sent := 0
+	for _, conn := range conns {
+		if conn.sendText(text) == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// converse upgrades the request and holds the endpoint's scripted conversation until the client disconnects
+func (h *socketHub) converse(w http.ResponseWriter, req *http.Request, endpoint *MockEndpoint, journal *Journal) {
+	conn, err := upgrade(w, req)
+	if err != nil {
+		log.Printf("Unable to upgrade %s to a WebSocket: %s", req.URL.Path, err)
+		return
+	}
+	defer conn.conn.Close()
+
+	key := eventHubKey(endpoint.Method, endpoint.Endpoint)
+	h.add(key, conn)
+	defer h.remove(key, conn)
+
+	// a single writer sends the scripted messages so they arrive in order: the messages sent on connect, then the
+	// replies to each client message in the order they were received
+	script := endpoint.WebSocket
+	queue := make(chan []WebSocketMessage, 16)
+	closed, stopped := make(chan struct{}), make(chan struct{})
+	defer close(closed)
+	go func() {
+		defer close(stopped)
+		sendMessages(conn, queue, closed)
+	}()
+	enqueue := func(messages []WebSocketMessage) {
+		select {
+		case queue <- messages:
+		case <-stopped:
+		}
+	}
+
+	enqueue(script.OnConnect)
+	for {
+		binaryMessage, message, err := conn.readMessage()
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("Closing WebSocket %s: %s", req.URL.Path, err)
+			}
+			return
+		}
+		if journal != nil {
+			journal.RecordFrame(&RecordedFrame{Time: time.Now(), Endpoint: req.URL.Path, Payload: string(message), Binary: binaryMessage})
+		}
+
+		for _, rule := range script.Rules {
+			if matchBody(rule.Match, message) {
+				enqueue(rule.Reply)
+				break
+			}
+		}
+	}
+}
+
+// sendMessages sends the queued messages in order, each after its delay, until the conversation is closed or sending
+// fails
+func sendMessages(conn *webSocketConn, queue <-chan []WebSocketMessage, closed <-chan struct{}) {
+	for {
+		select {
+		case <-closed:
+			return
+		case messages := <-queue:
+			for _, message := range messages {
+				if message.Delay > 0 {
+					select {
+					case <-time.After(time.Duration(message.Delay)):
+					case <-closed:
+						return
+					}
+				}
+				if err := conn.sendText(message.Text); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
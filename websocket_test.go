@@ -0,0 +1,222 @@
+package mockservice_test
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wchan2/mock_service"
+)
+
+// testWebSocket is a minimal WebSocket client for exercising WebSocket mock endpoints
+type testWebSocket struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialWebSocket(t *testing.T, server *httptest.Server, path string) *testWebSocket {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("Expected dialing the server to succeed but got %s", err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	key := make([]byte, 16)
+	rand.Read(key)
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", path, base64.StdEncoding.EncodeToString(key))
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Expected a handshake response but got %s", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected %d status but got %d", http.StatusSwitchingProtocols, resp.StatusCode)
+	}
+	return &testWebSocket{conn: conn, reader: reader}
+}
+
+func (ws *testWebSocket) send(t *testing.T, text string) {
+	ws.sendFrame(t, 0x81, text)
+}
+
+// sendFrame sends a single masked frame with the first header byte holding the FIN bit and opcode
+func (ws *testWebSocket) sendFrame(t *testing.T, first byte, text string) {
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{first, 0x80 | byte(len(text))}
+	frame = append(frame, mask...)
+	for i := 0; i < len(text); i++ {
+		frame = append(frame, text[i]^mask[i%4])
+	}
+	if _, err := ws.conn.Write(frame); err != nil {
+		t.Fatalf("Expected sending a message to succeed but got %s", err)
+	}
+}
+
+func (ws *testWebSocket) receive(t *testing.T) string {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(ws.reader, header); err != nil {
+		t.Fatalf("Expected to receive a message but got %s", err)
+	}
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		extended := make([]byte, 2)
+		io.ReadFull(ws.reader, extended)
+		length = int(binary.BigEndian.Uint16(extended))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.reader, payload); err != nil {
+		t.Fatalf("Expected to receive a message payload but got %s", err)
+	}
+	return string(payload)
+}
+
+func TestWebSocket(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	err = service.Endpoints().Create(&mockservice.MockEndpoint{
+		Method:   http.MethodGet,
+		Endpoint: "/prices",
+		WebSocket: &mockservice.WebSocketScript{
+			OnConnect: []mockservice.WebSocketMessage{{Text: "welcome"}},
+			Rules: []mockservice.WebSocketRule{
+				{
+					Match: []mockservice.BodyMatcher{{EqualToJSON: []byte(`{"subscribe": "EURUSD"}`)}},
+					Reply: []mockservice.WebSocketMessage{{Text: `{"EURUSD": 1.1}`}, {Text: `{"EURUSD": 1.2}`, Delay: mockservice.Duration(10 * time.Millisecond)}},
+				},
+				{
+					Match: []mockservice.BodyMatcher{{Matches: "^ping"}},
+					Reply: []mockservice.WebSocketMessage{{Text: "pong"}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected creating the WebSocket endpoint to succeed but got %s", err)
+	}
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	ws := dialWebSocket(t, server, "/prices")
+	defer ws.conn.Close()
+
+	if message := ws.receive(t); message != "welcome" {
+		t.Errorf(`Expected "welcome" on connect but got "%s"`, message)
+	}
+
+	ws.send(t, `{ "subscribe" : "EURUSD" }`)
+	if message := ws.receive(t); message != `{"EURUSD": 1.1}` {
+		t.Errorf("Expected the first price but got %s", message)
+	}
+	if message := ws.receive(t); message != `{"EURUSD": 1.2}` {
+		t.Errorf("Expected the second price but got %s", message)
+	}
+
+	ws.send(t, "ping 1")
+	if message := ws.receive(t); message != "pong" {
+		t.Errorf(`Expected "pong" but got "%s"`, message)
+	}
+
+	pushed := serve(service, http.MethodPost, "/mocks/websocket?endpoint=/prices", "market closed")
+	if pushed.Body.String() != `{"clients":1}` {
+		t.Errorf("Expected the message to be pushed to 1 client but got %s", pushed.Body.String())
+	}
+	if message := ws.receive(t); message != "market closed" {
+		t.Errorf(`Expected the pushed message but got "%s"`, message)
+	}
+
+	frames := service.Journal().Frames()
+	if len(frames) != 2 || frames[0].Payload != `{ "subscribe" : "EURUSD" }` || frames[1].Payload != "ping 1" || frames[1].Endpoint != "/prices" {
+		t.Errorf("Expected the received messages to be recorded but got %+v", frames)
+	}
+
+	t.Run("Not_an_upgrade_request", func(t *testing.T) {
+		if recorder := serve(service, http.MethodGet, "/prices", ""); recorder.Code != http.StatusUpgradeRequired {
+			t.Errorf("Expected %d status but got %d", http.StatusUpgradeRequired, recorder.Code)
+		}
+	})
+
+	t.Run("Invalid_rule", func(t *testing.T) {
+		err := service.Endpoints().Create(&mockservice.MockEndpoint{
+			Method:    http.MethodGet,
+			Endpoint:  "/invalid",
+			WebSocket: &mockservice.WebSocketScript{Rules: []mockservice.WebSocketRule{{Match: []mockservice.BodyMatcher{{Matches: "("}}}}},
+		})
+		if err == nil {
+			t.Errorf("Expected an error for an invalid regular expression")
+		}
+	})
+}
+
+func TestWebSocket_Ordering(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	service.Endpoints().Create(&mockservice.MockEndpoint{
+		Method:   http.MethodGet,
+		Endpoint: "/chat",
+		WebSocket: &mockservice.WebSocketScript{
+			OnConnect: []mockservice.WebSocketMessage{{Text: "welcome", Delay: mockservice.Duration(30 * time.Millisecond)}},
+			Rules: []mockservice.WebSocketRule{
+				{Match: []mockservice.BodyMatcher{{Matches: "^slow"}}, Reply: []mockservice.WebSocketMessage{{Text: "slow reply", Delay: mockservice.Duration(30 * time.Millisecond)}}},
+				{Match: []mockservice.BodyMatcher{{Matches: "^fast"}}, Reply: []mockservice.WebSocketMessage{{Text: "fast reply"}}},
+			},
+		},
+	})
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	ws := dialWebSocket(t, server, "/chat")
+	defer ws.conn.Close()
+	ws.send(t, "slow")
+	ws.send(t, "fast")
+	for _, expected := range []string{"welcome", "slow reply", "fast reply"} {
+		if message := ws.receive(t); message != expected {
+			t.Errorf(`Expected "%s" but got "%s"`, expected, message)
+		}
+	}
+}
+
+func TestWebSocket_InvalidFrames(t *testing.T) {
+	service, err := mockservice.New("/mocks")
+	if err != nil {
+		t.Fatalf("Expected err in creating new mock service to be nil but got %s", err)
+	}
+	service.Endpoints().Create(&mockservice.MockEndpoint{Method: http.MethodGet, Endpoint: "/chat", WebSocket: &mockservice.WebSocketScript{}})
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	cases := []struct {
+		name   string
+		frames func(t *testing.T, ws *testWebSocket)
+	}{
+		{"Fragmented_ping", func(t *testing.T, ws *testWebSocket) { ws.sendFrame(t, 0x09, "ping") }},
+		{"Orphan_continuation", func(t *testing.T, ws *testWebSocket) { ws.sendFrame(t, 0x80, "rest") }},
+		{"Message_inside_fragmented_message", func(t *testing.T, ws *testWebSocket) {
+			ws.sendFrame(t, 0x01, "first")
+			ws.sendFrame(t, 0x81, "second")
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ws := dialWebSocket(t, server, "/chat")
+			defer ws.conn.Close()
+			c.frames(t, ws)
+
+			if payload := ws.receive(t); len(payload) != 2 || binary.BigEndian.Uint16([]byte(payload)) != 1002 {
+				t.Errorf("Expected the connection to be closed with status 1002 but got %q", payload)
+			}
+		})
+	}
+}